@@ -8,5 +8,6 @@ require (
 	github.com/alibabacloud-go/darabonba-openapi v0.1.7
 	github.com/alibabacloud-go/ecs-20140526/v2 v2.1.0
 	github.com/alibabacloud-go/tea v1.1.17
+	github.com/alibabacloud-go/tea-utils v1.3.9
 	github.com/hahaps/common-provider v0.0.0-20211207084144-cf56fbf57df5
 )
@@ -3,20 +3,41 @@ package main
 import (
 	"github.com/hahaps/common-provider/src/input"
 	"github.com/hahaps/input-provider-aliyun/src"
+	"github.com/hahaps/input-provider-aliyun/src/cli"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/version"
 	"log"
-	"strings"
+	"os"
 )
 
+// Set at build time by -ldflags "-X main.VERSION=..."
 var VERSION string = "v0.1"
 
 type Version struct {}
 
-func (Version)Check(version string, matched *bool) error {
-	*matched = strings.Compare(VERSION, version) == 0
+// Check matches VERSION against the version constraint of the host, as
+// "v0.1" or ">=0.1 <0.3".
+func (Version)Check(constraint string, matched *bool) error {
+	var err error
+	*matched, err = version.Match(VERSION, constraint)
+	if err != nil {
+		return common.NewError(common.InvalidParam, "%v", err)
+	}
+	return nil
+}
+
+// Capabilities returns VERSION and the features of the resources. The host
+// version is unused for now.
+func (Version)Capabilities(host string, capabilities *src.Capabilities) error {
+	*capabilities = src.NewCapabilities(VERSION)
 	return nil
 }
 
 func main() {
+	// The host starts the provider without args
+	if len(os.Args) > 1 {
+		os.Exit(cli.Run(os.Args[1:], os.Stdout, os.Stderr))
+	}
 	if err := input.RunProvider(Version{}, src.ResourceMap); err != nil {
 		log.Fatal(err)
 	}
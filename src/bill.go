@@ -1,10 +1,7 @@
 package src
 
 import (
-	"errors"
-	"fmt"
 	bssopenapi20171214 "github.com/alibabacloud-go/bssopenapi-20171214/client"
-	openapi "github.com/alibabacloud-go/darabonba-openapi/client"
 	"github.com/alibabacloud-go/tea/tea"
 	"github.com/hahaps/common-provider/src/common/utils"
 	"github.com/hahaps/common-provider/src/input"
@@ -51,36 +48,31 @@ type InstanceBill struct {
 	input.Resource
 }
 
-func (bill *InstanceBill)init(credential input.Credential) error {
-	config := &openapi.Config{
-		AccessKeyId: &credential.SecretId,
-		AccessKeySecret: &credential.SecretKey,
-	}
-	config.Endpoint = tea.String("business.aliyuncs.com")
-	cli, err := bssopenapi20171214.NewClient(config)
-	if err != nil {
-		return err
-	}
-	bill.credential = credential
-	bill.client = cli
-	return nil
-}
-
 func (InstanceBill)Call(params input.Params, replay *input.Replay) error {
 	bill := &InstanceBill{}
-	var next string
 	var err error
 	var bills []interface{}
 	params.Args, err = utils.CheckParam(params.Args, InstanceBillSchemes)
+	if err != nil {
+		return common.ParamError(err)
+	}
+	invoker, err := common.NewInvoker(params)
 	if err != nil {
 		return err
 	}
-	err = bill.init(params.Credential)
+	defer invoker.Close()
+	bill.credential = params.Credential
+	bill.client, err = common.BssClient(invoker, params.Credential)
 	if err != nil {
 		return err
 	}
+	defer common.ReleaseClient(bill.client)
 	limit := int32(params.Args["limit"].(int))
-	next = params.Args["marker"].(string)
+	// Unlike the inventory resources, the empty marker is the first page
+	pager, err := common.NewPaginator(common.MaxResultsStyle, params.Args["marker"].(string), nil, limit)
+	if err != nil {
+		return err
+	}
 	billingCycle := params.Args["billing_cycle"].(string)
 	if billingCycle == "current" {
 		now := time.Now()
@@ -95,22 +87,29 @@ func (InstanceBill)Call(params input.Params, replay *input.Replay) error {
 	}
 	request := &bssopenapi20171214.DescribeInstanceBillRequest{
 		BillingCycle: &billingCycle,
-		MaxResults: &limit,
-		NextToken: &next,
+		MaxResults: tea.Int32(pager.PageSize()),
 		IsHideZeroCharge: &hideZeroCharge,
 	}
+	if pager.NextToken() != "" {
+		request.NextToken = tea.String(pager.NextToken())
+	}
 	if subscription != "" {
 		request.SubscriptionType = &subscription
 	}
-	resp, err := bill.client.DescribeInstanceBill(request)
+	var resp *bssopenapi20171214.DescribeInstanceBillResponse
+	err = invoker.Invoke(common.ProductBss, "", "DescribeInstanceBill", func() (err error) {
+		resp, err = bill.client.DescribeInstanceBill(request)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 	if resp.Body.Success == nil || (resp.Body.Success != nil && !(*resp.Body.Success)) {
-		return errors.New(utils.SafeString(resp.Body.Message))
+		return common.ResponseError(common.ProductBss, "", "DescribeInstanceBill", utils.SafeString(resp.Body.Code),
+			utils.SafeString(resp.Body.Message), utils.SafeString(resp.Body.RequestId))
 	}
 	if resp.Body.Data == nil || resp.Body.Data.Items == nil {
-		return errors.New("bad response for query instance bill")
+		return common.NewError(common.Upstream, "bad response for query instance bill")
 	}
 	for _, b := range resp.Body.Data.Items {
 		iBill := models.NewInstanceBillModel()
@@ -146,16 +145,17 @@ func (InstanceBill)Call(params input.Params, replay *input.Replay) error {
 		iBill.SetChecksum()
 		checked, key := iBill.CheckRequired()
 		if !checked {
-			return errors.New(
-				fmt.Sprintf("Value[%v] should not be empty", key))
+			return common.NewError(common.Upstream, "Value[%v] should not be empty", key)
 		}
 		bills = append(bills, iBill)
 	}
 	if !utils.CheckQueryKeys(query, models.InstanceBillModel{}) {
-		return errors.New("query key is not attribute of InstanceBillModel")
+		return common.NewError(common.Internal, "query key is not attribute of InstanceBillModel")
+	}
+	replay.Next, err = pager.Next(utils.SafeInt32(resp.Body.Data.TotalCount), utils.SafeString(resp.Body.Data.NextToken))
+	if err != nil {
+		return err
 	}
-	next = utils.SafeString(resp.Body.Data.NextToken)
-	replay.Next = next
 	replay.Query = query
 	replay.Result = bills
 	return nil
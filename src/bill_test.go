@@ -0,0 +1,121 @@
+package src_test
+
+import (
+	"github.com/hahaps/common-provider/src/models"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func bill(id string, amount float64) map[string]interface{} {
+	return map[string]interface{}{
+		"InstanceID": id,
+		"ProductCode": "ecs",
+		"Region": "China (Hangzhou)",
+		"BillingDate": "2021-01-01",
+		"PretaxGrossAmount": amount,
+		"PretaxAmount": amount,
+		"Currency": "CNY",
+	}
+}
+
+func bills(items ...map[string]interface{}) fakeapi.Handler {
+	return fakeapi.Pages{
+		List: "Data.Items",
+		Counters: "Data",
+		Items: fakeapi.Items(items...),
+		Extra: map[string]interface{}{
+			"Success": true,
+			"Code": "Success",
+			"Data": map[string]interface{}{},
+		},
+	}.Handler()
+}
+
+func TestInstanceBill(t *testing.T) {
+	server := fakeapi.Start(t)
+	replay, err := fakeapi.Call("InstanceBill", map[string]interface{}{"billing_cycle": "2021-01", "is_hide_zero_charge": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.Result) != 1 || replay.Next != "" {
+		t.Fatalf("got %v records and Next %q, want 1 record and no Next", len(replay.Result), replay.Next)
+	}
+	record := replay.Result[0].(*models.InstanceBillModel)
+	if record.InstanceId != "i-fake0001" || record.BillingCycle != "2021-01" || record.PretaxAmount != 1.5 || record.AccountId != fakeapi.AccountId {
+		t.Errorf("unexpected bill %+v", record)
+	}
+	if replay.Query["BillingCycle"] != "2021-01" {
+		t.Errorf("unexpected query %v", replay.Query)
+	}
+	request := server.Requests("DescribeInstanceBill")[0]
+	if request.Get("BillingCycle") != "2021-01" || request.Get("IsHideZeroCharge") != "true" {
+		t.Errorf("unexpected request %v", request)
+	}
+}
+
+func TestInstanceBillCurrent(t *testing.T) {
+	server := fakeapi.Start(t)
+	if _, err := fakeapi.Call("InstanceBill", map[string]interface{}{"billing_cycle": "current"}); err != nil {
+		t.Fatal(err)
+	}
+	if cycle := server.Requests("DescribeInstanceBill")[0].Get("BillingCycle"); cycle != time.Now().Format("2006-01") {
+		t.Errorf("billing cycle %v, want the current month", cycle)
+	}
+}
+
+func TestInstanceBillPages(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Handle("DescribeInstanceBill", bills(bill("i-1", 1), bill("i-2", 2), bill("i-3", 3)))
+	args := map[string]interface{}{"billing_cycle": "2021-01", "limit": 2}
+	var ids []string
+	for calls := 0; ; calls++ {
+		if calls == 3 {
+			t.Fatal("pagination does not end")
+		}
+		replay, err := fakeapi.Call("InstanceBill", args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, record := range replay.Result {
+			ids = append(ids, record.(*models.InstanceBillModel).InstanceId)
+		}
+		if replay.Next == "" {
+			break
+		}
+		args["marker"] = replay.Next
+	}
+	if len(ids) != 3 || ids[0] != "i-1" || ids[2] != "i-3" {
+		t.Errorf("bills %v, want [i-1 i-2 i-3]", ids)
+	}
+	requests := server.Requests("DescribeInstanceBill")
+	if len(requests) != 2 || requests[0].Get("MaxResults") != "2" || requests[0].Get("NextToken") != "" || requests[1].Get("NextToken") != "2" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
+
+func TestInstanceBillFail(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Fail("DescribeInstanceBill", 1, 400, "NotApplicable", "This API is not applicable for caller.")
+	if _, err := fakeapi.Call("InstanceBill", map[string]interface{}{"billing_cycle": "2021-01"}); common.ErrorCodeOf(err) == "" {
+		t.Errorf("got %v, want an API error", err)
+	}
+	// BSS reports some failures in the body of a 200 response
+	server.Handle("DescribeInstanceBill", func(url.Values) (int, interface{}) {
+		return http.StatusOK, map[string]interface{}{
+			"Success": false,
+			"Code": "InvalidParameter",
+			"Message": "The billing cycle is invalid.",
+		}
+	})
+	_, err := fakeapi.Call("InstanceBill", map[string]interface{}{"billing_cycle": "2021-13"})
+	if common.ErrorCodeOf(err) != common.InvalidParam {
+		t.Errorf("got %v, want an InvalidParam error", err)
+	}
+	if _, err = fakeapi.Call("InstanceBill", map[string]interface{}{}); common.ErrorCodeOf(err) != common.InvalidParam {
+		t.Errorf("got %v without billing_cycle, want an InvalidParam error", err)
+	}
+}
@@ -0,0 +1,53 @@
+package src
+
+// Features of a resource
+const (
+	FeatureIncremental string = "incremental"
+	FeatureTombstones string = "tombstones"
+	FeatureMultiRegion string = "multi-region"
+)
+
+// Features of each resource of ResourceMap. A multi-region resource pages
+// through every region of its "region" param, a list or "all", with one
+// marker.
+var FeatureMap = map[string][]string {
+	"Server": []string{FeatureIncremental, FeatureTombstones, FeatureMultiRegion},
+	"ServerMetric": []string{},
+	"Image": []string{FeatureIncremental, FeatureTombstones, FeatureMultiRegion},
+	"Disk": []string{FeatureIncremental, FeatureTombstones, FeatureMultiRegion},
+	"Network": []string{FeatureIncremental, FeatureTombstones, FeatureMultiRegion},
+	"Subnet": []string{FeatureIncremental, FeatureTombstones, FeatureMultiRegion},
+	"Nic": []string{FeatureIncremental, FeatureTombstones, FeatureMultiRegion},
+	"SecurityGroup": []string{FeatureIncremental, FeatureTombstones, FeatureMultiRegion},
+	"FloatingIp": []string{FeatureIncremental, FeatureTombstones, FeatureMultiRegion},
+	"FloatingIpMetric": []string{},
+	"InstanceBill": []string{FeatureIncremental},
+	"ResourceGroup": []string{FeatureIncremental, FeatureTombstones},
+	"Schema": []string{},
+	"CredentialCheck": []string{},
+	"MetricCatalog": []string{},
+	"Metric": []string{},
+}
+
+type ResourceCapabilities struct {
+	Name string
+	Features []string
+}
+
+// Capabilities tells the host the version of the provider and what its
+// resources support, so it can keep working with an older provider.
+type Capabilities struct {
+	Version string
+	Resources []ResourceCapabilities
+}
+
+func NewCapabilities(version string) Capabilities {
+	capabilities := Capabilities{Version: version}
+	for _, name := range ResourceNames() {
+		capabilities.Resources = append(capabilities.Resources, ResourceCapabilities{
+			Name: name,
+			Features: append([]string{}, FeatureMap[name]...),
+		})
+	}
+	return capabilities
+}
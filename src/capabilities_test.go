@@ -0,0 +1,79 @@
+package src_test
+
+import (
+	"github.com/hahaps/input-provider-aliyun/src"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"github.com/hahaps/input-provider-aliyun/src/state"
+	"reflect"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	capabilities := src.NewCapabilities("v0.2.0")
+	if capabilities.Version != "v0.2.0" || len(capabilities.Resources) != len(src.ResourceMap) {
+		t.Fatalf("unexpected capabilities %+v", capabilities)
+	}
+	want := map[string][]string{
+		"Server": {src.FeatureIncremental, src.FeatureTombstones, src.FeatureMultiRegion},
+		"FloatingIp": {src.FeatureIncremental, src.FeatureTombstones, src.FeatureMultiRegion},
+		"InstanceBill": {src.FeatureIncremental},
+		"ServerMetric": {},
+		"FloatingIpMetric": {},
+		"CredentialCheck": {},
+	}
+	for _, resource := range capabilities.Resources {
+		if features, ok := want[resource.Name]; ok && !reflect.DeepEqual(resource.Features, features) {
+			t.Errorf("%v features %v, want %v", resource.Name, resource.Features, features)
+		}
+	}
+}
+
+// walkRegions pages through the resource name over two regions and returns
+// the regions of its pages.
+func walkRegions(t *testing.T, name string) []string {
+	fakeapi.Start(t)
+	var regions []string
+	args := map[string]interface{}{"region": "cn-hangzhou,cn-beijing", "marker": "1"}
+	for calls := 0; args["marker"] != "" && calls < 10; calls++ {
+		replay, err := fakeapi.Call(name, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if region, _ := replay.Query["RegionId"].(string); len(regions) == 0 || regions[len(regions) - 1] != region {
+			regions = append(regions, region)
+		}
+		args["marker"] = replay.Next
+	}
+	return regions
+}
+
+// The features match the resources: incremental and tombstones need a
+// tracked resource, tombstones its model.
+func TestFeatureMap(t *testing.T) {
+	for _, name := range src.ResourceNames() {
+		features, ok := src.FeatureMap[name]
+		if !ok {
+			t.Errorf("features of %v not declared", name)
+			continue
+		}
+		tracked, isTracked := src.ResourceMap[name].(*state.TrackedResource)
+		for _, feature := range features {
+			switch feature {
+			case src.FeatureIncremental:
+				if !isTracked {
+					t.Errorf("%v is not tracked", name)
+				}
+			case src.FeatureTombstones:
+				if _, ok := tracked.Resource.(state.ModelResource); !isTracked || !ok {
+					t.Errorf("%v has no tombstones", name)
+				}
+			case src.FeatureMultiRegion:
+				if regions := walkRegions(t, name); !reflect.DeepEqual(regions, []string{"cn-hangzhou", "cn-beijing"}) {
+					t.Errorf("%v pages through regions %v", name, regions)
+				}
+			default:
+				t.Errorf("unknown feature %v of %v", feature, name)
+			}
+		}
+	}
+}
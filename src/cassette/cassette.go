@@ -0,0 +1,204 @@
+package cassette
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	Record = "record"
+	Replay = "replay"
+)
+
+const Redacted = "REDACTED"
+
+// VolatileParams change on every request, they are neither recorded nor
+// matched.
+var VolatileParams = map[string]bool{
+	"AccessKeyId": true,
+	"SecurityToken": true,
+	"Signature": true,
+	"SignatureMethod": true,
+	"SignatureNonce": true,
+	"SignatureType": true,
+	"SignatureVersion": true,
+	"Timestamp": true,
+}
+
+// SecretFields are redacted from the recorded responses.
+var SecretFields = map[string]bool{
+	"AccessKeyId": true,
+	"AccessKeySecret": true,
+	"SecurityToken": true,
+}
+
+type Interaction struct {
+	Host string `json:"host"`
+	Action string `json:"action"`
+	Params map[string]string `json:"params"`
+	Status int `json:"status"`
+	Body interface{} `json:"body"`
+}
+
+type Cassette struct {
+	Path string `json:"-"`
+	Interactions []*Interaction `json:"interactions"`
+	lock sync.Mutex
+	played map[*Interaction]int
+}
+
+func Load(path string) (*Cassette, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cassette{Path: path}
+	if err = json.Unmarshal(content, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Add appends the interaction and saves the cassette.
+func (c *Cassette)Add(interaction *Interaction) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.Interactions = append(c.Interactions, interaction)
+	content, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.Path, content, 0600)
+}
+
+// Find returns the interaction recorded for action and params. Identical
+// requests replay their recorded interactions in turn, the last one
+// repeating.
+func (c *Cassette)Find(action string, params map[string]string) *Interaction {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.played == nil {
+		c.played = map[*Interaction]int{}
+	}
+	var matched []*Interaction
+	for _, interaction := range c.Interactions {
+		if interaction.Action == action && sameParams(interaction.Params, params) {
+			matched = append(matched, interaction)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	played := 0
+	for _, interaction := range matched {
+		played += c.played[interaction]
+	}
+	if played >= len(matched) {
+		played = len(matched) - 1
+	}
+	c.played[matched[played]]++
+	return matched[played]
+}
+
+// Sanitize drops the volatile params of a request.
+func Sanitize(values url.Values) map[string]string {
+	params := map[string]string{}
+	for key := range values {
+		if !VolatileParams[key] {
+			params[key] = values.Get(key)
+		}
+	}
+	return params
+}
+
+// Redact replaces the secret fields of a decoded JSON body.
+func Redact(body interface{}) interface{} {
+	switch value := body.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if SecretFields[key] {
+				value[key] = Redacted
+			} else {
+				value[key] = Redact(field)
+			}
+		}
+	case []interface{}:
+		for i, field := range value {
+			value[i] = Redact(field)
+		}
+	}
+	return body
+}
+
+// Leaks lists the volatile params and unredacted secret fields left in the
+// interactions, as "DescribeInstances params.Signature", to check a cassette
+// before sharing it.
+func (c *Cassette)Leaks() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	var leaks []string
+	for _, interaction := range c.Interactions {
+		for key := range interaction.Params {
+			if VolatileParams[key] {
+				leaks = append(leaks, interaction.Action + " params." + key)
+			}
+		}
+		for _, path := range secretPaths(interaction.Body, "body") {
+			leaks = append(leaks, interaction.Action + " " + path)
+		}
+	}
+	sort.Strings(leaks)
+	return leaks
+}
+
+func secretPaths(body interface{}, path string) []string {
+	var paths []string
+	switch value := body.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if SecretFields[key] && field != Redacted {
+				paths = append(paths, path + "." + key)
+			} else {
+				paths = append(paths, secretPaths(field, path + "." + key)...)
+			}
+		}
+	case []interface{}:
+		for i, field := range value {
+			paths = append(paths, secretPaths(field, fmt.Sprintf("%v[%v]", path, i))...)
+		}
+	}
+	return paths
+}
+
+func sameParams(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Key describes a request in error messages.
+func Key(action string, params map[string]string) string {
+	var keys []string
+	for key, value := range params {
+		keys = append(keys, key + "=" + value)
+	}
+	sort.Strings(keys)
+	return action + "?" + strings.Join(keys, "&")
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
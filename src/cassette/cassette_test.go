@@ -0,0 +1,124 @@
+package cassette_test
+
+import (
+	"github.com/hahaps/common-provider/src/input"
+	"github.com/hahaps/input-provider-aliyun/src"
+	"github.com/hahaps/input-provider-aliyun/src/cassette"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	fakeapi.Main(m)
+}
+
+func TestSanitize(t *testing.T) {
+	values := url.Values{
+		"Action": {"DescribeInstances"},
+		"RegionId": {"cn-hangzhou"},
+		"AccessKeyId": {"LTAI-secret"},
+		"SecurityToken": {"token"},
+		"Signature": {"sig"},
+		"SignatureNonce": {"nonce"},
+		"Timestamp": {"2021-01-01T00:00:00Z"},
+	}
+	params := cassette.Sanitize(values)
+	if len(params) != 2 || params["Action"] != "DescribeInstances" || params["RegionId"] != "cn-hangzhou" {
+		t.Errorf("unexpected params %v", params)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	body := map[string]interface{}{
+		"Credentials": map[string]interface{}{
+			"AccessKeyId": "STS.id",
+			"AccessKeySecret": "secret",
+			"SecurityToken": "token",
+			"Expiration": "2021-01-01T00:00:00Z",
+		},
+		"List": []interface{}{map[string]interface{}{"AccessKeyId": "id"}},
+	}
+	c := &cassette.Cassette{Interactions: []*cassette.Interaction{{Action: "AssumeRole", Body: body}}}
+	if leaks := c.Leaks(); len(leaks) != 4 {
+		t.Errorf("leaks %v, want 4", leaks)
+	}
+	cassette.Redact(body)
+	credentials := body["Credentials"].(map[string]interface{})
+	if credentials["AccessKeySecret"] != cassette.Redacted || credentials["Expiration"] != "2021-01-01T00:00:00Z" {
+		t.Errorf("unexpected credentials %v", credentials)
+	}
+	if leaks := c.Leaks(); len(leaks) != 0 {
+		t.Errorf("leaks %v after redaction", leaks)
+	}
+}
+
+func TestFind(t *testing.T) {
+	throttled := &cassette.Interaction{Action: "DescribeInstances", Params: map[string]string{"RegionId": "cn-hangzhou"}, Status: 400}
+	ok := &cassette.Interaction{Action: "DescribeInstances", Params: map[string]string{"RegionId": "cn-hangzhou"}, Status: 200}
+	c := &cassette.Cassette{Interactions: []*cassette.Interaction{throttled, ok}}
+	params := map[string]string{"RegionId": "cn-hangzhou"}
+	for i, want := range []*cassette.Interaction{throttled, ok, ok} {
+		if got := c.Find("DescribeInstances", params); got != want {
+			t.Errorf("request %v replays %+v, want %+v", i, got, want)
+		}
+	}
+	if got := c.Find("DescribeInstances", map[string]string{"RegionId": "cn-beijing"}); got != nil {
+		t.Errorf("unrecorded request replays %+v", got)
+	}
+}
+
+// A recorded Call keeps no AccessKey, token or signature, of the request or
+// of the assumed role.
+func TestRecordRedacted(t *testing.T) {
+	server := fakeapi.NewServer()
+	server.LoadDefaults()
+	server.Install()
+	defer server.Close()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	defer common.UseCassette("", "")
+	credential := fakeapi.Credential
+	credential.Extra = map[string]interface{}{common.ExtraRoleArn: "acs:ram::1:role/recorder"}
+	params := input.Params{
+		Credential: credential,
+		Args: map[string]interface{}{
+			"region": fakeapi.Region,
+			"cassette_mode": cassette.Record,
+			"cassette": path,
+		},
+	}
+	if err := src.ResourceMap["Server"].Call(params, &input.Replay{}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := cassette.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	actions := map[string]bool{}
+	for _, interaction := range c.Interactions {
+		actions[interaction.Action] = true
+	}
+	if !actions["AssumeRole"] || !actions["DescribeInstances"] {
+		t.Errorf("recorded actions %v", actions)
+	}
+	if leaks := c.Leaks(); len(leaks) != 0 {
+		t.Errorf("recorded secrets %v", leaks)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"AccessKeyId\": \"STS.", "fake-id", "fake-secret", "fake-token", "Signature"} {
+		if strings.Contains(string(content), secret) {
+			t.Errorf("cassette holds %q:\n%s", secret, content)
+		}
+	}
+	requests := server.Requests("DescribeInstances")
+	if len(requests) != 1 || !strings.HasPrefix(requests[0].Get("AccessKeyId"), "STS.") || requests[0].Get("Signature") == "" {
+		t.Errorf("the server got %v, want a signed request of the assumed role", requests)
+	}
+}
@@ -0,0 +1,175 @@
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Proxy is a local HTTP proxy the OpenAPI clients talk plain HTTP to. It
+// records the forwarded traffic into the cassette, or replays the cassette
+// with no network.
+type Proxy struct {
+	Mode string
+	Cassette *Cassette
+	Client *http.Client
+	lock sync.RWMutex
+	schemes map[string]string
+	listener net.Listener
+	server *http.Server
+}
+
+// Start serves the cassette at path in mode on a local port. Recording
+// appends to an existing cassette.
+func Start(mode, path string) (*Proxy, error) {
+	var c *Cassette
+	var err error
+	switch mode {
+	case Record:
+		c = &Cassette{Path: path}
+		if exists(path) {
+			if c, err = Load(path); err != nil {
+				return nil, err
+			}
+		}
+	case Replay:
+		if c, err = Load(path); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %v", mode)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	proxy := &Proxy{
+		Mode: mode,
+		Cassette: c,
+		Client: &http.Client{Timeout: 60 * time.Second},
+		schemes: map[string]string{},
+		listener: listener,
+	}
+	proxy.server = &http.Server{Handler: proxy}
+	go proxy.server.Serve(listener)
+	return proxy, nil
+}
+
+// URL is the proxy address for the clients.
+func (proxy *Proxy)URL() string {
+	return "http://" + proxy.listener.Addr().String()
+}
+
+// Upstream sets the protocol the requests for host are forwarded with,
+// https by default.
+func (proxy *Proxy)Upstream(host, scheme string) {
+	proxy.lock.Lock()
+	defer proxy.lock.Unlock()
+	proxy.schemes[host] = scheme
+}
+
+func (proxy *Proxy)Close() error {
+	return proxy.server.Close()
+}
+
+func (proxy *Proxy)ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Cassette.BadRequest", err.Error())
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err = r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "Cassette.BadRequest", err.Error())
+		return
+	}
+	action := r.Form.Get("Action")
+	params := Sanitize(r.Form)
+	if proxy.Mode == Replay {
+		interaction := proxy.Cassette.Find(action, params)
+		if interaction == nil {
+			writeError(w, http.StatusNotFound, "Cassette.NotFound",
+				"no recorded interaction for " + Key(action, params))
+			return
+		}
+		writeJSON(w, interaction.Status, interaction.Body)
+		return
+	}
+	status, content, err := proxy.forward(r, body)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "Cassette.Upstream", err.Error())
+		return
+	}
+	var decoded interface{}
+	if err = json.Unmarshal(content, &decoded); err != nil {
+		decoded = string(content)
+	}
+	interaction := &Interaction{
+		Host: r.Host,
+		Action: action,
+		Params: params,
+		Status: status,
+		Body: Redact(decoded),
+	}
+	if err = proxy.Cassette.Add(interaction); err != nil {
+		writeError(w, http.StatusInternalServerError, "Cassette.Write", err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(content)
+}
+
+func (proxy *Proxy)forward(r *http.Request, body []byte) (int, []byte, error) {
+	proxy.lock.RLock()
+	scheme, ok := proxy.schemes[r.Host]
+	proxy.lock.RUnlock()
+	if !ok {
+		scheme = "https"
+	}
+	target := url.URL{Scheme: scheme, Host: r.Host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+	request, err := http.NewRequest(r.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	for key, values := range r.Header {
+		if key != "Proxy-Connection" && key != "Accept-Encoding" {
+			request.Header[key] = values
+		}
+	}
+	resp, err := proxy.Client.Do(request)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+	return resp.StatusCode, content, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	content, err := json.Marshal(body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(content)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"Code": code,
+		"Message": message,
+		"RequestId": "",
+	})
+}
@@ -0,0 +1,224 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/hahaps/common-provider/src/common/utils"
+	"github.com/hahaps/common-provider/src/input"
+	"github.com/hahaps/input-provider-aliyun/src"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Env var of the default --account-id flag
+const EnvAccountId = "ALIBABA_CLOUD_ACCOUNT_ID"
+
+const usage = `Usage:
+  provider-aliyun                       serve the resources to the CloudTracker host
+  provider-aliyun list-resources        list the resources
+  provider-aliyun describe <Resource>   print the JSON Schema of the args of a resource
+  provider-aliyun fetch <Resource> [flags]
+                                        call a resource and print its records
+`
+
+type multiFlag []string
+
+func (values *multiFlag)String() string {
+	return strings.Join(*values, ", ")
+}
+
+func (values *multiFlag)Set(value string) error {
+	*values = append(*values, value)
+	return nil
+}
+
+// Run runs the subcommand of args and returns the exit code.
+func Run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	var err error
+	switch args[0] {
+	case "list-resources":
+		err = listResources(stdout)
+	case "describe":
+		err = describe(args[1:], stdout)
+	case "fetch":
+		err = fetch(args[1:], stdout, stderr)
+	case "help", "-h", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "unknown command %v\n%v", args[0], usage)
+		return 2
+	}
+	if err == flag.ErrHelp {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
+
+func listResources(stdout io.Writer) error {
+	for _, name := range src.ResourceNames() {
+		fmt.Fprintln(stdout, name)
+	}
+	return nil
+}
+
+func describe(args []string, stdout io.Writer) error {
+	if len(args) != 1 {
+		return errors.New("usage: provider-aliyun describe <Resource>")
+	}
+	schema, err := src.JSONSchema(args[0])
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(schema)
+}
+
+func fetch(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return errors.New("usage: provider-aliyun fetch <Resource> [flags]")
+	}
+	name := args[0]
+	resource, ok := src.ResourceMap[name]
+	if !ok {
+		return fmt.Errorf("unknown resource %v", name)
+	}
+	var rawArgs, endpoints multiFlag
+	credential := input.Credential{Extra: map[string]interface{}{}}
+	flags := flag.NewFlagSet("fetch " + name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Var(&rawArgs, "arg", "param of the resource as `name=value`, repeatable")
+	argsJson := flags.String("args", "", "params of the resource as a JSON object")
+	allPages := flags.Bool("all-pages", false, "follow the markers to the last page")
+	format := flags.String("format", "ndjson", "output format: json, ndjson, csv or table")
+	flags.StringVar(&credential.SecretId, "access-key-id", "", "AccessKey ID, from the credential chain by default")
+	flags.StringVar(&credential.SecretKey, "access-key-secret", "", "AccessKey secret")
+	flags.StringVar(&credential.AccountId, "account-id", os.Getenv(EnvAccountId), "account id set to the records")
+	roleArn := flags.String("role-arn", "", "RAM role to assume")
+	flags.Var(&endpoints, "endpoint", "endpoint of a product as `product=url`, or of every product as url, repeatable")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *roleArn != "" {
+		credential.Extra[common.ExtraRoleArn] = *roleArn
+	}
+	for _, endpoint := range endpoints {
+		pair := strings.SplitN(endpoint, "=", 2)
+		if len(pair) == 2 {
+			common.SetEndpoint(pair[0], "", pair[1])
+			continue
+		}
+		for product := range common.EndpointRules {
+			common.SetEndpoint(product, "", endpoint)
+		}
+	}
+	params, err := parseArgs(name, *argsJson, rawArgs)
+	if err != nil {
+		return err
+	}
+	out, err := NewWriter(*format, stdout)
+	if err != nil {
+		return err
+	}
+	for {
+		replay := &input.Replay{}
+		pageArgs := map[string]interface{}{}
+		for key, value := range params {
+			pageArgs[key] = value
+		}
+		err = resource.Call(input.Params{Credential: credential, Args: pageArgs}, replay)
+		if err != nil {
+			return err
+		}
+		for _, record := range replay.Result {
+			if err = out.Write(record); err != nil {
+				return err
+			}
+		}
+		if replay.Next == "" || !*allPages {
+			if replay.Next != "" {
+				fmt.Fprintf(stderr, "next marker: %v\n", replay.Next)
+			}
+			break
+		}
+		params["marker"] = replay.Next
+	}
+	return out.Close()
+}
+
+// parseArgs reads the params of the JSON object, then of the name=value
+// pairs converted to the type of their scheme. Slices are comma separated
+// and maps are JSON objects.
+func parseArgs(name, argsJson string, rawArgs []string) (map[string]interface{}, error) {
+	params := map[string]interface{}{}
+	if argsJson != "" {
+		if err := json.Unmarshal([]byte(argsJson), &params); err != nil {
+			return nil, fmt.Errorf("bad --args: %v", err)
+		}
+	}
+	schemes, err := src.Schemes(name)
+	if err != nil {
+		return nil, err
+	}
+	types := map[string]reflect.Kind{}
+	for _, scheme := range schemes {
+		types[scheme.Param] = scheme.Type
+	}
+	for _, raw := range rawArgs {
+		pair := strings.SplitN(raw, "=", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("bad --arg %v, should be name=value", raw)
+		}
+		key, value := pair[0], pair[1]
+		switch types[key] {
+		case utils.Int:
+			number, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("bad --arg %v: %v", raw, err)
+			}
+			params[key] = number
+		case utils.Bool:
+			flag, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("bad --arg %v: %v", raw, err)
+			}
+			params[key] = flag
+		case utils.Float64:
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad --arg %v: %v", raw, err)
+			}
+			params[key] = number
+		case utils.Slice, utils.Array:
+			var items []interface{}
+			for _, item := range strings.Split(value, ",") {
+				items = append(items, strings.TrimSpace(item))
+			}
+			params[key] = items
+		case utils.Map:
+			obj := map[string]interface{}{}
+			if err := json.Unmarshal([]byte(value), &obj); err != nil {
+				return nil, fmt.Errorf("bad --arg %v: %v", raw, err)
+			}
+			params[key] = obj
+		default:
+			params[key] = value
+		}
+	}
+	return params, nil
+}
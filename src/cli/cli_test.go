@@ -0,0 +1,162 @@
+package cli_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/hahaps/input-provider-aliyun/src"
+	"github.com/hahaps/input-provider-aliyun/src/cli"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	fakeapi.Main(m)
+}
+
+// run runs the subcommand of args and returns its exit code and outputs.
+func run(args ...string) (int, string, string) {
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := cli.Run(args, stdout, stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// fetch runs fetch of resource against server with args.
+func fetch(t *testing.T, server *fakeapi.Server, resource string, args ...string) (string, string) {
+	t.Helper()
+	args = append([]string{"fetch", resource,
+		"--endpoint", server.URL,
+		"--access-key-id", fakeapi.Credential.SecretId,
+		"--access-key-secret", fakeapi.Credential.SecretKey,
+		"--account-id", fakeapi.AccountId,
+	}, args...)
+	code, stdout, stderr := run(args...)
+	if code != 0 {
+		t.Fatalf("%v exited with %v: %v", args, code, stderr)
+	}
+	return stdout, stderr
+}
+
+func TestListResources(t *testing.T) {
+	code, stdout, _ := run("list-resources")
+	if code != 0 {
+		t.Fatalf("exited with %v", code)
+	}
+	names := strings.Fields(stdout)
+	if strings.Join(names, ",") != strings.Join(src.ResourceNames(), ",") {
+		t.Errorf("listed %v, want %v", names, src.ResourceNames())
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	code, stdout, _ := run("describe", "Network")
+	if code != 0 {
+		t.Fatalf("exited with %v", code)
+	}
+	schema := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(stdout), &schema); err != nil {
+		t.Fatal(err)
+	}
+	properties, _ := schema["properties"].(map[string]interface{})
+	for _, param := range []string{"region", "limit", "marker", "tags", "resource_group_id"} {
+		if properties[param] == nil {
+			t.Errorf("param %v missing in %v", param, stdout)
+		}
+	}
+	if code, _, stderr := run("describe", "Unknown"); code != 1 || stderr == "" {
+		t.Errorf("unknown resource exited with %v: %q", code, stderr)
+	}
+}
+
+func TestFetchJson(t *testing.T) {
+	server := fakeapi.Start(t)
+	stdout, _ := fetch(t, server, "Network", "--arg", "region=" + fakeapi.Region, "--format", "json")
+	var records []map[string]interface{}
+	if err := json.Unmarshal([]byte(stdout), &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0]["ProviderId"] != "vpc-fake0001" || records[0]["AccountId"] != fakeapi.AccountId {
+		t.Errorf("unexpected records %v", stdout)
+	}
+}
+
+func TestFetchNdjson(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Handle("DescribeVpcs", fakeapi.Pages{
+		List: "Vpcs.Vpc",
+		Items: fakeapi.Items(
+			map[string]interface{}{"VpcId": "vpc-1", "Status": "Available"},
+			map[string]interface{}{"VpcId": "vpc-2", "Status": "Available"},
+		),
+	}.Handler())
+	stdout, stderr := fetch(t, server, "Network", "--arg", "region=" + fakeapi.Region, "--arg", "limit=1")
+	if lines := strings.Split(strings.TrimSpace(stdout), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"vpc-1"`) {
+		t.Errorf("first page %q", stdout)
+	}
+	if !strings.HasPrefix(stderr, "next marker: ") {
+		t.Errorf("no next marker in %q", stderr)
+	}
+	stdout, _ = fetch(t, server, "Network", "--arg", "region=" + fakeapi.Region, "--arg", "limit=1", "--all-pages")
+	if lines := strings.Split(strings.TrimSpace(stdout), "\n"); len(lines) != 2 || !strings.Contains(lines[1], `"vpc-2"`) {
+		t.Errorf("every page %q", stdout)
+	}
+}
+
+func TestFetchCsv(t *testing.T) {
+	server := fakeapi.Start(t)
+	stdout, _ := fetch(t, server, "Network", "--arg", "region=" + fakeapi.Region, "--format", "csv")
+	rows, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %v rows, want a header and a record: %q", len(rows), stdout)
+	}
+	record := map[string]string{}
+	for i, name := range rows[0] {
+		record[name] = rows[1][i]
+	}
+	if record["ProviderId"] != "vpc-fake0001" || record["Tags"] != "env=test" || !strings.HasPrefix(record["Extra"], "{") {
+		t.Errorf("unexpected record %v", record)
+	}
+}
+
+// The metric records are pointers to pointers of models.
+func TestFetchCsvMetric(t *testing.T) {
+	server := fakeapi.Start(t)
+	stdout, _ := fetch(t, server, "ServerMetric", "--arg", "region=" + fakeapi.Region, "--arg", "metric_names=CPUUtilization", "--format", "csv")
+	rows, err := csv.NewReader(strings.NewReader(stdout)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) < 2 || !strings.Contains(strings.Join(rows[0], ","), "InstanceId") || !strings.Contains(strings.Join(rows[1], ","), "i-fake0001") {
+		t.Errorf("unexpected metrics %q", stdout)
+	}
+}
+
+func TestFetchTable(t *testing.T) {
+	server := fakeapi.Start(t)
+	stdout, _ := fetch(t, server, "ServerMetric", "--arg", "region=" + fakeapi.Region, "--arg", "metric_names=CPUUtilization", "--format", "table")
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) < 2 || !strings.Contains(lines[0], "InstanceId") || !strings.Contains(lines[1], "i-fake0001") {
+		t.Fatalf("unexpected table %q", stdout)
+	}
+	// The columns are aligned
+	column := strings.Index(lines[0], "InstanceId")
+	for _, line := range lines[1:] {
+		if len(line) < column || !strings.HasPrefix(line[column:], "i-fake0001") {
+			t.Errorf("misaligned line %q under %q", line, lines[0])
+		}
+	}
+}
+
+func TestFetchFail(t *testing.T) {
+	server := fakeapi.Start(t)
+	if code, _, stderr := run("fetch", "Network", "--endpoint", server.URL, "--format", "xml"); code != 1 || !strings.Contains(stderr, "unknown format") {
+		t.Errorf("xml format exited with %v: %q", code, stderr)
+	}
+	if code, _, _ := run("fetch", "Unknown"); code != 1 {
+		t.Errorf("unknown resource exited with %v", code)
+	}
+}
@@ -0,0 +1,173 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+)
+
+// Writer prints the records fetched.
+type Writer interface {
+	Write(record interface{}) error
+	Close() error
+}
+
+func NewWriter(format string, out io.Writer) (Writer, error) {
+	switch format {
+	case "json":
+		return &jsonWriter{out: out}, nil
+	case "ndjson":
+		return &ndjsonWriter{encoder: json.NewEncoder(out)}, nil
+	case "csv":
+		return &csvWriter{out: csv.NewWriter(out)}, nil
+	case "table":
+		return &tableWriter{out: tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)}, nil
+	}
+	return nil, fmt.Errorf("unknown format %v, should be json, ndjson, csv or table", format)
+}
+
+// jsonWriter prints a JSON array of every record on Close.
+type jsonWriter struct {
+	out io.Writer
+	records []interface{}
+}
+
+func (writer *jsonWriter)Write(record interface{}) error {
+	writer.records = append(writer.records, record)
+	return nil
+}
+
+func (writer *jsonWriter)Close() error {
+	if writer.records == nil {
+		writer.records = []interface{}{}
+	}
+	content, err := json.MarshalIndent(writer.records, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(writer.out, string(content))
+	return err
+}
+
+// ndjsonWriter prints a JSON object per line as soon as a record comes.
+type ndjsonWriter struct {
+	encoder *json.Encoder
+}
+
+func (writer *ndjsonWriter)Write(record interface{}) error {
+	return writer.encoder.Encode(record)
+}
+
+func (writer *ndjsonWriter)Close() error {
+	return nil
+}
+
+// columns reads the exported fields of struct records as cells, the header
+// taken from the first record. Nested values are written as JSON.
+type columns struct {
+	header []string
+}
+
+// cells returns the cells of record, and whether the header was just read.
+func (cols *columns)cells(record interface{}) ([]string, bool, error) {
+	val := reflect.ValueOf(record)
+	for val.Kind() == reflect.Ptr || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil, false, fmt.Errorf("nil record %T", record)
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, false, fmt.Errorf("%T is not a struct record", record)
+	}
+	first := cols.header == nil
+	if first {
+		for i := 0; i < val.NumField(); i++ {
+			if field := val.Type().Field(i); field.PkgPath == "" && !field.Anonymous {
+				cols.header = append(cols.header, field.Name)
+			}
+		}
+	}
+	row := make([]string, len(cols.header))
+	for i, name := range cols.header {
+		field := val.FieldByName(name)
+		if !field.IsValid() {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.Map, reflect.Slice, reflect.Struct, reflect.Interface, reflect.Ptr:
+			content, err := json.Marshal(field.Interface())
+			if err != nil {
+				return nil, false, err
+			}
+			row[i] = string(content)
+		default:
+			row[i] = fmt.Sprint(field.Interface())
+		}
+	}
+	return row, first, nil
+}
+
+// csvWriter prints the columns of the records as CSV.
+type csvWriter struct {
+	out *csv.Writer
+	columns columns
+}
+
+func (writer *csvWriter)Write(record interface{}) error {
+	row, first, err := writer.columns.cells(record)
+	if err != nil {
+		return err
+	}
+	if first {
+		if err = writer.out.Write(writer.columns.header); err != nil {
+			return err
+		}
+	}
+	return writer.out.Write(row)
+}
+
+func (writer *csvWriter)Close() error {
+	writer.out.Flush()
+	return writer.out.Error()
+}
+
+// tableWriter prints the columns of the records aligned, on Close.
+type tableWriter struct {
+	out *tabwriter.Writer
+	columns columns
+}
+
+func (writer *tableWriter)Write(record interface{}) error {
+	row, first, err := writer.columns.cells(record)
+	if err != nil {
+		return err
+	}
+	if first {
+		if _, err = fmt.Fprintln(writer.out, tableRow(writer.columns.header)); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintln(writer.out, tableRow(row))
+	return err
+}
+
+func (writer *tableWriter)Close() error {
+	return writer.out.Flush()
+}
+
+// tableRow joins the cells by tabs, escaping the tabs and newlines of the
+// cells.
+func tableRow(cells []string) string {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = tableEscaper.Replace(cell)
+	}
+	return strings.Join(escaped, "\t")
+}
+
+var tableEscaper = strings.NewReplacer("\t", `\t`, "\n", `\n`)
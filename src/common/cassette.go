@@ -0,0 +1,75 @@
+package common
+
+import (
+	"github.com/hahaps/input-provider-aliyun/src/cassette"
+	"os"
+	"sync"
+)
+
+// Env vars switching the provider to a cassette, overridden by the
+// "cassette_mode" and "cassette" params.
+const (
+	EnvCassetteMode = "ALIBABA_CLOUD_CASSETTE_MODE"
+	EnvCassette = "ALIBABA_CLOUD_CASSETTE"
+)
+
+var cassetteLock sync.Mutex
+var cassetteProxy *cassette.Proxy
+var cassettePath string
+
+// UseCassette records the API traffic of the provider into the cassette at
+// path, or replays it, as mode is "record" or "replay". An empty mode goes
+// back to the network.
+func UseCassette(mode, path string) error {
+	cassetteLock.Lock()
+	defer cassetteLock.Unlock()
+	if cassetteProxy != nil && cassetteProxy.Mode == mode && cassettePath == path {
+		return nil
+	}
+	if cassetteProxy == nil && mode == "" {
+		return nil
+	}
+	if cassetteProxy != nil {
+		cassetteProxy.Close()
+		cassetteProxy, cassettePath = nil, ""
+	}
+	defer ResetClients()
+	if mode == "" {
+		return nil
+	}
+	if path == "" {
+		return NewError(InvalidParam, "cassette path is required in %v mode", mode)
+	}
+	proxy, err := cassette.Start(mode, path)
+	if err != nil {
+		return NewError(InvalidParam, "bad cassette %v: %v", path, err)
+	}
+	cassetteProxy, cassettePath = proxy, path
+	return nil
+}
+
+func useCassette(args map[string]interface{}) error {
+	mode, path := args["cassette_mode"].(string), args["cassette"].(string)
+	if mode == "" {
+		mode = os.Getenv(EnvCassetteMode)
+	}
+	if path == "" {
+		path = os.Getenv(EnvCassette)
+	}
+	return UseCassette(mode, path)
+}
+
+// cassetteUpstream routes the traffic to host through the cassette proxy
+// when one is in use, returning the proxy URL.
+func cassetteUpstream(host, scheme string) string {
+	cassetteLock.Lock()
+	defer cassetteLock.Unlock()
+	if cassetteProxy == nil {
+		return ""
+	}
+	if scheme == "" {
+		scheme = "https"
+	}
+	cassetteProxy.Upstream(host, scheme)
+	return cassetteProxy.URL()
+}
@@ -0,0 +1,380 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	cms20190101 "github.com/alibabacloud-go/cms-20190101/v2/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hahaps/common-provider/src/common/utils"
+	"github.com/hahaps/common-provider/src/input"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Sources of a MetricCatalog
+const (
+	CatalogApi string = "api"
+	CatalogCache string = "cache"
+	CatalogBuiltin string = "builtin"
+)
+
+// Env var of the default "metric_catalog_file" param
+const EnvMetricCatalogFile = "ALIBABA_CLOUD_METRIC_CATALOG_FILE"
+
+// Metric metas per DescribeMetricMetaList page
+const metricMetaPageSize int32 = 1000
+
+// Params of the metric resources choosing their MetricCatalog. The
+// "metric_catalog" is "auto" to load it from DescribeMetricMetaList, or
+// "builtin" to use the built-in tables only.
+var MetricCatalogSchemes = []utils.Scheme {
+	utils.Scheme{
+		Param: "metric_catalog",
+		Required: false,
+		Type: utils.String,
+		Default: "auto",
+	},
+	utils.Scheme{
+		Param: "metric_catalog_file",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
+	utils.Scheme{
+		Param: "metric_catalog_ttl",
+		Required: false,
+		Type: utils.Int,
+		Default: 86400,
+	},
+}
+
+// MetricMeta describes a metric of a namespace.
+type MetricMeta struct {
+	Namespace string
+	MetricName string
+	Description string
+	Unit string
+	Statistics []string
+	Dimensions []string
+	Periods []string
+	// SeriesByValue names the series by the values of the ExtraDimensions,
+	// as the agent metrics, instead of by their names
+	SeriesByValue bool
+}
+
+// InstanceDimension returns the dimension naming the instance of the
+// metric: "instanceId", or the first dimension besides userId for the
+// namespaces naming it otherwise, as the "BucketName" of OSS.
+func (meta *MetricMeta)InstanceDimension() string {
+	for _, dimension := range meta.Dimensions {
+		if dimension == "instanceId" {
+			return dimension
+		}
+	}
+	for _, dimension := range meta.Dimensions {
+		if dimension != "userId" {
+			return dimension
+		}
+	}
+	return "instanceId"
+}
+
+// ExtraDimensions returns the dimensions of the metric besides the userId
+// and the InstanceDimension.
+func (meta *MetricMeta)ExtraDimensions() []string {
+	instanceDimension := meta.InstanceDimension()
+	var extra []string
+	for _, dimension := range meta.Dimensions {
+		if dimension != "userId" && dimension != instanceDimension {
+			extra = append(extra, dimension)
+		}
+	}
+	return extra
+}
+
+// DimensionValues returns the values of the ExtraDimensions in a datapoint
+// of the metric, as {"mountpoint": "/data"}, or nil without extra ones.
+func (meta *MetricMeta)DimensionValues(datapoint map[string]interface{}) map[string]string {
+	extra := meta.ExtraDimensions()
+	if len(extra) == 0 {
+		return nil
+	}
+	values := map[string]string{}
+	for _, dimension := range extra {
+		if value, ok := datapoint[dimension]; ok && value != nil {
+			values[dimension] = fmt.Sprint(value)
+		} else {
+			values[dimension] = ""
+		}
+	}
+	return values
+}
+
+// SeriesName names the statistic stat of a datapoint of the metric, as
+// "VPC_PublicIP_InternetInRate.Average/ip" after the names of the
+// ExtraDimensions, or "diskusage_utilization.Average/mountpoint=/data" after
+// their values when SeriesByValue. The values keep the series of an instance
+// apart, as the name is part of the index of the MetricModel.
+func (meta *MetricMeta)SeriesName(stat string, datapoint map[string]interface{}) string {
+	name := meta.MetricName + "." + stat
+	extra := meta.ExtraDimensions()
+	if len(extra) == 0 {
+		return name
+	}
+	if !meta.SeriesByValue {
+		return name + "/" + strings.Join(extra, ",")
+	}
+	values := meta.DimensionValues(datapoint)
+	var pairs []string
+	for _, dimension := range extra {
+		pairs = append(pairs, dimension + "=" + values[dimension])
+	}
+	return name + "/" + strings.Join(pairs, ",")
+}
+
+// MetricCatalog holds the metrics of a namespace, keyed by name.
+type MetricCatalog struct {
+	Namespace string
+	// CatalogApi, CatalogCache or CatalogBuiltin
+	Source string
+	// Unix time the metrics were loaded from the API
+	Time int64
+	Metrics map[string]*MetricMeta
+}
+
+func (catalog *MetricCatalog)Lookup(metricName string) (*MetricMeta, bool) {
+	meta, ok := catalog.Metrics[metricName]
+	return meta, ok
+}
+
+// Names returns the sorted names of the metrics.
+func (catalog *MetricCatalog)Names() []string {
+	var names []string
+	for name := range catalog.Metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// BuiltinMetricCatalog converts a built-in metric table, keyed by metric
+// name with "Statistics", "Unit", "EDimensions", the extra dimensions, and
+// "SeriesByValue", "true" for the agent metrics.
+func BuiltinMetricCatalog(namespace string, table map[string]map[string]string) *MetricCatalog {
+	catalog := &MetricCatalog{
+		Namespace: namespace,
+		Source: CatalogBuiltin,
+		Metrics: map[string]*MetricMeta{},
+	}
+	for name, meta := range table {
+		catalog.Metrics[name] = &MetricMeta{
+			Namespace: namespace,
+			MetricName: name,
+			Unit: meta["Unit"],
+			Statistics: splitList(meta["Statistics"]),
+			Dimensions: append([]string{"userId", "instanceId"}, splitList(meta["EDimensions"])...),
+			SeriesByValue: meta["SeriesByValue"] == "true",
+		}
+	}
+	return catalog
+}
+
+type catalogFile struct {
+	Namespaces map[string]*MetricCatalog `json:"namespaces"`
+}
+
+var catalogLock sync.Mutex
+
+// Catalogs loaded from the API, keyed by file and namespace
+var catalogs = map[string]*MetricCatalog{}
+
+func catalogPath(path string) (string, error) {
+	if path == "" {
+		path = os.Getenv(EnvMetricCatalogFile)
+	}
+	if path != "" {
+		return path, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "provider-aliyun", "metric_catalog.json"), nil
+}
+
+func readCatalogFile(path string) catalogFile {
+	file := catalogFile{}
+	if content, err := ioutil.ReadFile(path); err == nil {
+		json.Unmarshal(content, &file)
+	}
+	if file.Namespaces == nil {
+		file.Namespaces = map[string]*MetricCatalog{}
+	}
+	return file
+}
+
+// writeCatalog stores catalog in the file at path, under its lock. The file
+// is read again under the lock, to keep the namespaces other processes
+// wrote meanwhile.
+func writeCatalog(path string, catalog *MetricCatalog) error {
+	unlock, err := LockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	file := readCatalogFile(path)
+	file.Namespaces[catalog.Namespace] = catalog
+	content, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, content)
+}
+
+// LoadMetricCatalog returns the catalog of namespace as chosen by the
+// MetricCatalogSchemes params of args. The catalog is loaded from
+// DescribeMetricMetaList and cached on disk for "metric_catalog_ttl"
+// seconds. When the API fails, a stale cache is used, then the built-in
+// table, if any.
+func LoadMetricCatalog(invoker *Invoker, credential input.Credential, namespace string, builtin map[string]map[string]string, args map[string]interface{}) (*MetricCatalog, error) {
+	catalogArgs := map[string]interface{}{}
+	for _, scheme := range MetricCatalogSchemes {
+		if val, ok := args[scheme.Param]; ok {
+			catalogArgs[scheme.Param] = val
+		}
+	}
+	catalogArgs, err := utils.CheckParam(catalogArgs, MetricCatalogSchemes)
+	if err != nil {
+		return nil, ParamError(err)
+	}
+	switch catalogArgs["metric_catalog"].(string) {
+	case "builtin":
+		if builtin == nil {
+			return nil, NewError(InvalidParam, "no built-in metrics of namespace %v", namespace)
+		}
+		return BuiltinMetricCatalog(namespace, builtin), nil
+	case "auto":
+	default:
+		return nil, NewError(InvalidParam, "bad metric_catalog %v", catalogArgs["metric_catalog"])
+	}
+	path, err := catalogPath(catalogArgs["metric_catalog_file"].(string))
+	if err != nil {
+		return nil, NewError(InvalidParam, "metric_catalog_file is required: %v", err)
+	}
+	ttl := int64(catalogArgs["metric_catalog_ttl"].(int))
+	now := time.Now().Unix()
+	key := path + "|" + namespace
+	catalogLock.Lock()
+	defer catalogLock.Unlock()
+	if catalog, ok := catalogs[key]; ok && catalog.Time + ttl > now {
+		return catalog, nil
+	}
+	cached := readCatalogFile(path).Namespaces[namespace]
+	if cached != nil && cached.Metrics != nil {
+		cached.Source = CatalogCache
+		seriesByValue(cached, builtin)
+		if cached.Time + ttl > now {
+			catalogs[key] = cached
+			return cached, nil
+		}
+	}
+	catalog, err := describeMetricMetas(invoker, credential, namespace)
+	if err == nil {
+		catalog.Time = now
+		seriesByValue(catalog, builtin)
+		// The catalog is still usable without its cache
+		writeCatalog(path, catalog)
+		catalogs[key] = catalog
+		return catalog, nil
+	}
+	if cached != nil && cached.Metrics != nil {
+		return cached, nil
+	}
+	if builtin != nil {
+		return BuiltinMetricCatalog(namespace, builtin), nil
+	}
+	return nil, err
+}
+
+// seriesByValue sets SeriesByValue on the metrics of a catalog loaded from
+// the API, which does not tell the agent metrics apart, as in builtin. The
+// metrics missing in builtin, as those of the namespaces without built-in
+// table, name their series by value, so that the series of an instance
+// differing only by a dimension, as the port of an SLB, keep their own index.
+func seriesByValue(catalog *MetricCatalog, builtin map[string]map[string]string) {
+	for name, meta := range catalog.Metrics {
+		table, ok := builtin[name]
+		meta.SeriesByValue = !ok || table["SeriesByValue"] == "true"
+	}
+}
+
+func describeMetricMetas(invoker *Invoker, credential input.Credential, namespace string) (*MetricCatalog, error) {
+	client, err := CmsClient(invoker, credential, "")
+	if err != nil {
+		return nil, err
+	}
+	defer ReleaseClient(client)
+	catalog := &MetricCatalog{
+		Namespace: namespace,
+		Source: CatalogApi,
+		Metrics: map[string]*MetricMeta{},
+	}
+	for page := int32(1); ; page++ {
+		request := &cms20190101.DescribeMetricMetaListRequest{
+			Namespace: tea.String(namespace),
+			PageNumber: tea.Int32(page),
+			PageSize: tea.Int32(metricMetaPageSize),
+		}
+		var resp *cms20190101.DescribeMetricMetaListResponse
+		err = invoker.Invoke(ProductCms, "", "DescribeMetricMetaList", func() (err error) {
+			resp, err = client.DescribeMetricMetaList(request)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp.Body.Success == nil || !(*resp.Body.Success) {
+			return nil, ResponseError(ProductCms, "", "DescribeMetricMetaList", utils.SafeString(resp.Body.Code),
+				utils.SafeString(resp.Body.Message), utils.SafeString(resp.Body.RequestId))
+		}
+		if resp.Body.Resources == nil {
+			return nil, NewError(Upstream, "bad response for query metric metas")
+		}
+		for _, b := range resp.Body.Resources.Resource {
+			meta := &MetricMeta{
+				Namespace: namespace,
+				MetricName: utils.SafeString(b.MetricName),
+				Description: utils.SafeString(b.Description),
+				Unit: utils.SafeString(b.Unit),
+				Statistics: splitList(utils.SafeString(b.Statistics)),
+				Dimensions: splitList(utils.SafeString(b.Dimensions)),
+				Periods: splitList(utils.SafeString(b.Periods)),
+			}
+			catalog.Metrics[meta.MetricName] = meta
+		}
+		total, _ := strconv.Atoi(utils.SafeString(resp.Body.TotalCount))
+		if len(resp.Body.Resources.Resource) == 0 || int(page * metricMetaPageSize) >= total {
+			break
+		}
+	}
+	if len(catalog.Metrics) == 0 {
+		return nil, NewError(InvalidParam, "no metrics in namespace %v", namespace)
+	}
+	return catalog, nil
+}
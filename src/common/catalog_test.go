@@ -0,0 +1,171 @@
+package common_test
+
+import (
+	"encoding/json"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const catalogNamespace = "acs_ecs_dashboard"
+
+var builtinMetrics = map[string]map[string]string{
+	"CPUUtilization": {"Statistics": "Average", "Unit": "%"},
+}
+
+// loadCatalog loads the catalog of catalogNamespace cached in path.
+func loadCatalog(t *testing.T, path string, builtin map[string]map[string]string) (*common.MetricCatalog, error) {
+	t.Helper()
+	common.UseLog(common.LogOff)
+	return common.LoadMetricCatalog(background, fakeapi.Credential, catalogNamespace, builtin, map[string]interface{}{
+		"metric_catalog_file": path,
+		"metric_catalog_ttl": 3600,
+	})
+}
+
+// cacheCatalog writes a catalog of catalogNamespace loaded at time, and
+// one of the namespace "other", to the file at path.
+func cacheCatalog(t *testing.T, path string, loaded time.Time) {
+	file := map[string]interface{}{
+		"namespaces": map[string]*common.MetricCatalog{
+			catalogNamespace: &common.MetricCatalog{
+				Namespace: catalogNamespace,
+				Source: common.CatalogApi,
+				Time: loaded.Unix(),
+				Metrics: map[string]*common.MetricMeta{
+					"cached_metric": &common.MetricMeta{Namespace: catalogNamespace, MetricName: "cached_metric"},
+				},
+			},
+			"other": &common.MetricCatalog{
+				Namespace: "other",
+				Source: common.CatalogApi,
+				Time: loaded.Unix(),
+				Metrics: map[string]*common.MetricMeta{},
+			},
+		},
+	}
+	content, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// cachedNamespaces returns the catalogs of the file at path.
+func cachedNamespaces(t *testing.T, path string) map[string]*common.MetricCatalog {
+	file := struct {
+		Namespaces map[string]*common.MetricCatalog `json:"namespaces"`
+	}{}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(content, &file); err != nil {
+		t.Fatal(err)
+	}
+	return file.Namespaces
+}
+
+func TestMetricCatalogApi(t *testing.T) {
+	server := fakeapi.Start(t)
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	catalog, err := loadCatalog(t, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := catalog.Lookup("CPUUtilization"); catalog.Source != common.CatalogApi || !ok {
+		t.Errorf("unexpected catalog %+v", catalog)
+	}
+	// Within the TTL, the catalog is not loaded again
+	if catalog, err = loadCatalog(t, path, nil); err != nil || catalog.Source != common.CatalogApi {
+		t.Errorf("got %+v, %v", catalog, err)
+	}
+	if n := len(server.Requests("DescribeMetricMetaList")); n != 1 {
+		t.Errorf("%v DescribeMetricMetaList requests, want 1", n)
+	}
+	if cached := cachedNamespaces(t, path)[catalogNamespace]; cached == nil || cached.Metrics["CPUUtilization"] == nil {
+		t.Errorf("catalog not cached in %v", path)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("cache file %v, %v", info, err)
+	}
+	if matches, _ := filepath.Glob(path + ".*.tmp"); len(matches) > 0 {
+		t.Errorf("temp files left %v", matches)
+	}
+}
+
+func TestMetricCatalogCache(t *testing.T) {
+	server := fakeapi.Start(t)
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	cacheCatalog(t, path, time.Now().Add(-time.Minute))
+	catalog, err := loadCatalog(t, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := catalog.Lookup("cached_metric"); catalog.Source != common.CatalogCache || !ok {
+		t.Errorf("unexpected catalog %+v", catalog)
+	}
+	if n := len(server.Requests("DescribeMetricMetaList")); n != 0 {
+		t.Errorf("%v DescribeMetricMetaList requests with a fresh cache", n)
+	}
+}
+
+func TestMetricCatalogStale(t *testing.T) {
+	server := fakeapi.Start(t)
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	cacheCatalog(t, path, time.Now().Add(-2 * time.Hour))
+	catalog, err := loadCatalog(t, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := catalog.Lookup("CPUUtilization"); catalog.Source != common.CatalogApi || !ok {
+		t.Errorf("unexpected catalog %+v", catalog)
+	}
+	if n := len(server.Requests("DescribeMetricMetaList")); n != 1 {
+		t.Errorf("%v DescribeMetricMetaList requests with a stale cache, want 1", n)
+	}
+	namespaces := cachedNamespaces(t, path)
+	if cached := namespaces[catalogNamespace]; cached == nil || cached.Time < time.Now().Add(-time.Minute).Unix() {
+		t.Errorf("stale catalog not replaced: %+v", cached)
+	}
+	if namespaces["other"] == nil {
+		t.Error("the catalog of another namespace is dropped")
+	}
+}
+
+func TestMetricCatalogStaleFallback(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Fail("DescribeMetricMetaList", 1, 403, "Forbidden", "User not authorized to operate on the specified resource.")
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	cacheCatalog(t, path, time.Now().Add(-2 * time.Hour))
+	catalog, err := loadCatalog(t, path, builtinMetrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := catalog.Lookup("cached_metric"); catalog.Source != common.CatalogCache || !ok {
+		t.Errorf("got %+v, want the stale cache", catalog)
+	}
+}
+
+func TestMetricCatalogBuiltinFallback(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Fail("DescribeMetricMetaList", 1, 403, "Forbidden", "User not authorized to operate on the specified resource.")
+	catalog, err := loadCatalog(t, filepath.Join(t.TempDir(), "catalog.json"), builtinMetrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta, ok := catalog.Lookup("CPUUtilization"); catalog.Source != common.CatalogBuiltin || !ok || meta.Unit != "%" {
+		t.Errorf("got %+v, want the built-in catalog", catalog)
+	}
+	server.Fail("DescribeMetricMetaList", 1, 403, "Forbidden", "User not authorized to operate on the specified resource.")
+	if _, err = loadCatalog(t, filepath.Join(t.TempDir(), "catalog.json"), nil); common.ErrorCodeOf(err) != common.Forbidden {
+		t.Errorf("got %v without built-in metrics, want a Forbidden error", err)
+	}
+}
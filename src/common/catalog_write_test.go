@@ -0,0 +1,37 @@
+package common
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// Concurrent writers of the catalog file keep the namespaces of each other,
+// as the processes sharing it.
+func TestWriteCatalogConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "catalog.json")
+	wg := sync.WaitGroup{}
+	errs := make([]error, 16)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = writeCatalog(path, &MetricCatalog{
+				Namespace: fmt.Sprintf("namespace-%v", i),
+				Source: CatalogApi,
+				Metrics: map[string]*MetricMeta{},
+			})
+		}(i)
+	}
+	wg.Wait()
+	namespaces := readCatalogFile(path).Namespaces
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("writer %v: %v", i, err)
+		}
+		if namespaces[fmt.Sprintf("namespace-%v", i)] == nil {
+			t.Errorf("namespace-%v lost", i)
+		}
+	}
+}
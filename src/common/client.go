@@ -0,0 +1,229 @@
+package common
+
+import (
+	"errors"
+	bssopenapi20171214 "github.com/alibabacloud-go/bssopenapi-20171214/client"
+	cms20190101 "github.com/alibabacloud-go/cms-20190101/v2/client"
+	openapi "github.com/alibabacloud-go/darabonba-openapi/client"
+	ecs20140526 "github.com/alibabacloud-go/ecs-20140526/v2/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hahaps/common-provider/src/input"
+	"sync"
+)
+
+// ClientBuilder creates the SDK client of a product from its config.
+type ClientBuilder func(config *openapi.Config) (interface{}, error)
+
+type clientKey struct {
+	Identity string
+	Product string
+	Endpoint string
+}
+
+var clientBuilders = map[string]ClientBuilder{
+	ProductEcs: func(config *openapi.Config) (interface{}, error) {
+		return ecs20140526.NewClient(config)
+	},
+	ProductCms: func(config *openapi.Config) (interface{}, error) {
+		return cms20190101.NewClient(config)
+	},
+	ProductBss: func(config *openapi.Config) (interface{}, error) {
+		return bssopenapi20171214.NewClient(config)
+	},
+	ProductVpc: func(config *openapi.Config) (interface{}, error) {
+		return NewVpcApiClient(config)
+	},
+	ProductResourceManager: func(config *openapi.Config) (interface{}, error) {
+		return NewResourceManagerApiClient(config)
+	},
+}
+
+var clientLock = sync.Mutex{}
+
+// clientPool holds the idle clients of a key, built with the AccessKeyId
+// and token of the pool
+type clientPool struct {
+	key clientKey
+	accessKeyId string
+	securityToken string
+	idle []interface{}
+}
+
+var clientPools = map[clientKey]*clientPool{}
+
+// Clients checked out by GetClient, to the pool they return to
+var checkedOut = map[interface{}]*clientPool{}
+
+// SetClientBuilder replaces the builder of product and drops its pooled
+// clients, so tests can inject fake clients. The previous builder is
+// returned to be restored afterwards.
+func SetClientBuilder(product string, builder ClientBuilder) ClientBuilder {
+	clientLock.Lock()
+	defer clientLock.Unlock()
+	previous := clientBuilders[product]
+	clientBuilders[product] = builder
+	for key := range clientPools {
+		if key.Product == product {
+			delete(clientPools, key)
+		}
+	}
+	return previous
+}
+
+// CredentialError types the failures of GetAccessKey as AuthFailed.
+func CredentialError(err error) error {
+	if typed := (*Error)(nil); errors.As(err, &typed) {
+		return err
+	}
+	return &Error{
+		Code: AuthFailed,
+		Message: err.Error(),
+		Err: err,
+	}
+}
+
+// ResetClients drops every pooled client.
+func ResetClients() {
+	clientLock.Lock()
+	defer clientLock.Unlock()
+	clientPools = map[clientKey]*clientPool{}
+}
+
+// GetClient checks out a client of product in region from the pool of its
+// AccessKey, product and endpoint, building one when none is idle. The SDK
+// clients write their headers on every request and are not safe for
+// concurrent use: a client serves one caller until ReleaseClient returns it
+// to the pool. The pool is dropped when a temporary token is refreshed.
+func GetClient(invoker *Invoker, credential input.Credential, product, region string) (interface{}, error) {
+	accessKey, err := GetAccessKey(invoker, credential)
+	if err != nil {
+		return nil, CredentialError(err)
+	}
+	config, err := newConfig(accessKey, product, region)
+	if err != nil {
+		return nil, NewError(Internal, "%v", err)
+	}
+	key := clientKey{
+		Identity: accessKey.Identity,
+		Product: product,
+		Endpoint: tea.StringValue(config.Protocol) + "://" + tea.StringValue(config.Endpoint),
+	}
+	clientLock.Lock()
+	defer clientLock.Unlock()
+	pool, ok := clientPools[key]
+	if !ok || pool.accessKeyId != accessKey.AccessKeyId || pool.securityToken != accessKey.SecurityToken {
+		pool = &clientPool{
+			key: key,
+			accessKeyId: accessKey.AccessKeyId,
+			securityToken: accessKey.SecurityToken,
+		}
+		clientPools[key] = pool
+	}
+	var cli interface{}
+	if n := len(pool.idle); n > 0 {
+		cli, pool.idle = pool.idle[n - 1], pool.idle[:n - 1]
+	} else {
+		builder, ok := clientBuilders[product]
+		if !ok {
+			return nil, NewError(Internal, "no client builder for product %v", product)
+		}
+		cli, err = builder(config)
+		if err != nil {
+			return nil, NewError(Internal, "%v", err)
+		}
+	}
+	checkedOut[cli] = pool
+	return cli, nil
+}
+
+// ReleaseClient returns a client of GetClient to its pool. The clients of a
+// dropped pool are discarded.
+func ReleaseClient(cli interface{}) {
+	clientLock.Lock()
+	defer clientLock.Unlock()
+	pool, ok := checkedOut[cli]
+	if !ok {
+		return
+	}
+	delete(checkedOut, cli)
+	if clientPools[pool.key] == pool {
+		pool.idle = append(pool.idle, cli)
+	}
+}
+
+func EcsClient(invoker *Invoker, credential input.Credential, region string) (*ecs20140526.Client, error) {
+	cli, err := GetClient(invoker, credential, ProductEcs, region)
+	if err != nil {
+		return nil, err
+	}
+	ecsClient, ok := cli.(*ecs20140526.Client)
+	if !ok {
+		return nil, NewError(Internal, "bad client type %T for product %v", cli, ProductEcs)
+	}
+	return ecsClient, nil
+}
+
+func CmsClient(invoker *Invoker, credential input.Credential, region string) (*cms20190101.Client, error) {
+	cli, err := GetClient(invoker, credential, ProductCms, region)
+	if err != nil {
+		return nil, err
+	}
+	cmsClient, ok := cli.(*cms20190101.Client)
+	if !ok {
+		return nil, NewError(Internal, "bad client type %T for product %v", cli, ProductCms)
+	}
+	return cmsClient, nil
+}
+
+// CmsClients checks out n clients of CMS in region, one for each of n
+// concurrent workers, to be released with ReleaseClient.
+func CmsClients(invoker *Invoker, credential input.Credential, region string, n int) ([]*cms20190101.Client, error) {
+	var cmsClients []*cms20190101.Client
+	for i := 0; i < n; i++ {
+		cmsClient, err := CmsClient(invoker, credential, region)
+		if err != nil {
+			for _, cli := range cmsClients {
+				ReleaseClient(cli)
+			}
+			return nil, err
+		}
+		cmsClients = append(cmsClients, cmsClient)
+	}
+	return cmsClients, nil
+}
+
+func BssClient(invoker *Invoker, credential input.Credential) (*bssopenapi20171214.Client, error) {
+	cli, err := GetClient(invoker, credential, ProductBss, "")
+	if err != nil {
+		return nil, err
+	}
+	bssClient, ok := cli.(*bssopenapi20171214.Client)
+	if !ok {
+		return nil, NewError(Internal, "bad client type %T for product %v", cli, ProductBss)
+	}
+	return bssClient, nil
+}
+
+func VpcClient(invoker *Invoker, credential input.Credential, region string) (*VpcApiClient, error) {
+	cli, err := GetClient(invoker, credential, ProductVpc, region)
+	if err != nil {
+		return nil, err
+	}
+	vpcClient, ok := cli.(*VpcApiClient)
+	if !ok {
+		return nil, NewError(Internal, "bad client type %T for product %v", cli, ProductVpc)
+	}
+	return vpcClient, nil
+}
+
+func ResourceManagerClient(invoker *Invoker, credential input.Credential) (*ResourceManagerApiClient, error) {
+	cli, err := GetClient(invoker, credential, ProductResourceManager, "")
+	if err != nil {
+		return nil, err
+	}
+	rmClient, ok := cli.(*ResourceManagerApiClient)
+	if !ok {
+		return nil, NewError(Internal, "bad client type %T for product %v", cli, ProductResourceManager)
+	}
+	return rmClient, nil
+}
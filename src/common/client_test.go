@@ -0,0 +1,116 @@
+package common_test
+
+import (
+	openapi "github.com/alibabacloud-go/darabonba-openapi/client"
+	ecs20140526 "github.com/alibabacloud-go/ecs-20140526/v2/client"
+	"github.com/hahaps/common-provider/src/input"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"sync"
+	"testing"
+)
+
+func TestGetClientPool(t *testing.T) {
+	common.ResetClients()
+	first, err := common.EcsClient(background, fakeapi.Credential, fakeapi.Region)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := common.EcsClient(background, fakeapi.Credential, fakeapi.Region)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Error("a checked out client is handed out again")
+	}
+	common.ReleaseClient(first)
+	common.ReleaseClient(second)
+	third, err := common.EcsClient(background, fakeapi.Credential, fakeapi.Region)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer common.ReleaseClient(third)
+	if third != first && third != second {
+		t.Error("a released client is not reused")
+	}
+	other, err := common.EcsClient(background, input.Credential{SecretId: "other-id", SecretKey: "other-key"}, fakeapi.Region)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer common.ReleaseClient(other)
+	if other == first || other == second {
+		t.Error("a client is shared by two AccessKeys")
+	}
+	clients, err := common.CmsClients(background, fakeapi.Credential, fakeapi.Region, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clients) != 2 || clients[0] == clients[1] {
+		t.Errorf("workers share a client in %v", clients)
+	}
+	for _, cli := range clients {
+		common.ReleaseClient(cli)
+	}
+}
+
+func TestGetClientReset(t *testing.T) {
+	common.ResetClients()
+	first, err := common.EcsClient(background, fakeapi.Credential, fakeapi.Region)
+	if err != nil {
+		t.Fatal(err)
+	}
+	common.ResetClients()
+	common.ReleaseClient(first)
+	second, err := common.EcsClient(background, fakeapi.Credential, fakeapi.Region)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer common.ReleaseClient(second)
+	if first == second {
+		t.Error("a client of a dropped pool is reused")
+	}
+}
+
+// Run with -race: the clients write their headers on every request.
+func TestGetClientConcurrent(t *testing.T) {
+	server := fakeapi.NewServer()
+	server.LoadDefaults()
+	server.Install()
+	defer server.Close()
+	common.UseLog(common.LogOff)
+	built := 0
+	lock := sync.Mutex{}
+	previous := common.SetClientBuilder(common.ProductEcs, func(config *openapi.Config) (interface{}, error) {
+		lock.Lock()
+		built++
+		lock.Unlock()
+		return ecs20140526.NewClient(config)
+	})
+	defer common.SetClientBuilder(common.ProductEcs, previous)
+	wg := sync.WaitGroup{}
+	errs := make([]error, 8)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 5 && errs[i] == nil; j++ {
+				client, err := common.EcsClient(background, fakeapi.Credential, fakeapi.Region)
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				_, errs[i] = client.DescribeRegions(&ecs20140526.DescribeRegionsRequest{})
+				common.ReleaseClient(client)
+			}
+		}(i)
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("caller %v: %v", i, err)
+		}
+	}
+	if built > len(errs) {
+		t.Errorf("%v clients built for %v callers", built, len(errs))
+	}
+}
@@ -0,0 +1,267 @@
+package common
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	cms20190101 "github.com/alibabacloud-go/cms-20190101/v2/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hahaps/common-provider/src/common/utils"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const metricCursorPrefix string = "m1."
+
+// Datapoints per DescribeMetricList page
+const MetricListLength string = "1000"
+
+// Dimensions accepted by CMS in one metric query
+const MaxMetricDimensions int = 50
+
+// Default of the "concurrency" param of the metric resources
+const DefaultMetricConcurrency int = 4
+
+var metricTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// MetricCursor is the position of a metric resource collecting a time
+// window: the marker of the inventory page whose instances are queried,
+// the index of the metric name and of the chunk of instances, and the
+// DescribeMetricList NextToken.
+type MetricCursor struct {
+	Marker string `json:"m"`
+	Metric int `json:"i,omitempty"`
+	// Index of the chunk of dimensions, see MetricDimensions
+	Chunk int `json:"c,omitempty"`
+	Token string `json:"t,omitempty"`
+}
+
+// ParseMetricCursor restores the cursor of marker. Other markers are taken
+// as the marker of the inventory page, the empty one as its first page.
+func ParseMetricCursor(marker string) (MetricCursor, error) {
+	cursor := MetricCursor{Marker: marker}
+	if marker == "" {
+		cursor.Marker = "1"
+	}
+	if !strings.HasPrefix(marker, metricCursorPrefix) {
+		return cursor, nil
+	}
+	content, err := base64.RawURLEncoding.DecodeString(marker[len(metricCursorPrefix):])
+	if err != nil || json.Unmarshal(content, &cursor) != nil || cursor.Marker == "" || cursor.Metric < 0 || cursor.Chunk < 0 {
+		return cursor, NewError(InvalidParam, "bad marker %v", marker)
+	}
+	return cursor, nil
+}
+
+func (cursor MetricCursor)String() string {
+	content, _ := json.Marshal(cursor)
+	return metricCursorPrefix + base64.RawURLEncoding.EncodeToString(content)
+}
+
+// Next returns the marker following the page of cursor, given the NextToken
+// of its response, the counts of chunks of dimensions and of metric names,
+// and the marker following the inventory page. "" marks the end.
+func (cursor MetricCursor)Next(token string, chunks, metrics int, inventoryNext string) string {
+	switch {
+	case token != "":
+		cursor.Token = token
+	case cursor.Chunk + 1 < chunks:
+		cursor.Chunk, cursor.Token = cursor.Chunk + 1, ""
+	case cursor.Metric + 1 < metrics:
+		cursor.Metric, cursor.Chunk, cursor.Token = cursor.Metric + 1, 0, ""
+	case inventoryNext != "":
+		cursor = MetricCursor{Marker: inventoryNext}
+	default:
+		return ""
+	}
+	return cursor.String()
+}
+
+// MetricTime converts a time of the "start_time" and "end_time" params, in
+// RFC3339, "2006-01-02 15:04:05" or milliseconds since the epoch, to the
+// milliseconds expected by CMS.
+func MetricTime(value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return millis, nil
+	}
+	for _, layout := range metricTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t.UnixNano() / int64(time.Millisecond), nil
+		}
+	}
+	return 0, NewError(InvalidParam, "bad time %v", value)
+}
+
+// MetricWindow reads the "start_time" and "end_time" params, in
+// milliseconds. An unset "end_time" is now. ok is false when "start_time"
+// is unset, for the last datapoints.
+func MetricWindow(args map[string]interface{}) (start, end int64, ok bool, err error) {
+	startTime, _ := args["start_time"].(string)
+	endTime, _ := args["end_time"].(string)
+	if startTime == "" {
+		if endTime != "" {
+			return 0, 0, false, NewError(InvalidParam, "Param[end_time] requires Param[start_time]")
+		}
+		return 0, 0, false, nil
+	}
+	if start, err = MetricTime(startTime); err != nil {
+		return 0, 0, false, err
+	}
+	end = time.Now().UnixNano() / int64(time.Millisecond)
+	if endTime != "" {
+		if end, err = MetricTime(endTime); err != nil {
+			return 0, 0, false, err
+		}
+	}
+	if end <= start {
+		return 0, 0, false, NewError(InvalidParam, "Param[end_time] should be after Param[start_time]")
+	}
+	return start, end, true, nil
+}
+
+// MetricQuery queries a metric for a chunk of dimensions.
+type MetricQuery struct {
+	MetricName string
+	// JSON list of dimensions
+	Dimensions string
+}
+
+// MetricDimensions marshals dimensions, as {"instanceId": "i-1"}, into JSON
+// lists of at most size dimensions.
+func MetricDimensions(dimensions []map[string]string, size int) []string {
+	if size <= 0 {
+		size = MaxMetricDimensions
+	}
+	var chunks []string
+	for start := 0; start < len(dimensions); start += size {
+		end := start + size
+		if end > len(dimensions) {
+			end = len(dimensions)
+		}
+		content, _ := json.Marshal(dimensions[start:end])
+		chunks = append(chunks, string(content))
+	}
+	return chunks
+}
+
+// PlanMetricQueries queries each metric for each chunk of dimensions.
+func PlanMetricQueries(metricNames []string, chunks []string) []MetricQuery {
+	var queries []MetricQuery
+	for _, metricName := range metricNames {
+		for _, chunk := range chunks {
+			queries = append(queries, MetricQuery{MetricName: metricName, Dimensions: chunk})
+		}
+	}
+	return queries
+}
+
+// RunMetricQueries runs the queries with at most concurrency workers, and
+// returns their datapoints in the order of queries. run is given the index
+// of its worker. The queries not started yet are skipped after a failure,
+// the error of the first failed query in order is returned.
+func RunMetricQueries(queries []MetricQuery, concurrency int, run func(worker int, query MetricQuery) ([]map[string]interface{}, error)) ([][]map[string]interface{}, error) {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	results := make([][]map[string]interface{}, len(queries))
+	errs := make([]error, len(queries))
+	failed := false
+	lock := sync.Mutex{}
+	workers := make(chan int, concurrency)
+	for worker := 0; worker < concurrency; worker++ {
+		workers <- worker
+	}
+	wg := sync.WaitGroup{}
+	for i := range queries {
+		worker := <-workers
+		lock.Lock()
+		stop := failed
+		lock.Unlock()
+		if stop {
+			break
+		}
+		wg.Add(1)
+		go func(i, worker int) {
+			defer func() {
+				workers <- worker
+				wg.Done()
+			}()
+			result, err := run(worker, queries[i])
+			lock.Lock()
+			defer lock.Unlock()
+			results[i], errs[i] = result, err
+			failed = failed || err != nil
+		}(i, worker)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return results, nil
+}
+
+// DescribeMetricLast returns the datapoints of a DescribeMetricLast query,
+// following its NextToken: a query without dimensions returns every
+// instance of the namespace, over several pages on large accounts.
+func DescribeMetricLast(invoker *Invoker, client *cms20190101.Client, region string, request *cms20190101.DescribeMetricLastRequest) ([]map[string]interface{}, error) {
+	var dataPoints []map[string]interface{}
+	for {
+		var resp *cms20190101.DescribeMetricLastResponse
+		err := invoker.Invoke(ProductCms, region, "DescribeMetricLast", func() (err error) {
+			resp, err = client.DescribeMetricLast(request)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if resp.Body.Success == nil || !(*resp.Body.Success) {
+			return nil, ResponseError(ProductCms, region, "DescribeMetricLast", utils.SafeString(resp.Body.Code),
+				utils.SafeString(resp.Body.Message), utils.SafeString(resp.Body.RequestId))
+		}
+		if datapoints := utils.SafeString(resp.Body.Datapoints); datapoints != "" {
+			var page []map[string]interface{}
+			if err = json.Unmarshal([]byte(datapoints), &page); err != nil {
+				return nil, NewError(Upstream, "bad datapoints of metric %v: %v", utils.SafeString(request.MetricName), err)
+			}
+			dataPoints = append(dataPoints, page...)
+		}
+		nextToken := utils.SafeString(resp.Body.NextToken)
+		if nextToken == "" {
+			return dataPoints, nil
+		}
+		request.NextToken = tea.String(nextToken)
+	}
+}
+
+// DescribeMetricList returns the datapoints of a DescribeMetricList page and
+// the NextToken of the following one.
+func DescribeMetricList(invoker *Invoker, client *cms20190101.Client, region string, request *cms20190101.DescribeMetricListRequest) ([]map[string]interface{}, string, error) {
+	var resp *cms20190101.DescribeMetricListResponse
+	err := invoker.Invoke(ProductCms, region, "DescribeMetricList", func() (err error) {
+		resp, err = client.DescribeMetricList(request)
+		return err
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if resp.Body.Success == nil || !(*resp.Body.Success) {
+		return nil, "", ResponseError(ProductCms, region, "DescribeMetricList", utils.SafeString(resp.Body.Code),
+			utils.SafeString(resp.Body.Message), utils.SafeString(resp.Body.RequestId))
+	}
+	var dataPoints []map[string]interface{}
+	if datapoints := utils.SafeString(resp.Body.Datapoints); datapoints != "" {
+		if err = json.Unmarshal([]byte(datapoints), &dataPoints); err != nil {
+			return nil, "", NewError(Upstream, "bad datapoints of metric %v: %v", utils.SafeString(request.MetricName), err)
+		}
+	}
+	return dataPoints, utils.SafeString(resp.Body.NextToken), nil
+}
@@ -0,0 +1,285 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hahaps/common-provider/src/common/utils"
+	"github.com/hahaps/common-provider/src/input"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Keys of input.Credential.Extra used to assume a RAM role
+const (
+	ExtraRoleArn string = "role_arn"
+	ExtraRoleSessionName string = "role_session_name"
+	ExtraRoleSessionDuration string = "role_session_duration"
+	ExtraRolePolicy string = "role_policy"
+	ExtraStsRegion string = "sts_region"
+)
+
+const DefaultRoleSessionName string = "cloudtracker"
+
+const DefaultRoleSessionDuration int64 = 3600
+
+// Temporary tokens are refreshed when they expire within this window
+var RoleRefreshWindow = 5 * time.Minute
+
+type AccessKey struct {
+	// Stable name of where the key comes from, used to share clients
+	Identity string
+	AccessKeyId string
+	AccessKeySecret string
+	SecurityToken string
+	// Zero for long-lived AccessKeys
+	Expiration time.Time
+}
+
+func (key *AccessKey)Expired(window time.Duration) bool {
+	if key.Expiration.IsZero() {
+		return false
+	}
+	return time.Now().Add(window).After(key.Expiration)
+}
+
+type RoleConfig struct {
+	Arn string
+	SessionName string
+	Duration int64
+	Policy string
+	Region string
+}
+
+// Env var of the file caching the role tokens across processes, the tokens
+// being kept in memory only when unset or "off"
+const EnvRoleCacheFile = "ALIBABA_CLOUD_ROLE_CACHE_FILE"
+
+const RoleCacheOff string = "off"
+
+// roleCall is an AssumeRole request in progress, shared by the concurrent
+// calls for its role
+type roleCall struct {
+	done chan struct{}
+	key *AccessKey
+	err error
+}
+
+var roleLock = sync.Mutex{}
+
+var roleKeys = map[string]*AccessKey{}
+
+var roleCalls = map[string]*roleCall{}
+
+func extraString(credential input.Credential, key string) string {
+	if credential.Extra == nil || credential.Extra[key] == nil {
+		return ""
+	}
+	return fmt.Sprint(credential.Extra[key])
+}
+
+// GetRoleConfig reads the role to assume from credential.Extra, nil if the
+// credential does not assume any role.
+func GetRoleConfig(credential input.Credential) (*RoleConfig, error) {
+	arn := extraString(credential, ExtraRoleArn)
+	if arn == "" {
+		return nil, nil
+	}
+	role := &RoleConfig{
+		Arn: arn,
+		SessionName: extraString(credential, ExtraRoleSessionName),
+		Duration: DefaultRoleSessionDuration,
+		Policy: extraString(credential, ExtraRolePolicy),
+		Region: extraString(credential, ExtraStsRegion),
+	}
+	if role.SessionName == "" {
+		role.SessionName = DefaultRoleSessionName
+	}
+	if duration := extraString(credential, ExtraRoleSessionDuration); duration != "" {
+		seconds, err := strconv.ParseFloat(duration, 64)
+		if err != nil || seconds <= 0 {
+			return nil, errors.New("bad role session duration " + duration)
+		}
+		role.Duration = int64(seconds)
+	}
+	return role, nil
+}
+
+// GetAccessKey returns the AccessKey used to sign requests of credential,
+// assuming the configured RAM role if any through invoker. Credentials without
+// SecretId/SecretKey are looked up through the CredentialChain.
+func GetAccessKey(invoker *Invoker, credential input.Credential) (*AccessKey, error) {
+	var key *AccessKey
+	var err error
+	if credential.SecretId != "" || credential.SecretKey != "" {
+		key = &AccessKey{
+			Identity: credential.SecretId,
+			AccessKeyId: credential.SecretId,
+			AccessKeySecret: credential.SecretKey,
+		}
+	} else {
+		key, err = RetrieveAccessKey(invoker, credential)
+		if err != nil {
+			return nil, err
+		}
+	}
+	role, err := GetRoleConfig(credential)
+	if err != nil || role == nil {
+		return key, err
+	}
+	return AssumeRole(invoker, key, role)
+}
+
+// AssumeRole exchanges key for a temporary token of role through invoker,
+// under the timeout and rate limits of its Call. Tokens are cached in
+// memory, and in the RoleCacheFile shared by the provider processes if any,
+// until they get close to their expiration. Concurrent calls for the same
+// role share one STS request, the others do not wait for it.
+func AssumeRole(invoker *Invoker, key *AccessKey, role *RoleConfig) (*AccessKey, error) {
+	cacheKey := fmt.Sprintf("%v|%v|%v|%v|%v", key.Identity, key.AccessKeyId, role.Arn, role.SessionName, role.Policy)
+	roleLock.Lock()
+	if cached, ok := roleKeys[cacheKey]; ok && !cached.Expired(RoleRefreshWindow) {
+		roleLock.Unlock()
+		return cached, nil
+	}
+	if call, ok := roleCalls[cacheKey]; ok {
+		roleLock.Unlock()
+		<-call.done
+		return call.key, call.err
+	}
+	call := &roleCall{done: make(chan struct{})}
+	roleCalls[cacheKey] = call
+	roleLock.Unlock()
+
+	call.key = readRoleKey(cacheKey)
+	if call.key == nil {
+		call.key, call.err = assumeRole(invoker, key, role)
+		if call.err == nil {
+			writeRoleKey(cacheKey, call.key)
+		}
+	}
+	roleLock.Lock()
+	delete(roleCalls, cacheKey)
+	if call.err == nil {
+		roleKeys[cacheKey] = call.key
+	}
+	roleLock.Unlock()
+	close(call.done)
+	return call.key, call.err
+}
+
+func assumeRole(invoker *Invoker, key *AccessKey, role *RoleConfig) (*AccessKey, error) {
+	config, err := newConfig(key, ProductSts, role.Region)
+	if err != nil {
+		return nil, err
+	}
+	cli, err := NewStsClient(config)
+	if err != nil {
+		return nil, err
+	}
+	request := &AssumeRoleRequest{
+		RoleArn: tea.String(role.Arn),
+		RoleSessionName: tea.String(role.SessionName),
+		DurationSeconds: tea.Int64(role.Duration),
+	}
+	if role.Policy != "" {
+		request.Policy = tea.String(role.Policy)
+	}
+	var resp *AssumeRoleResponse
+	err = invoker.Invoke(ProductSts, role.Region, "AssumeRole", func() (err error) {
+		resp, err = cli.AssumeRole(request)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Body == nil || resp.Body.Credentials == nil {
+		return nil, errors.New("bad response for assume role " + role.Arn)
+	}
+	credentials := resp.Body.Credentials
+	assumed := &AccessKey{
+		Identity: key.Identity + "|" + role.Arn,
+		AccessKeyId: utils.SafeString(credentials.AccessKeyId),
+		AccessKeySecret: utils.SafeString(credentials.AccessKeySecret),
+		SecurityToken: utils.SafeString(credentials.SecurityToken),
+	}
+	assumed.Expiration, err = time.Parse(time.RFC3339, utils.SafeString(credentials.Expiration))
+	if err != nil {
+		return nil, errors.New("bad expiration for assume role " + role.Arn)
+	}
+	return assumed, nil
+}
+
+// ResetRoleKeys drops the tokens cached in memory.
+func ResetRoleKeys() {
+	roleLock.Lock()
+	defer roleLock.Unlock()
+	roleKeys = map[string]*AccessKey{}
+}
+
+// RoleCacheFile returns the file caching the tokens across processes, ""
+// when disabled, the default: the file holds their secrets in plain text.
+func RoleCacheFile() string {
+	path := os.Getenv(EnvRoleCacheFile)
+	if path == RoleCacheOff {
+		return ""
+	}
+	return path
+}
+
+// Entries of the role cache file are keyed by the hash of the cache key,
+// which holds the AccessKeyId
+func roleFileKey(cacheKey string) string {
+	sum := sha256.Sum256([]byte(cacheKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func readRoleKeys(path string) map[string]*AccessKey {
+	keys := map[string]*AccessKey{}
+	if content, err := ioutil.ReadFile(path); err == nil {
+		json.Unmarshal(content, &keys)
+	}
+	return keys
+}
+
+func readRoleKey(cacheKey string) *AccessKey {
+	path := RoleCacheFile()
+	if path == "" {
+		return nil
+	}
+	cached, ok := readRoleKeys(path)[roleFileKey(cacheKey)]
+	if !ok || cached == nil || cached.Expired(RoleRefreshWindow) {
+		return nil
+	}
+	return cached
+}
+
+// writeRoleKey saves the token of cacheKey, dropping the expired ones. The
+// token is still usable without its cache.
+func writeRoleKey(cacheKey string, key *AccessKey) {
+	path := RoleCacheFile()
+	if path == "" {
+		return
+	}
+	unlock, err := LockFile(path)
+	if err != nil {
+		return
+	}
+	defer unlock()
+	keys := readRoleKeys(path)
+	for fileKey, cached := range keys {
+		if cached == nil || cached.Expired(0) {
+			delete(keys, fileKey)
+		}
+	}
+	keys[roleFileKey(cacheKey)] = key
+	if content, err := json.Marshal(keys); err == nil {
+		WriteFileAtomic(path, content)
+	}
+}
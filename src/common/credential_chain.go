@@ -0,0 +1,303 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hahaps/common-provider/src/input"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Keys of input.Credential.Extra used by the credential chain
+const (
+	ExtraProfile string = "profile"
+	ExtraProfilePath string = "profile_path"
+	ExtraMetadataUrl string = "metadata_url"
+	ExtraEcsRamRole string = "ecs_ram_role"
+)
+
+const (
+	EnvAccessKeyId string = "ALIBABA_CLOUD_ACCESS_KEY_ID"
+	EnvAccessKeySecret string = "ALIBABA_CLOUD_ACCESS_KEY_SECRET"
+	EnvSecurityToken string = "ALIBABA_CLOUD_SECURITY_TOKEN"
+	EnvProfile string = "ALIBABA_CLOUD_PROFILE"
+	EnvProfilePath string = "ALIBABA_CLOUD_CONFIG_FILE"
+	EnvMetadataUrl string = "ALIBABA_CLOUD_ECS_METADATA_URL"
+	EnvEcsRamRole string = "ALIBABA_CLOUD_ECS_METADATA"
+)
+
+const DefaultMetadataUrl string = "http://100.100.100.200/latest/meta-data/ram/security-credentials/"
+
+// CredentialProvider looks up an AccessKey for credential, assuming roles
+// through invoker. It returns nil without error when its source is not
+// available.
+type CredentialProvider interface {
+	Retrieve(invoker *Invoker, credential input.Credential) (*AccessKey, error)
+}
+
+var CredentialChain = []CredentialProvider{
+	&EnvProvider{},
+	&ProfileProvider{},
+	&EcsRoleProvider{},
+}
+
+// RetrieveAccessKey walks the CredentialChain and returns the first key found.
+func RetrieveAccessKey(invoker *Invoker, credential input.Credential) (*AccessKey, error) {
+	for _, provider := range CredentialChain {
+		key, err := provider.Retrieve(invoker, credential)
+		if err != nil {
+			return nil, err
+		}
+		if key != nil {
+			return key, nil
+		}
+	}
+	return nil, errors.New("no credential found in params, env, profile or ECS metadata")
+}
+
+func extraOrEnv(credential input.Credential, extra, env string) string {
+	if val := extraString(credential, extra); val != "" {
+		return val
+	}
+	return os.Getenv(env)
+}
+
+// EnvProvider reads the ALIBABA_CLOUD_ACCESS_KEY_* environment variables.
+type EnvProvider struct {}
+
+func (EnvProvider)Retrieve(invoker *Invoker, credential input.Credential) (*AccessKey, error) {
+	id := os.Getenv(EnvAccessKeyId)
+	secret := os.Getenv(EnvAccessKeySecret)
+	if id == "" && secret == "" {
+		return nil, nil
+	}
+	if id == "" || secret == "" {
+		return nil, fmt.Errorf("both %v and %v should be set", EnvAccessKeyId, EnvAccessKeySecret)
+	}
+	return &AccessKey{
+		Identity: "env:" + id,
+		AccessKeyId: id,
+		AccessKeySecret: secret,
+		SecurityToken: os.Getenv(EnvSecurityToken),
+	}, nil
+}
+
+type profile struct {
+	Name string `json:"name"`
+	Mode string `json:"mode"`
+	AccessKeyId string `json:"access_key_id"`
+	AccessKeySecret string `json:"access_key_secret"`
+	StsToken string `json:"sts_token"`
+	RamRoleName string `json:"ram_role_name"`
+	RamRoleArn string `json:"ram_role_arn"`
+	RamSessionName string `json:"ram_session_name"`
+	ExpiredSeconds int64 `json:"expired_seconds"`
+	RegionId string `json:"region_id"`
+}
+
+type profileConfig struct {
+	Current string `json:"current"`
+	Profiles []profile `json:"profiles"`
+}
+
+// ProfileProvider reads a named profile of the aliyun CLI config file,
+// ~/.aliyun/config.json by default.
+type ProfileProvider struct {
+	// Config file path, overrides the extra/env settings when set
+	Path string
+}
+
+func (p ProfileProvider)path(credential input.Credential) string {
+	if p.Path != "" {
+		return p.Path
+	}
+	if path := extraOrEnv(credential, ExtraProfilePath, EnvProfilePath); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".aliyun", "config.json")
+}
+
+func (p ProfileProvider)Retrieve(invoker *Invoker, credential input.Credential) (*AccessKey, error) {
+	path := p.path(credential)
+	if path == "" {
+		return nil, nil
+	}
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	config := profileConfig{}
+	if err = json.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("bad aliyun config file %v: %v", path, err)
+	}
+	name := extraOrEnv(credential, ExtraProfile, EnvProfile)
+	if name == "" {
+		name = config.Current
+	}
+	for _, prof := range config.Profiles {
+		if prof.Name == name {
+			return p.accessKey(invoker, credential, path, prof)
+		}
+	}
+	if name == config.Current || name == "" {
+		return nil, nil
+	}
+	return nil, fmt.Errorf("profile %v not found in %v", name, path)
+}
+
+func (p ProfileProvider)accessKey(invoker *Invoker, credential input.Credential, path string, prof profile) (*AccessKey, error) {
+	key := &AccessKey{
+		Identity: "profile:" + path + "#" + prof.Name,
+		AccessKeyId: prof.AccessKeyId,
+		AccessKeySecret: prof.AccessKeySecret,
+	}
+	switch prof.Mode {
+	case "", "AK":
+		return key, nil
+	case "StsToken":
+		key.SecurityToken = prof.StsToken
+		return key, nil
+	case "RamRoleArn":
+		role := &RoleConfig{
+			Arn: prof.RamRoleArn,
+			SessionName: prof.RamSessionName,
+			Duration: prof.ExpiredSeconds,
+			Region: prof.RegionId,
+		}
+		if role.SessionName == "" {
+			role.SessionName = DefaultRoleSessionName
+		}
+		if role.Duration <= 0 {
+			role.Duration = DefaultRoleSessionDuration
+		}
+		return AssumeRole(invoker, key, role)
+	case "EcsRamRole":
+		return EcsRoleProvider{Role: prof.RamRoleName}.Retrieve(invoker, credential)
+	}
+	return nil, fmt.Errorf("unsupported mode %v of profile %v", prof.Mode, prof.Name)
+}
+
+// EcsRoleProvider reads the RAM role credentials of the ECS instance from
+// the metadata service. The role is discovered when not configured.
+type EcsRoleProvider struct {
+	// Metadata base URL and role name, override the extra/env settings when set
+	BaseUrl string
+	Role string
+}
+
+var MetadataTimeout = time.Second
+
+var ecsRoleLock = sync.Mutex{}
+
+var ecsRoleKeys = map[string]*AccessKey{}
+
+// Roles discovered at each metadata URL, "" when the service is unreachable
+// or no role is attached, kept for the life of the process
+var ecsRoles = map[string]string{}
+
+type metadataCredential struct {
+	Code string
+	AccessKeyId string
+	AccessKeySecret string
+	SecurityToken string
+	Expiration string
+}
+
+func (p EcsRoleProvider)Retrieve(invoker *Invoker, credential input.Credential) (*AccessKey, error) {
+	baseUrl := p.BaseUrl
+	if baseUrl == "" {
+		baseUrl = extraOrEnv(credential, ExtraMetadataUrl, EnvMetadataUrl)
+	}
+	if baseUrl == "" {
+		baseUrl = DefaultMetadataUrl
+	}
+	if !strings.HasSuffix(baseUrl, "/") {
+		baseUrl += "/"
+	}
+	client := &http.Client{Timeout: MetadataTimeout}
+	role := p.Role
+	if role == "" {
+		role = extraOrEnv(credential, ExtraEcsRamRole, EnvEcsRamRole)
+	}
+	if role == "" {
+		role = discoverEcsRole(client, baseUrl)
+		if role == "" {
+			return nil, nil
+		}
+	}
+	cacheKey := baseUrl + role
+	ecsRoleLock.Lock()
+	defer ecsRoleLock.Unlock()
+	if cached, ok := ecsRoleKeys[cacheKey]; ok && !cached.Expired(RoleRefreshWindow) {
+		return cached, nil
+	}
+	content, err := metadataGet(client, baseUrl + role)
+	if err != nil {
+		return nil, fmt.Errorf("get ECS RAM role %v credential: %v", role, err)
+	}
+	meta := metadataCredential{}
+	if err = json.Unmarshal([]byte(content), &meta); err != nil {
+		return nil, fmt.Errorf("bad ECS RAM role %v credential: %v", role, err)
+	}
+	if meta.Code != "" && meta.Code != "Success" {
+		return nil, fmt.Errorf("get ECS RAM role %v credential: %v", role, meta.Code)
+	}
+	key := &AccessKey{
+		Identity: "ecs:" + baseUrl + role,
+		AccessKeyId: meta.AccessKeyId,
+		AccessKeySecret: meta.AccessKeySecret,
+		SecurityToken: meta.SecurityToken,
+	}
+	key.Expiration, err = time.Parse(time.RFC3339, meta.Expiration)
+	if err != nil {
+		return nil, fmt.Errorf("bad expiration of ECS RAM role %v credential", role)
+	}
+	ecsRoleKeys[cacheKey] = key
+	return key, nil
+}
+
+// discoverEcsRole returns the role attached to the instance, looked up
+// once per process: GetAccessKey runs several times per Call, and the
+// lookup waits for MetadataTimeout off ECS.
+func discoverEcsRole(client *http.Client, baseUrl string) string {
+	ecsRoleLock.Lock()
+	defer ecsRoleLock.Unlock()
+	if role, ok := ecsRoles[baseUrl]; ok {
+		return role
+	}
+	role := ""
+	if content, err := metadataGet(client, baseUrl); err == nil {
+		role = strings.TrimSpace(strings.Split(content, "\n")[0])
+	}
+	ecsRoles[baseUrl] = role
+	return role
+}
+
+func metadataGet(client *http.Client, url string) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("metadata service returns %v", resp.StatusCode)
+	}
+	return string(content), nil
+}
@@ -0,0 +1,280 @@
+package common
+
+import (
+	"fmt"
+	"github.com/hahaps/common-provider/src/input"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Invoker of the roles assumed by the tests
+var background = BackgroundInvoker("")
+
+func setEnv(t *testing.T, key, value string) {
+	previous, set := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if set {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func resetEcsRoles(t *testing.T) {
+	reset := func() {
+		ecsRoleLock.Lock()
+		ecsRoles = map[string]string{}
+		ecsRoleKeys = map[string]*AccessKey{}
+		ecsRoleLock.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func TestEnvProvider(t *testing.T) {
+	setEnv(t, EnvAccessKeyId, "")
+	setEnv(t, EnvAccessKeySecret, "")
+	setEnv(t, EnvSecurityToken, "")
+	key, err := EnvProvider{}.Retrieve(background, input.Credential{})
+	if key != nil || err != nil {
+		t.Errorf("got %+v, %v without env vars, want nothing", key, err)
+	}
+	os.Setenv(EnvAccessKeyId, "env-id")
+	if _, err = (EnvProvider{}).Retrieve(background, input.Credential{}); err == nil {
+		t.Error("an AccessKeyId without secret should fail")
+	}
+	os.Setenv(EnvAccessKeySecret, "env-secret")
+	os.Setenv(EnvSecurityToken, "env-token")
+	key, err = EnvProvider{}.Retrieve(background, input.Credential{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.AccessKeyId != "env-id" || key.AccessKeySecret != "env-secret" || key.SecurityToken != "env-token" {
+		t.Errorf("unexpected key %+v", key)
+	}
+}
+
+const testProfiles = `{
+	"current": "default",
+	"profiles": [
+		{"name": "default", "mode": "AK", "access_key_id": "default-id", "access_key_secret": "default-secret"},
+		{"name": "sts", "mode": "StsToken", "access_key_id": "sts-id", "access_key_secret": "sts-secret", "sts_token": "sts-token"},
+		{"name": "odd", "mode": "ChainableRamRoleArn"}
+	]
+}`
+
+func writeProfiles(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProfileProvider(t *testing.T) {
+	setEnv(t, EnvProfile, "")
+	path := writeProfiles(t, testProfiles)
+	key, err := ProfileProvider{Path: path}.Retrieve(background, input.Credential{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.AccessKeyId != "default-id" || key.AccessKeySecret != "default-secret" || key.SecurityToken != "" {
+		t.Errorf("unexpected key %+v of the current profile", key)
+	}
+	credential := input.Credential{
+		Extra: map[string]interface{}{
+			ExtraProfile: "sts",
+			ExtraProfilePath: path,
+		},
+	}
+	key, err = ProfileProvider{}.Retrieve(background, credential)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.AccessKeyId != "sts-id" || key.SecurityToken != "sts-token" {
+		t.Errorf("unexpected key %+v of the sts profile", key)
+	}
+	setEnv(t, EnvProfile, "missing")
+	if _, err = (ProfileProvider{Path: path}).Retrieve(background, input.Credential{}); err == nil {
+		t.Error("a missing profile should fail")
+	}
+	os.Setenv(EnvProfile, "odd")
+	if _, err = (ProfileProvider{Path: path}).Retrieve(background, input.Credential{}); err == nil {
+		t.Error("an unsupported mode should fail")
+	}
+}
+
+func TestProfileProviderFile(t *testing.T) {
+	setEnv(t, EnvProfile, "")
+	missing := filepath.Join(t.TempDir(), "config.json")
+	key, err := ProfileProvider{Path: missing}.Retrieve(background, input.Credential{})
+	if key != nil || err != nil {
+		t.Errorf("got %+v, %v without config file, want nothing", key, err)
+	}
+	if _, err = (ProfileProvider{Path: writeProfiles(t, "{")}).Retrieve(background, input.Credential{}); err == nil {
+		t.Error("a bad config file should fail")
+	}
+	path := writeProfiles(t, `{"current": "gone", "profiles": []}`)
+	key, err = ProfileProvider{Path: path}.Retrieve(background, input.Credential{})
+	if key != nil || err != nil {
+		t.Errorf("got %+v, %v without the current profile, want nothing", key, err)
+	}
+}
+
+// metadataServer serves the RAM role "reader" and counts the requests by path
+func metadataServer(t *testing.T, discovery int) (*httptest.Server, func(path string) int) {
+	lock := sync.Mutex{}
+	counts := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		counts[r.URL.Path]++
+		lock.Unlock()
+		switch r.URL.Path {
+		case "/":
+			w.WriteHeader(discovery)
+			fmt.Fprint(w, "reader\n")
+		case "/reader":
+			fmt.Fprintf(w, `{"Code": "Success", "AccessKeyId": "STS.ecs-id", "AccessKeySecret": "ecs-secret", "SecurityToken": "ecs-token", "Expiration": "%v"}`,
+				time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, func(path string) int {
+		lock.Lock()
+		defer lock.Unlock()
+		return counts[path]
+	}
+}
+
+func TestEcsRoleProvider(t *testing.T) {
+	resetEcsRoles(t)
+	setEnv(t, EnvEcsRamRole, "")
+	server, count := metadataServer(t, http.StatusOK)
+	for i := 0; i < 3; i++ {
+		key, err := EcsRoleProvider{BaseUrl: server.URL}.Retrieve(background, input.Credential{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key == nil || key.AccessKeyId != "STS.ecs-id" || key.SecurityToken != "ecs-token" {
+			t.Fatalf("unexpected key %+v", key)
+		}
+	}
+	if count("/") != 1 {
+		t.Errorf("role discovered %v times, want 1", count("/"))
+	}
+	if count("/reader") != 1 {
+		t.Errorf("role credential requested %v times, want 1", count("/reader"))
+	}
+}
+
+func TestEcsRoleProviderNoRole(t *testing.T) {
+	resetEcsRoles(t)
+	setEnv(t, EnvEcsRamRole, "")
+	server, count := metadataServer(t, http.StatusNotFound)
+	for i := 0; i < 3; i++ {
+		key, err := EcsRoleProvider{BaseUrl: server.URL}.Retrieve(background, input.Credential{})
+		if key != nil || err != nil {
+			t.Fatalf("got %+v, %v without role, want nothing", key, err)
+		}
+	}
+	if count("/") != 1 {
+		t.Errorf("role discovered %v times, want 1", count("/"))
+	}
+}
+
+func TestEcsRoleProviderUnreachable(t *testing.T) {
+	resetEcsRoles(t)
+	setEnv(t, EnvEcsRamRole, "")
+	timeout := MetadataTimeout
+	MetadataTimeout = 200 * time.Millisecond
+	defer func() { MetadataTimeout = timeout }()
+	// Accepts connections but never answers, as a filtered address would
+	hang := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hang
+	}))
+	defer server.Close()
+	defer close(hang)
+	if key, err := (EcsRoleProvider{BaseUrl: server.URL}).Retrieve(background, input.Credential{}); key != nil || err != nil {
+		t.Fatalf("got %+v, %v off ECS, want nothing", key, err)
+	}
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if key, err := (EcsRoleProvider{BaseUrl: server.URL}).Retrieve(background, input.Credential{}); key != nil || err != nil {
+			t.Fatalf("got %+v, %v off ECS, want nothing", key, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed >= MetadataTimeout {
+		t.Errorf("retrieving again took %v, the failed discovery should be cached", elapsed)
+	}
+}
+
+func TestEcsRoleProviderConfigured(t *testing.T) {
+	resetEcsRoles(t)
+	server, count := metadataServer(t, http.StatusOK)
+	credential := input.Credential{
+		Extra: map[string]interface{}{
+			ExtraMetadataUrl: server.URL,
+			ExtraEcsRamRole: "reader",
+		},
+	}
+	key, err := EcsRoleProvider{}.Retrieve(background, credential)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key == nil || key.AccessKeySecret != "ecs-secret" {
+		t.Errorf("unexpected key %+v", key)
+	}
+	if count("/") != 0 {
+		t.Errorf("configured role discovered %v times, want 0", count("/"))
+	}
+	credential.Extra[ExtraEcsRamRole] = "writer"
+	if _, err = (EcsRoleProvider{}).Retrieve(background, credential); err == nil {
+		t.Error("a missing role should fail")
+	}
+}
+
+func TestRetrieveAccessKeyOrder(t *testing.T) {
+	resetEcsRoles(t)
+	setEnv(t, EnvProfile, "")
+	setEnv(t, EnvSecurityToken, "")
+	setEnv(t, EnvEcsRamRole, "")
+	server, _ := metadataServer(t, http.StatusOK)
+	setEnv(t, EnvMetadataUrl, server.URL)
+	setEnv(t, EnvProfilePath, writeProfiles(t, testProfiles))
+	setEnv(t, EnvAccessKeyId, "env-id")
+	setEnv(t, EnvAccessKeySecret, "env-secret")
+	cases := []struct {
+		unset string
+		id string
+	}{
+		{"", "env-id"},
+		{EnvAccessKeyId, "default-id"},
+		{EnvProfilePath, "STS.ecs-id"},
+	}
+	for _, c := range cases {
+		if c.unset == EnvAccessKeyId {
+			os.Setenv(EnvAccessKeyId, "")
+			os.Setenv(EnvAccessKeySecret, "")
+		} else if c.unset != "" {
+			os.Setenv(c.unset, filepath.Join(t.TempDir(), "none.json"))
+		}
+		key, err := RetrieveAccessKey(background, input.Credential{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.AccessKeyId != c.id {
+			t.Errorf("got AccessKeyId %v, want %v", key.AccessKeyId, c.id)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package common_test
+
+import (
+	"context"
+	"github.com/hahaps/common-provider/src/input"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Invoker of the roles assumed by the tests
+var background = common.BackgroundInvoker("")
+
+func fakeSts(t *testing.T) *fakeapi.Server {
+	server := fakeapi.NewServer()
+	server.LoadDefaults()
+	server.Install()
+	common.ResetRoleKeys()
+	previous, set := os.LookupEnv(common.EnvRoleCacheFile)
+	os.Setenv(common.EnvRoleCacheFile, filepath.Join(t.TempDir(), "roles.json"))
+	t.Cleanup(func() {
+		server.Close()
+		common.ResetRoleKeys()
+		if set {
+			os.Setenv(common.EnvRoleCacheFile, previous)
+		} else {
+			os.Unsetenv(common.EnvRoleCacheFile)
+		}
+	})
+	return server
+}
+
+func roleCredential(arn string) input.Credential {
+	return input.Credential{
+		SecretId: "fake-id",
+		SecretKey: "fake-key",
+		Extra: map[string]interface{}{
+			common.ExtraRoleArn: arn,
+		},
+	}
+}
+
+func TestAssumeRole(t *testing.T) {
+	server := fakeSts(t)
+	common.UseLog(common.LogOff)
+	key, err := common.GetAccessKey(background, roleCredential("acs:ram::2:role/reader"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.AccessKeyId != "STS.fake-" + common.DefaultRoleSessionName || key.SecurityToken != "fake-token-acs:ram::2:role/reader" {
+		t.Errorf("unexpected key %+v", key)
+	}
+	if key.Expired(common.RoleRefreshWindow) {
+		t.Errorf("fresh key expired at %v", key.Expiration)
+	}
+	requests := server.Requests("AssumeRole")
+	if len(requests) != 1 {
+		t.Fatalf("%v AssumeRole requests, want 1", len(requests))
+	}
+	if requests[0].Get("RoleArn") != "acs:ram::2:role/reader" || requests[0].Get("DurationSeconds") != "3600" {
+		t.Errorf("unexpected request %v", requests[0])
+	}
+	if _, err = common.GetAccessKey(background, roleCredential("acs:ram::2:role/reader")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(server.Requests("AssumeRole")); n != 1 {
+		t.Errorf("%v AssumeRole requests for a cached token, want 1", n)
+	}
+}
+
+func TestAssumeRoleShared(t *testing.T) {
+	server := fakeSts(t)
+	common.UseLog(common.LogOff)
+	server.Handle("AssumeRole", func(params url.Values) (int, interface{}) {
+		time.Sleep(100 * time.Millisecond)
+		return fakeapi.AssumeRole(params)
+	})
+	wg := sync.WaitGroup{}
+	keys := make([]*common.AccessKey, 10)
+	for i := range keys {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			keys[i], _ = common.GetAccessKey(background, roleCredential("acs:ram::2:role/shared"))
+		}(i)
+	}
+	wg.Wait()
+	for _, key := range keys {
+		if key == nil || key.SecurityToken != keys[0].SecurityToken {
+			t.Fatalf("calls got different keys %+v", keys)
+		}
+	}
+	if n := len(server.Requests("AssumeRole")); n != 1 {
+		t.Errorf("%v AssumeRole requests for concurrent calls, want 1", n)
+	}
+}
+
+func TestAssumeRoleNotBlocking(t *testing.T) {
+	server := fakeSts(t)
+	common.UseLog(common.LogOff)
+	release := make(chan struct{})
+	server.Handle("AssumeRole", func(params url.Values) (int, interface{}) {
+		if params.Get("RoleArn") == "acs:ram::2:role/slow" {
+			select {
+			case <-release:
+			case <-time.After(5 * time.Second):
+			}
+		}
+		return fakeapi.AssumeRole(params)
+	})
+	slow := make(chan error)
+	go func() {
+		_, err := common.GetAccessKey(background, roleCredential("acs:ram::2:role/slow"))
+		slow <- err
+	}()
+	for len(server.Requests("AssumeRole")) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	start := time.Now()
+	if _, err := common.GetAccessKey(background, roleCredential("acs:ram::3:role/fast")); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("another role waited %v for the slow one", elapsed)
+	}
+	close(release)
+	if err := <-slow; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAssumeRoleThrottled(t *testing.T) {
+	server := fakeSts(t)
+	common.UseLog(common.LogOff)
+	server.Fail("AssumeRole", 2, 400, "Throttling", "Request was denied due to request throttling.")
+	key, err := common.GetAccessKey(background, roleCredential("acs:ram::2:role/throttled"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.SecurityToken == "" {
+		t.Errorf("unexpected key %+v", key)
+	}
+	if n := len(server.Requests("AssumeRole")); n != 3 {
+		t.Errorf("%v AssumeRole requests, want 3", n)
+	}
+}
+
+func TestAssumeRoleDenied(t *testing.T) {
+	server := fakeSts(t)
+	common.UseLog(common.LogOff)
+	server.Fail("AssumeRole", 1, 403, "NoPermission", "You are not authorized to do this action.")
+	_, err := common.GetAccessKey(background, roleCredential("acs:ram::2:role/denied"))
+	if common.ErrorCodeOf(err) != common.Forbidden {
+		t.Errorf("got %v, want a Forbidden error", err)
+	}
+	if n := len(server.Requests("AssumeRole")); n != 1 {
+		t.Errorf("%v AssumeRole requests, want 1", n)
+	}
+}
+
+func TestAssumeRoleFileCache(t *testing.T) {
+	server := fakeSts(t)
+	common.UseLog(common.LogOff)
+	first, err := common.GetAccessKey(background, roleCredential("acs:ram::2:role/cached"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Another process only shares the file
+	common.ResetRoleKeys()
+	second, err := common.GetAccessKey(background, roleCredential("acs:ram::2:role/cached"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.SecurityToken != first.SecurityToken {
+		t.Errorf("got %+v from the file, want %+v", second, first)
+	}
+	if n := len(server.Requests("AssumeRole")); n != 1 {
+		t.Errorf("%v AssumeRole requests, want 1", n)
+	}
+	info, err := os.Stat(common.RoleCacheFile())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("role cache file mode %v, want 0600", info.Mode().Perm())
+	}
+	os.Setenv(common.EnvRoleCacheFile, common.RoleCacheOff)
+	common.ResetRoleKeys()
+	if _, err = common.GetAccessKey(background, roleCredential("acs:ram::2:role/cached")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(server.Requests("AssumeRole")); n != 2 {
+		t.Errorf("%v AssumeRole requests without the file, want 2", n)
+	}	// The file cache is opt-in
+	os.Unsetenv(common.EnvRoleCacheFile)
+	if path := common.RoleCacheFile(); path != "" {
+		t.Errorf("role cache file %v by default, want none", path)
+	}
+}
+
+func TestAssumeRoleInvoker(t *testing.T) {
+	server := fakeSts(t)
+	common.UseLog(common.LogOff)
+	invoker := common.BackgroundInvoker("")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	invoker.Ctx = ctx
+	if _, err := common.GetAccessKey(invoker, roleCredential("acs:ram::2:role/canceled")); err == nil {
+		t.Error("role assumed past the end of the call")
+	}
+	if n := len(server.Requests("AssumeRole")); n != 0 {
+		t.Errorf("%v AssumeRole requests past the end of the call, want 0", n)
+	}
+}
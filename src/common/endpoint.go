@@ -0,0 +1,146 @@
+package common
+
+import (
+	"fmt"
+	openapi "github.com/alibabacloud-go/darabonba-openapi/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hahaps/common-provider/src/input"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	ProductEcs string = "ecs"
+	ProductCms string = "cms"
+	ProductBss string = "bss"
+	ProductSts string = "sts"
+	ProductVpc string = "vpc"
+	ProductResourceManager string = "resourcemanager"
+)
+
+type EndpointRule struct {
+	// Endpoint used when no region is given or the region is unknown
+	Central string
+	// Regional endpoint pattern, "%s" is replaced by the region id.
+	// Empty for global products.
+	Regional string
+	// Regions not following the regional pattern
+	Regions map[string]string
+}
+
+var EndpointRules = map[string]EndpointRule{
+	ProductEcs: EndpointRule{
+		Central: "ecs-cn-hangzhou.aliyuncs.com",
+		Regional: "ecs.%s.aliyuncs.com",
+	},
+	ProductVpc: EndpointRule{
+		Central: "vpc.aliyuncs.com",
+		Regional: "vpc.%s.aliyuncs.com",
+	},
+	ProductCms: EndpointRule{
+		Central: "metrics.cn-hangzhou.aliyuncs.com",
+		Regional: "metrics.%s.aliyuncs.com",
+	},
+	ProductSts: EndpointRule{
+		Central: "sts.aliyuncs.com",
+		Regional: "sts.%s.aliyuncs.com",
+	},
+	ProductResourceManager: EndpointRule{
+		Central: "resourcemanager.aliyuncs.com",
+	},
+	ProductBss: EndpointRule{
+		Central: "business.aliyuncs.com",
+		Regions: map[string]string{
+			"ap-southeast-1": "business.ap-southeast-1.aliyuncs.com",
+		},
+	},
+}
+
+// EndpointOverrides is keyed by "product/region", or by "product" alone to
+// override the endpoint of every region of a product.
+var EndpointOverrides = map[string]string{}
+
+var endpointLock = sync.RWMutex{}
+
+var regionPattern = regexp.MustCompile(`^[a-z]{2,}-[a-z0-9-]+$`)
+
+func endpointKey(product, region string) string {
+	if region == "" {
+		return product
+	}
+	return product + "/" + region
+}
+
+// SetEndpoint overrides the endpoint of product in region. An empty region
+// overrides every region, an empty endpoint removes the override.
+func SetEndpoint(product, region, endpoint string) {
+	endpointLock.Lock()
+	defer endpointLock.Unlock()
+	if endpoint == "" {
+		delete(EndpointOverrides, endpointKey(product, region))
+		return
+	}
+	EndpointOverrides[endpointKey(product, region)] = endpoint
+}
+
+// Endpoint resolves the endpoint of product in region, looking at the
+// overrides first, then the product rule, then the central endpoint.
+func Endpoint(product, region string) (string, error) {
+	endpointLock.RLock()
+	defer endpointLock.RUnlock()
+	if endpoint, ok := EndpointOverrides[endpointKey(product, region)]; ok {
+		return endpoint, nil
+	}
+	if endpoint, ok := EndpointOverrides[product]; ok {
+		return endpoint, nil
+	}
+	rule, ok := EndpointRules[product]
+	if !ok {
+		return "", fmt.Errorf("no endpoint for product %v", product)
+	}
+	if endpoint, ok := rule.Regions[region]; ok {
+		return endpoint, nil
+	}
+	if rule.Regional == "" || !regionPattern.MatchString(region) {
+		return rule.Central, nil
+	}
+	return fmt.Sprintf(rule.Regional, region), nil
+}
+
+// NewConfig builds the OpenAPI client config of product in region.
+// Endpoints may carry a "http://" or "https://" scheme to force the protocol.
+func NewConfig(invoker *Invoker, credential input.Credential, product, region string) (*openapi.Config, error) {
+	key, err := GetAccessKey(invoker, credential)
+	if err != nil {
+		return nil, err
+	}
+	return newConfig(key, product, region)
+}
+
+func newConfig(key *AccessKey, product, region string) (*openapi.Config, error) {
+	endpoint, err := Endpoint(product, region)
+	if err != nil {
+		return nil, err
+	}
+	config := &openapi.Config{
+		AccessKeyId: tea.String(key.AccessKeyId),
+		AccessKeySecret: tea.String(key.AccessKeySecret),
+	}
+	if key.SecurityToken != "" {
+		config.SecurityToken = tea.String(key.SecurityToken)
+	}
+	if idx := strings.Index(endpoint, "://"); idx > 0 {
+		config.Protocol = tea.String(endpoint[:idx])
+		endpoint = endpoint[idx + 3:]
+	}
+	config.Endpoint = tea.String(endpoint)
+	if proxy := cassetteUpstream(endpoint, tea.StringValue(config.Protocol)); proxy != "" {
+		config.Protocol = tea.String("http")
+		config.HttpProxy = tea.String(proxy)
+	}
+	if region != "" {
+		config.RegionId = tea.String(region)
+	}
+	return config, nil
+}
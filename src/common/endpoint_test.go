@@ -0,0 +1,68 @@
+package common
+
+import (
+	"testing"
+)
+
+func TestEndpoint(t *testing.T) {
+	cases := []struct {
+		product string
+		region string
+		endpoint string
+	}{
+		{ProductEcs, "ap-southeast-1", "ecs.ap-southeast-1.aliyuncs.com"},
+		{ProductEcs, "cn-shanghai", "ecs.cn-shanghai.aliyuncs.com"},
+		{ProductEcs, "eu-central-1", "ecs.eu-central-1.aliyuncs.com"},
+		{ProductEcs, "cn-hangzhou", "ecs.cn-hangzhou.aliyuncs.com"},
+		{ProductEcs, "", "ecs-cn-hangzhou.aliyuncs.com"},
+		{ProductEcs, "not a region", "ecs-cn-hangzhou.aliyuncs.com"},
+		{ProductVpc, "ap-southeast-1", "vpc.ap-southeast-1.aliyuncs.com"},
+		{ProductVpc, "cn-shanghai", "vpc.cn-shanghai.aliyuncs.com"},
+		{ProductVpc, "", "vpc.aliyuncs.com"},
+		{ProductCms, "eu-central-1", "metrics.eu-central-1.aliyuncs.com"},
+		{ProductCms, "", "metrics.cn-hangzhou.aliyuncs.com"},
+		{ProductSts, "cn-shanghai", "sts.cn-shanghai.aliyuncs.com"},
+		{ProductResourceManager, "cn-shanghai", "resourcemanager.aliyuncs.com"},
+		{ProductBss, "ap-southeast-1", "business.ap-southeast-1.aliyuncs.com"},
+		{ProductBss, "cn-shanghai", "business.aliyuncs.com"},
+	}
+	for _, c := range cases {
+		endpoint, err := Endpoint(c.product, c.region)
+		if err != nil {
+			t.Errorf("Endpoint(%v, %v): %v", c.product, c.region, err)
+			continue
+		}
+		if endpoint != c.endpoint {
+			t.Errorf("Endpoint(%v, %v) = %v, want %v", c.product, c.region, endpoint, c.endpoint)
+		}
+	}
+}
+
+func TestEndpointUnknownProduct(t *testing.T) {
+	if _, err := Endpoint("nope", "cn-shanghai"); err == nil {
+		t.Error("Endpoint of an unknown product should fail")
+	}
+}
+
+func TestEndpointOverride(t *testing.T) {
+	SetEndpoint(ProductEcs, "cn-shanghai", "ecs-vpc.cn-shanghai.aliyuncs.com")
+	SetEndpoint(ProductCms, "", "http://127.0.0.1:8080")
+	defer SetEndpoint(ProductEcs, "cn-shanghai", "")
+	defer SetEndpoint(ProductCms, "", "")
+	cases := []struct {
+		product string
+		region string
+		endpoint string
+	}{
+		{ProductEcs, "cn-shanghai", "ecs-vpc.cn-shanghai.aliyuncs.com"},
+		{ProductEcs, "cn-beijing", "ecs.cn-beijing.aliyuncs.com"},
+		{ProductCms, "cn-beijing", "http://127.0.0.1:8080"},
+		{ProductCms, "", "http://127.0.0.1:8080"},
+	}
+	for _, c := range cases {
+		endpoint, _ := Endpoint(c.product, c.region)
+		if endpoint != c.endpoint {
+			t.Errorf("Endpoint(%v, %v) = %v, want %v", c.product, c.region, endpoint, c.endpoint)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/alibabacloud-go/tea/tea"
+	"net"
+	"strconv"
+	"strings"
+)
+
+type ErrorCode string
+
+const (
+	// Invalid, disabled or expired AccessKey or token
+	AuthFailed ErrorCode = "AuthFailed"
+	// Missing RAM permission
+	Forbidden ErrorCode = "Forbidden"
+	// Region not opened to the account or unknown
+	RegionUnavailable ErrorCode = "RegionUnavailable"
+	// Throttled after the retries
+	Throttled ErrorCode = "Throttled"
+	// Bad params of the resource or of the request
+	InvalidParam ErrorCode = "InvalidParam"
+	// Resource or action named by the request not found
+	NotFound ErrorCode = "NotFound"
+	// Failures and bad responses of the Aliyun side
+	Upstream ErrorCode = "Upstream"
+	// Bugs of the provider
+	Internal ErrorCode = "Internal"
+)
+
+// Error is the error returned by every resource Call. Only the message
+// reaches CloudTracker through RPC, so it starts with "[Code]".
+type Error struct {
+	Code ErrorCode
+	// Error code returned by Aliyun, if any
+	ApiCode string
+	Product string
+	Action string
+	Region string
+	RequestId string
+	Message string
+	Err error
+}
+
+func (err *Error)Error() string {
+	msg := "[" + string(err.Code) + "]"
+	if err.Action != "" {
+		msg += " " + err.Product + " " + err.Action
+		if err.Region != "" {
+			msg += " in " + err.Region
+		}
+		msg += ":"
+	}
+	if err.ApiCode != "" {
+		msg += " " + err.ApiCode
+	}
+	msg += " " + err.Message
+	if err.RequestId != "" {
+		msg += " (RequestId: " + err.RequestId + ")"
+	}
+	return msg
+}
+
+func (err *Error)Unwrap() error {
+	return err.Err
+}
+
+// NewError creates an error not related to any API invocation.
+func NewError(code ErrorCode, format string, args ...interface{}) *Error {
+	return &Error{
+		Code: code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// ParamError wraps the error of a param check.
+func ParamError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if typed := (*Error)(nil); errors.As(err, &typed) {
+		return err
+	}
+	return &Error{
+		Code: InvalidParam,
+		Message: err.Error(),
+		Err: err,
+	}
+}
+
+// ErrorCodeOf returns the code of err, Internal for untyped errors.
+func ErrorCodeOf(err error) ErrorCode {
+	if typed := (*Error)(nil); errors.As(err, &typed) {
+		return typed.Code
+	}
+	return Internal
+}
+
+// ClassifyCode maps an Aliyun error code and HTTP status to an ErrorCode.
+func ClassifyCode(apiCode string, status int) ErrorCode {
+	switch {
+	case strings.HasPrefix(apiCode, "InvalidAccessKeyId"),
+		strings.HasPrefix(apiCode, "InvalidAccessKeySecret"),
+		strings.HasPrefix(apiCode, "InvalidSecurityToken"),
+		strings.HasPrefix(apiCode, "SignatureDoesNotMatch"),
+		strings.HasPrefix(apiCode, "IncompleteSignature"),
+		apiCode == "InvalidAccessKey",
+		apiCode == "SignatureNonceUsed":
+		return AuthFailed
+	case strings.HasPrefix(apiCode, "InvalidRegionId"),
+		strings.HasPrefix(apiCode, "InvalidRegion"),
+		strings.HasPrefix(apiCode, "Region.NotOpen"),
+		apiCode == "Forbidden.RegionId",
+		apiCode == "UnsupportedRegion":
+		return RegionUnavailable
+	case strings.HasPrefix(apiCode, "Throttling"), status == 429:
+		return Throttled
+	case strings.HasPrefix(apiCode, "Forbidden"),
+		strings.HasPrefix(apiCode, "NoPermission"),
+		strings.HasPrefix(apiCode, "NotAuthorized"),
+		strings.HasPrefix(apiCode, "UnauthorizedOperation"),
+		status == 401, status == 403:
+		return Forbidden
+	case strings.HasSuffix(apiCode, ".NotFound"),
+		strings.HasPrefix(apiCode, "NotFound"),
+		status == 404:
+		return NotFound
+	case strings.HasPrefix(apiCode, "InvalidParameter"),
+		strings.HasPrefix(apiCode, "InvalidParam"),
+		strings.HasPrefix(apiCode, "MissingParameter"),
+		strings.HasPrefix(apiCode, "Invalid"),
+		strings.HasPrefix(apiCode, "Missing"),
+		status == 400:
+		return InvalidParam
+	}
+	return Upstream
+}
+
+// Classify wraps err, returned by action on product in region, into an
+// Error carrying its code and RequestId.
+func Classify(product, region, action string, err error) error {
+	if err == nil {
+		return nil
+	}
+	if typed := (*Error)(nil); errors.As(err, &typed) {
+		return err
+	}
+	classified := &Error{
+		Code: Upstream,
+		Product: product,
+		Action: action,
+		Region: region,
+		Message: err.Error(),
+		Err: err,
+	}
+	sdkErr := &tea.SDKError{}
+	dnsErr := &net.DNSError{}
+	switch {
+	case errors.As(err, &sdkErr):
+		classified.ApiCode = tea.StringValue(sdkErr.Code)
+		classified.Message = sdkMessage(sdkErr)
+		classified.Code = ClassifyCode(classified.ApiCode, SDKStatusCode(sdkErr))
+		data := map[string]interface{}{}
+		if json.Unmarshal([]byte(tea.StringValue(sdkErr.Data)), &data) == nil {
+			for _, key := range []string{"RequestId", "requestId"} {
+				if id, ok := data[key].(string); ok {
+					classified.RequestId = id
+				}
+			}
+		}
+	case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+		// Regional endpoints only exist for the opened regions
+		classified.Code = RegionUnavailable
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		classified.Code = Upstream
+	}
+	return classified
+}
+
+// sdkMessage strips the status and RequestId the SDK adds to the message.
+func sdkMessage(err *tea.SDKError) string {
+	msg := statusPattern.ReplaceAllString(tea.StringValue(err.Message), "")
+	if idx := strings.LastIndex(msg, " request id: "); idx >= 0 {
+		msg = msg[:idx]
+	}
+	return strings.TrimSpace(msg)
+}
+
+// ResponseError creates the error of a response reporting a failure in
+// its body rather than through its HTTP status.
+func ResponseError(product, region, action, apiCode, message, requestId string) error {
+	// Some products report the HTTP status as code
+	status, _ := strconv.Atoi(apiCode)
+	return &Error{
+		Code: ClassifyCode(apiCode, status),
+		ApiCode: apiCode,
+		Product: product,
+		Action: action,
+		Region: region,
+		RequestId: requestId,
+		Message: message,
+	}
+}
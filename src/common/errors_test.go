@@ -0,0 +1,106 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/alibabacloud-go/tea/tea"
+	"net"
+	"testing"
+)
+
+func TestClassifyCode(t *testing.T) {
+	cases := []struct {
+		apiCode string
+		status int
+		want ErrorCode
+	}{
+		{"InvalidAccessKeyId.NotFound", 404, AuthFailed},
+		{"InvalidAccessKeySecret", 400, AuthFailed},
+		{"InvalidSecurityToken.Expired", 400, AuthFailed},
+		{"SignatureDoesNotMatch", 400, AuthFailed},
+		{"SignatureNonceUsed", 400, AuthFailed},
+		{"InvalidRegionId.NotFound", 404, RegionUnavailable},
+		{"Region.NotOpen", 403, RegionUnavailable},
+		{"Forbidden.RegionId", 403, RegionUnavailable},
+		{"UnsupportedRegion", 400, RegionUnavailable},
+		{"Throttling.User", 400, Throttled},
+		{"", 429, Throttled},
+		{"Forbidden.RAM", 403, Forbidden},
+		{"NoPermission", 403, Forbidden},
+		{"NotAuthorized", 400, Forbidden},
+		{"UnauthorizedOperation", 400, Forbidden},
+		{"", 401, Forbidden},
+		{"InvalidInstanceId.NotFound", 404, NotFound},
+		{"InvalidAction.NotFound", 404, NotFound},
+		{"", 404, NotFound},
+		{"InvalidParameter", 400, InvalidParam},
+		{"InvalidParam.PageSize", 400, InvalidParam},
+		{"MissingRegionId", 400, InvalidParam},
+		{"", 400, InvalidParam},
+		{"InternalError", 500, Upstream},
+		{"ServiceUnavailable", 503, Upstream},
+		{"", 0, Upstream},
+	}
+	for _, c := range cases {
+		if got := ClassifyCode(c.apiCode, c.status); got != c.want {
+			t.Errorf("ClassifyCode(%q, %v) is %v, want %v", c.apiCode, c.status, got, c.want)
+		}
+	}
+}
+
+func sdkError(code string, status int) error {
+	return tea.NewSDKError(map[string]interface{}{
+		"code": code,
+		"message": fmt.Sprintf("code: %v, %v request id: req-1", status, code),
+		"data": map[string]interface{}{"RequestId": "req-1"},
+	})
+}
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		err error
+		want ErrorCode
+		apiCode string
+	}{
+		{sdkError("Forbidden.RAM", 403), Forbidden, "Forbidden.RAM"},
+		{sdkError("Throttling", 400), Throttled, "Throttling"},
+		{sdkError("InvalidParameter", 400), InvalidParam, "InvalidParameter"},
+		{sdkError("InvalidVpcId.NotFound", 404), NotFound, "InvalidVpcId.NotFound"},
+		{sdkError("InternalError", 500), Upstream, "InternalError"},
+		{&net.DNSError{Err: "no such host", Name: "ecs.cn-nowhere.aliyuncs.com", IsNotFound: true}, RegionUnavailable, ""},
+		{context.DeadlineExceeded, Upstream, ""},
+		{errors.New("connection reset"), Upstream, ""},
+		{NewError(InvalidParam, "bad limit"), InvalidParam, ""},
+	}
+	for _, c := range cases {
+		err := Classify(ProductEcs, "cn-hangzhou", "DescribeInstances", c.err)
+		typed := (*Error)(nil)
+		if !errors.As(err, &typed) {
+			t.Fatalf("Classify(%v) is untyped %v", c.err, err)
+		}
+		if typed.Code != c.want || typed.ApiCode != c.apiCode {
+			t.Errorf("Classify(%v) is %v %q, want %v %q", c.err, typed.Code, typed.ApiCode, c.want, c.apiCode)
+		}
+		if c.apiCode != "" && typed.RequestId != "req-1" {
+			t.Errorf("Classify(%v) lost the RequestId in %v", c.err, err)
+		}
+	}
+	if Classify(ProductEcs, "", "DescribeRegions", nil) != nil {
+		t.Error("nil is classified")
+	}
+}
+
+func TestResponseError(t *testing.T) {
+	err := ResponseError(ProductCms, "cn-hangzhou", "DescribeMetricLast", "403", "denied", "req-2")
+	if ErrorCodeOf(err) != Forbidden {
+		t.Errorf("got %v, want a Forbidden error", err)
+	}
+	want := "[Forbidden] cms DescribeMetricLast in cn-hangzhou: 403 denied (RequestId: req-2)"
+	if err.Error() != want {
+		t.Errorf("message %q, want %q", err.Error(), want)
+	}
+	if ErrorCodeOf(errors.New("plain")) != Internal {
+		t.Error("untyped errors are not Internal")
+	}
+}
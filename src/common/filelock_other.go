@@ -0,0 +1,33 @@
+// +build !aix,!darwin,!dragonfly,!freebsd,!linux,!netbsd,!openbsd,!solaris
+
+package common
+
+import (
+	"os"
+	"time"
+)
+
+// Age of a lock file left by a dead process
+const staleLock = 30 * time.Second
+
+// lockFile creates path exclusively, without flock, and removes it on
+// release. A lock file older than staleLock is taken over.
+func lockFile(path string) (func(), error) {
+	for {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			file.Close()
+			return func() {
+				os.Remove(path)
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLock {
+			os.Remove(path)
+			continue
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
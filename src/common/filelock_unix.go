@@ -0,0 +1,30 @@
+// +build aix darwin dragonfly freebsd linux netbsd openbsd solaris
+
+package common
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile holds a flock on path, released when the process dies.
+func lockFile(path string) (func(), error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		err = syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return func() {
+		syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}, nil
+}
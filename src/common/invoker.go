@@ -0,0 +1,186 @@
+package common
+
+import (
+	"context"
+	"github.com/hahaps/common-provider/src/common/utils"
+	"github.com/hahaps/common-provider/src/input"
+	"time"
+)
+
+// Optional params shared by every resource to tune the SDK invocations
+var InvokerSchemes = []utils.Scheme {
+	utils.Scheme{
+		Param: "timeout",
+		Required: false,
+		Type: utils.Int,
+		Default: 0,
+	},
+	utils.Scheme{
+		Param: "retry_max_attempts",
+		Required: false,
+		Type: utils.Int,
+		Default: DefaultRetryPolicy.MaxAttempts,
+	},
+	utils.Scheme{
+		Param: "retry_budget",
+		Required: false,
+		Type: utils.Int,
+		Default: int(DefaultRetryPolicy.Budget / time.Second),
+	},
+	utils.Scheme{
+		Param: "rate_limits",
+		Required: false,
+		Type: utils.Map,
+	},
+	utils.Scheme{
+		Param: "rate_limit_file",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
+	utils.Scheme{
+		Param: "cassette_mode",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
+	utils.Scheme{
+		Param: "cassette",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
+	utils.Scheme{
+		Param: "log",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
+	utils.Scheme{
+		Param: "metrics_addr",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
+	utils.Scheme{
+		Param: "metrics_file",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
+}
+
+// Invoker runs the SDK invocations of one resource Call.
+type Invoker struct {
+	Ctx context.Context
+	Retry RetryPolicy
+	AccountId string
+	// QPS of each "product/Action", see DefaultQuotas
+	Quotas map[string]float64
+	cancel context.CancelFunc
+	// Metrics file flushed on Close, see FlushMetrics
+	metricsFile string
+}
+
+// NewInvoker reads the InvokerSchemes params. "timeout" bounds the whole
+// Call in seconds, the retries stop when it is reached. The quotas of
+// "rate_limit_file" and then "rate_limits" override DefaultQuotas.
+// "cassette_mode" and "cassette" switch the provider to a cassette, see
+// UseCassette. "log" and "metrics_addr" set where the invocations are
+// logged and their metrics served, see UseLog and ServeMetrics, and
+// "metrics_file" where their metrics are added on Close, see FlushMetrics.
+func NewInvoker(params input.Params) (*Invoker, error) {
+	args := map[string]interface{}{}
+	for _, scheme := range InvokerSchemes {
+		if val, ok := params.Args[scheme.Param]; ok {
+			args[scheme.Param] = val
+		}
+	}
+	args, err := utils.CheckParam(args, InvokerSchemes)
+	if err != nil {
+		return nil, ParamError(err)
+	}
+	if err = useCassette(args); err != nil {
+		return nil, err
+	}
+	if err = useLog(args); err != nil {
+		return nil, err
+	}
+	invoker := &Invoker{
+		Retry: DefaultRetryPolicy,
+		AccountId: params.Credential.AccountId,
+		Quotas: map[string]float64{},
+		metricsFile: useMetrics(args),
+	}
+	for action, qps := range DefaultQuotas {
+		invoker.Quotas[action] = qps
+	}
+	if path := args["rate_limit_file"].(string); path != "" {
+		if err = LoadQuotas(path, invoker.Quotas); err != nil {
+			return nil, ParamError(err)
+		}
+	}
+	if limits, ok := args["rate_limits"].(map[string]interface{}); ok {
+		if err = MergeQuotas(limits, invoker.Quotas); err != nil {
+			return nil, ParamError(err)
+		}
+	}
+	invoker.Retry.MaxAttempts = args["retry_max_attempts"].(int)
+	invoker.Retry.Budget = time.Duration(args["retry_budget"].(int)) * time.Second
+	if timeout := args["timeout"].(int); timeout > 0 {
+		invoker.Ctx, invoker.cancel = context.WithTimeout(context.Background(), time.Duration(timeout) * time.Second)
+	} else {
+		invoker.Ctx, invoker.cancel = context.WithCancel(context.Background())
+	}
+	return invoker, nil
+}
+
+// BackgroundInvoker runs the SDK invocations made outside of a resource
+// Call, with the DefaultRetryPolicy and the DefaultQuotas.
+func BackgroundInvoker(accountId string) *Invoker {
+	return &Invoker{
+		Ctx: context.Background(),
+		Retry: DefaultRetryPolicy,
+		AccountId: accountId,
+		Quotas: DefaultQuotas,
+	}
+}
+
+// Close releases the context of the invoker and flushes the metrics to
+// its metrics file. A failure to flush them is only logged.
+func (invoker *Invoker)Close() {
+	if invoker.cancel != nil {
+		invoker.cancel()
+	}
+	if invoker.metricsFile != "" {
+		if err := FlushMetrics(invoker.metricsFile); err != nil {
+			logWarning("flush metrics to %v: %v", invoker.metricsFile, err)
+		}
+	}
+}
+
+// Quota returns the QPS allowed for action on product.
+func (invoker *Invoker)Quota(product, action string) float64 {
+	if qps, ok := invoker.Quotas[product + "/" + action]; ok {
+		return qps
+	}
+	return DefaultQuota
+}
+
+// Invoke runs fn, the SDK invocation of action on product in region, with
+// the retry policy of the invoker. Every attempt waits for the rate limit
+// of the action in the account. Failures are returned as Error. The
+// invocation is logged and counted in the metrics. The context of the
+// invoker is not passed to fn, so a timeout only stops the retries.
+func (invoker *Invoker)Invoke(product, region, action string, fn func() error) error {
+	if err := invoker.Ctx.Err(); err != nil {
+		return Classify(product, region, action, err)
+	}
+	bucket := Limiter(invoker.AccountId, product, action, invoker.Quota(product, action))
+	return observedDo(invoker.Ctx, invoker.Retry, invoker.AccountId, product, region, action, func() error {
+		if err := bucket.Wait(invoker.Ctx); err != nil {
+			return err
+		}
+		return fn()
+	})
+}
@@ -0,0 +1,149 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Env var of the default "log" param
+const EnvLog = "ALIBABA_CLOUD_LOG"
+
+// Targets of the "log" param besides a file path
+const (
+	LogStderr string = "stderr"
+	LogOff string = "off"
+)
+
+var logLock sync.Mutex
+var logOutput io.Writer = os.Stderr
+var logTarget string = LogStderr
+
+// InvocationLog is the JSON line logged for each SDK invocation, after its
+// retries. Code is "OK" for the successful ones.
+type InvocationLog struct {
+	Time string `json:"time"`
+	AccountId string `json:"account_id,omitempty"`
+	Product string `json:"product"`
+	Action string `json:"action"`
+	Region string `json:"region,omitempty"`
+	LatencyMs float64 `json:"latency_ms"`
+	Code string `json:"code"`
+	ApiCode string `json:"api_code,omitempty"`
+	RequestId string `json:"request_id,omitempty"`
+	Retries int `json:"retries"`
+	Error string `json:"error,omitempty"`
+}
+
+// UseLog writes the invocation logs to target: "stderr", which is the
+// default, "off", or the path of a file to append to.
+func UseLog(target string) error {
+	if target == "" {
+		target = LogStderr
+	}
+	logLock.Lock()
+	defer logLock.Unlock()
+	if target == logTarget {
+		return nil
+	}
+	if file, ok := logOutput.(*os.File); ok && file != os.Stderr {
+		file.Close()
+	}
+	logOutput, logTarget = nil, LogOff
+	switch target {
+	case LogOff:
+	case LogStderr:
+		logOutput = os.Stderr
+	default:
+		file, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return NewError(InvalidParam, "bad log file %v: %v", target, err)
+		}
+		logOutput = file
+	}
+	logTarget = target
+	return nil
+}
+
+func useLog(args map[string]interface{}) error {
+	target := args["log"].(string)
+	if target == "" {
+		target = os.Getenv(EnvLog)
+	}
+	return UseLog(target)
+}
+
+// observe logs and counts an SDK invocation, err being its classified error.
+func observe(accountId, product, region, action string, latency time.Duration, retries int, err error) {
+	entry := InvocationLog{
+		Time: time.Now().UTC().Format(time.RFC3339Nano),
+		AccountId: accountId,
+		Product: product,
+		Action: action,
+		Region: region,
+		LatencyMs: float64(latency) / float64(time.Millisecond),
+		Code: "OK",
+		Retries: retries,
+	}
+	if err != nil {
+		entry.Code = string(ErrorCodeOf(err))
+		entry.Error = err.Error()
+		if typed := (*Error)(nil); errors.As(err, &typed) {
+			entry.ApiCode = typed.ApiCode
+			entry.RequestId = typed.RequestId
+		}
+	}
+	recordInvocation(entry, latency)
+	line, jsonErr := json.Marshal(entry)
+	if jsonErr != nil {
+		return
+	}
+	logLock.Lock()
+	defer logLock.Unlock()
+	if logOutput != nil {
+		logOutput.Write(append(line, '\n'))
+	}
+}
+
+// warningLog is the JSON line logged for a failure not failing the Call.
+type warningLog struct {
+	Time string `json:"time"`
+	Level string `json:"level"`
+	Message string `json:"message"`
+}
+
+// logWarning logs message with the invocations, as a JSON line of level
+// "warning".
+func logWarning(format string, args ...interface{}) {
+	line, err := json.Marshal(warningLog{
+		Time: time.Now().UTC().Format(time.RFC3339Nano),
+		Level: "warning",
+		Message: fmt.Sprintf(format, args...),
+	})
+	if err != nil {
+		return
+	}
+	logLock.Lock()
+	defer logLock.Unlock()
+	if logOutput != nil {
+		logOutput.Write(append(line, '\n'))
+	}
+}
+
+// observedDo runs fn with policy and observes it as action on product in
+// region. The error is returned classified.
+func observedDo(ctx context.Context, policy RetryPolicy, accountId, product, region, action string, fn func() error) error {
+	start := time.Now()
+	retries := 0
+	err := policy.Do(ctx, fn, func(attempt int, err error) {
+		retries++
+	})
+	err = Classify(product, region, action, err)
+	observe(accountId, product, region, action, time.Since(start), retries, err)
+	return err
+}
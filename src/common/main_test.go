@@ -0,0 +1,12 @@
+package common
+
+import (
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// Keep the buckets of the tests out of the user cache
+	os.Setenv(EnvRateLimitStateFile, RateLimitStateOff)
+	os.Exit(m.Run())
+}
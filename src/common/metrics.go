@@ -0,0 +1,276 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Env var of the default "metrics_addr" param
+const EnvMetricsAddr = "ALIBABA_CLOUD_METRICS_ADDR"
+
+// Env var of the default "metrics_file" param
+const EnvMetricsFile = "ALIBABA_CLOUD_METRICS_FILE"
+
+// Upper bounds in seconds of the buckets of the invocation latency
+var LatencyBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+type invocationSeries struct {
+	AccountId string
+	Product string
+	Action string
+	Region string
+	Code string
+}
+
+type invocationStats struct {
+	Count uint64
+	Retries uint64
+	Seconds float64
+	// Count of each of LatencyBuckets
+	Buckets []uint64
+}
+
+// plus returns stats with other added, the counts of other being negated
+// when sign is negative.
+func (stats invocationStats)plus(other invocationStats, sign int) invocationStats {
+	apply := func(value, delta uint64) uint64 {
+		if sign < 0 {
+			return value - delta
+		}
+		return value + delta
+	}
+	result := invocationStats{
+		Count: apply(stats.Count, other.Count),
+		Retries: apply(stats.Retries, other.Retries),
+		Seconds: stats.Seconds + float64(sign) * other.Seconds,
+		Buckets: make([]uint64, len(LatencyBuckets)),
+	}
+	for i := range result.Buckets {
+		var value, delta uint64
+		if i < len(stats.Buckets) {
+			value = stats.Buckets[i]
+		}
+		if i < len(other.Buckets) {
+			delta = other.Buckets[i]
+		}
+		result.Buckets[i] = apply(value, delta)
+	}
+	return result
+}
+
+var metricsLock sync.Mutex
+var invocationMetrics = map[invocationSeries]*invocationStats{}
+var metricsServer *http.Server
+var metricsAddr string
+
+// Invocations already added to the metrics file, see FlushMetrics
+var flushLock sync.Mutex
+var flushedMetrics = map[invocationSeries]invocationStats{}
+
+func recordInvocation(entry InvocationLog, latency time.Duration) {
+	series := invocationSeries{
+		AccountId: entry.AccountId,
+		Product: entry.Product,
+		Action: entry.Action,
+		Region: entry.Region,
+		Code: entry.Code,
+	}
+	metricsLock.Lock()
+	defer metricsLock.Unlock()
+	stats, ok := invocationMetrics[series]
+	if !ok {
+		stats = &invocationStats{Buckets: make([]uint64, len(LatencyBuckets))}
+		invocationMetrics[series] = stats
+	}
+	stats.Count++
+	stats.Retries += uint64(entry.Retries)
+	stats.Seconds += latency.Seconds()
+	for i, bound := range LatencyBuckets {
+		if latency.Seconds() <= bound {
+			stats.Buckets[i]++
+		}
+	}
+}
+
+func labelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func (series invocationSeries)labels() string {
+	return fmt.Sprintf(`account_id="%v",product="%v",action="%v",region="%v",code="%v"`,
+		labelValue(series.AccountId), labelValue(series.Product), labelValue(series.Action),
+		labelValue(series.Region), labelValue(series.Code))
+}
+
+// snapshotMetrics copies the invocation metrics of the process.
+func snapshotMetrics() map[invocationSeries]invocationStats {
+	metricsLock.Lock()
+	defer metricsLock.Unlock()
+	stats := map[invocationSeries]invocationStats{}
+	for key, value := range invocationMetrics {
+		stats[key] = invocationStats{
+			Count: value.Count,
+			Retries: value.Retries,
+			Seconds: value.Seconds,
+			Buckets: append([]uint64{}, value.Buckets...),
+		}
+	}
+	return stats
+}
+
+// WriteMetrics writes the invocation metrics of the process in the
+// Prometheus text format.
+func WriteMetrics(out io.Writer) error {
+	return writeMetrics(out, snapshotMetrics())
+}
+
+func writeMetrics(out io.Writer, stats map[invocationSeries]invocationStats) error {
+	var series []invocationSeries
+	labels := map[invocationSeries]string{}
+	for key := range stats {
+		series = append(series, key)
+		labels[key] = key.labels()
+	}
+	sort.Slice(series, func(i, j int) bool {
+		return labels[series[i]] < labels[series[j]]
+	})
+	var buf strings.Builder
+	buf.WriteString("# HELP aliyun_api_requests_total SDK invocations, after their retries.\n")
+	buf.WriteString("# TYPE aliyun_api_requests_total counter\n")
+	for _, key := range series {
+		fmt.Fprintf(&buf, "aliyun_api_requests_total{%v} %v\n", labels[key], stats[key].Count)
+	}
+	buf.WriteString("# HELP aliyun_api_retries_total Retries of the SDK invocations.\n")
+	buf.WriteString("# TYPE aliyun_api_retries_total counter\n")
+	for _, key := range series {
+		fmt.Fprintf(&buf, "aliyun_api_retries_total{%v} %v\n", labels[key], stats[key].Retries)
+	}
+	buf.WriteString("# HELP aliyun_api_request_duration_seconds Latency of the SDK invocations, retries included.\n")
+	buf.WriteString("# TYPE aliyun_api_request_duration_seconds histogram\n")
+	for _, key := range series {
+		for i, bound := range LatencyBuckets {
+			fmt.Fprintf(&buf, "aliyun_api_request_duration_seconds_bucket{%v,le=\"%v\"} %v\n", labels[key], bound, stats[key].Buckets[i])
+		}
+		fmt.Fprintf(&buf, "aliyun_api_request_duration_seconds_bucket{%v,le=\"+Inf\"} %v\n", labels[key], stats[key].Count)
+		fmt.Fprintf(&buf, "aliyun_api_request_duration_seconds_sum{%v} %v\n", labels[key], stats[key].Seconds)
+		fmt.Fprintf(&buf, "aliyun_api_request_duration_seconds_count{%v} %v\n", labels[key], stats[key].Count)
+	}
+	_, err := io.WriteString(out, buf.String())
+	return err
+}
+
+// ServeMetrics serves WriteMetrics at "/metrics" on addr, as
+// "127.0.0.1:9464". An empty addr stops serving. The endpoint only lives
+// as long as the process, see FlushMetrics for short lived ones.
+func ServeMetrics(addr string) error {
+	metricsLock.Lock()
+	defer metricsLock.Unlock()
+	if addr == metricsAddr {
+		return nil
+	}
+	if metricsServer != nil {
+		metricsServer.Close()
+		metricsServer, metricsAddr = nil, ""
+	}
+	if addr == "" {
+		return nil
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("serve metrics on %v: %v", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		WriteMetrics(w)
+	})
+	metricsServer, metricsAddr = &http.Server{Handler: mux}, addr
+	go metricsServer.Serve(listener)
+	return nil
+}
+
+// useMetrics serves the metrics at the "metrics_addr" param and returns
+// the "metrics_file" param. A failure to serve them is only logged, as an
+// address already bound by a concurrent provider process.
+func useMetrics(args map[string]interface{}) string {
+	addr := args["metrics_addr"].(string)
+	if addr == "" {
+		addr = os.Getenv(EnvMetricsAddr)
+	}
+	if err := ServeMetrics(addr); err != nil {
+		logWarning("%v", err)
+	}
+	path := args["metrics_file"].(string)
+	if path == "" {
+		path = os.Getenv(EnvMetricsFile)
+	}
+	return path
+}
+
+type metricsTotal struct {
+	Series invocationSeries
+	Stats invocationStats
+}
+
+// FlushMetrics adds the invocations of the process since its last flush
+// to the metrics of path, in the Prometheus text format, for the textfile
+// collector of node_exporter. The provider processes share path, their
+// totals being kept in path + ".json" and updated under the lock of path.
+func FlushMetrics(path string) error {
+	flushLock.Lock()
+	defer flushLock.Unlock()
+	current := snapshotMetrics()
+	deltas := map[invocationSeries]invocationStats{}
+	for key, stats := range current {
+		if delta := stats.plus(flushedMetrics[key], -1); delta.Count > 0 {
+			deltas[key] = delta
+		}
+	}
+	if len(deltas) == 0 {
+		return nil
+	}
+	unlock, err := LockFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	totals := map[invocationSeries]invocationStats{}
+	var saved []metricsTotal
+	if content, err := ioutil.ReadFile(path + ".json"); err == nil {
+		// Totals of an unreadable file start over
+		json.Unmarshal(content, &saved)
+	}
+	for _, total := range saved {
+		totals[total.Series] = total.Stats
+	}
+	for key, delta := range deltas {
+		totals[key] = totals[key].plus(delta, 1)
+	}
+	saved = saved[:0]
+	for key, stats := range totals {
+		saved = append(saved, metricsTotal{Series: key, Stats: stats})
+	}
+	content, err := json.Marshal(saved)
+	if err != nil {
+		return err
+	}
+	if err = WriteFileAtomic(path + ".json", content); err != nil {
+		return err
+	}
+	var buf strings.Builder
+	writeMetrics(&buf, totals)
+	if err = WriteFileAtomic(path, []byte(buf.String())); err != nil {
+		return err
+	}
+	flushedMetrics = current
+	return nil
+}
@@ -0,0 +1,110 @@
+package common
+
+import (
+	"github.com/hahaps/common-provider/src/input"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// resetMetrics starts the test as a new process, without invocations.
+func resetMetrics(t *testing.T) {
+	reset := func() {
+		metricsLock.Lock()
+		invocationMetrics = map[invocationSeries]*invocationStats{}
+		metricsLock.Unlock()
+		flushLock.Lock()
+		flushedMetrics = map[invocationSeries]invocationStats{}
+		flushLock.Unlock()
+	}
+	reset()
+	t.Cleanup(reset)
+}
+
+func invoke(action, code string, retries int) {
+	recordInvocation(InvocationLog{AccountId: "1", Product: ProductEcs, Action: action, Region: "cn-hangzhou", Code: code, Retries: retries}, 200 * time.Millisecond)
+}
+
+func TestWriteMetrics(t *testing.T) {
+	resetMetrics(t)
+	invoke("DescribeInstances", "OK", 0)
+	invoke("DescribeInstances", "OK", 2)
+	var buf strings.Builder
+	if err := WriteMetrics(&buf); err != nil {
+		t.Fatal(err)
+	}
+	labels := `account_id="1",product="ecs",action="DescribeInstances",region="cn-hangzhou",code="OK"`
+	for _, line := range []string{
+		"aliyun_api_requests_total{" + labels + "} 2",
+		"aliyun_api_retries_total{" + labels + "} 2",
+		"aliyun_api_request_duration_seconds_bucket{" + labels + `,le="0.1"} 0`,
+		"aliyun_api_request_duration_seconds_bucket{" + labels + `,le="0.25"} 2`,
+	} {
+		if !strings.Contains(buf.String(), line + "\n") {
+			t.Errorf("missing %v in\n%v", line, buf.String())
+		}
+	}
+}
+
+// An address already bound, as by a concurrent process, is logged and the
+// Call goes on.
+func TestServeMetricsBound(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	logFile := filepath.Join(t.TempDir(), "provider.log")
+	defer UseLog(LogOff)
+	invoker, err := NewInvoker(input.Params{Args: map[string]interface{}{
+		"metrics_addr": listener.Addr().String(),
+		"log": logFile,
+	}})
+	if err != nil {
+		t.Fatalf("NewInvoker failed on a bound metrics addr: %v", err)
+	}
+	invoker.Close()
+	content, _ := ioutil.ReadFile(logFile)
+	if !strings.Contains(string(content), `"level":"warning"`) || !strings.Contains(string(content), listener.Addr().String()) {
+		t.Errorf("unexpected log %s", content)
+	}
+}
+
+// Provider processes add their invocations to the same metrics file.
+func TestFlushMetrics(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "provider_aliyun.prom")
+	resetMetrics(t)
+	invoke("DescribeInstances", "OK", 1)
+	args := map[string]interface{}{"metrics_file": path, "log": LogOff}
+	invoker, err := NewInvoker(input.Params{Args: args})
+	if err != nil {
+		t.Fatal(err)
+	}
+	invoker.Close()
+	// Flushed invocations are not added twice
+	invoker.Close()
+	resetMetrics(t)
+	invoke("DescribeInstances", "OK", 0)
+	invoke("DescribeInstances", "Throttled", 4)
+	if err = FlushMetrics(path); err != nil {
+		t.Fatal(err)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := `account_id="1",product="ecs",action="DescribeInstances",region="cn-hangzhou"`
+	for _, line := range []string{
+		"aliyun_api_requests_total{" + labels + `,code="OK"} 2`,
+		"aliyun_api_retries_total{" + labels + `,code="OK"} 1`,
+		"aliyun_api_requests_total{" + labels + `,code="Throttled"} 1`,
+		"aliyun_api_request_duration_seconds_count{" + labels + `,code="OK"} 2`,
+	} {
+		if !strings.Contains(string(content), line + "\n") {
+			t.Errorf("missing %v in\n%s", line, content)
+		}
+	}
+}
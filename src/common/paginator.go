@@ -0,0 +1,188 @@
+package common
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+type PageStyle int
+
+const (
+	// PageNumber/PageSize, the end is computed from TotalCount
+	PageNumberStyle PageStyle = iota
+	// NextToken cursor with a page size
+	NextTokenStyle
+	// NextToken cursor with MaxResults
+	MaxResultsStyle
+)
+
+const markerVersion int = 1
+
+const markerPrefix string = "v1."
+
+var ErrTotalChanged = NewError(Upstream, "total count changed during pagination, restart from the first page")
+
+type pageState struct {
+	Version int `json:"v"`
+	Region string `json:"r,omitempty"`
+	Page int32 `json:"p,omitempty"`
+	Token string `json:"t,omitempty"`
+	// TotalCount of the first page, to detect changes in the middle
+	Total int32 `json:"n,omitempty"`
+}
+
+// Paginator walks the pages of a list API over one or more regions. Its
+// state is carried between calls by an opaque marker.
+type Paginator struct {
+	Style PageStyle
+	Regions []string
+	Limit int32
+	state pageState
+	done bool
+}
+
+// NewPaginator restores the paginator from marker. "" marks the end of the
+// pagination. Markers of previous versions are still accepted: plain page
+// numbers, "<region>:<page>", and raw NextTokens for the cursor styles.
+// Cursor styles restart page number markers from the first page.
+func NewPaginator(style PageStyle, marker string, regions []string, limit int32) (*Paginator, error) {
+	if len(regions) == 0 {
+		regions = []string{""}
+	}
+	pager := &Paginator{
+		Style: style,
+		Regions: regions,
+		Limit: limit,
+		state: pageState{
+			Version: markerVersion,
+			Region: regions[0],
+			Page: 1,
+		},
+	}
+	if marker == "" {
+		pager.done = true
+		return pager, nil
+	}
+	if strings.HasPrefix(marker, markerPrefix) {
+		content, err := base64.RawURLEncoding.DecodeString(marker[len(markerPrefix):])
+		if err != nil {
+			return nil, NewError(InvalidParam, "bad marker %v", marker)
+		}
+		if err = json.Unmarshal(content, &pager.state); err != nil {
+			return nil, NewError(InvalidParam, "bad marker %v", marker)
+		}
+		if pager.state.Version != markerVersion {
+			return nil, NewError(InvalidParam, "unsupported marker version %v", pager.state.Version)
+		}
+	} else {
+		page := marker
+		if idx := strings.Index(marker, ":"); idx >= 0 {
+			pager.state.Region = marker[:idx]
+			page = marker[idx + 1:]
+		}
+		number, err := strconv.ParseInt(page, 10, 32)
+		if style != PageNumberStyle && err != nil {
+			// Raw NextToken of a previous provider version
+			pager.state.Region = regions[0]
+			pager.state.Token = marker
+		} else if err != nil || number < 1 {
+			return nil, NewError(InvalidParam, "bad page number[marker] info")
+		} else if style == PageNumberStyle {
+			pager.state.Page = int32(number)
+		}
+	}
+	if pager.regionIndex() < 0 {
+		return nil, NewError(InvalidParam, "bad region %v in marker", pager.state.Region)
+	}
+	return pager, nil
+}
+
+// MarkerPosition returns the region of marker, "" when the marker does not
+// tell it, and whether marker is the first page of that region.
+func MarkerPosition(marker string) (string, bool) {
+	if strings.HasPrefix(marker, markerPrefix) {
+		state := pageState{}
+		content, err := base64.RawURLEncoding.DecodeString(marker[len(markerPrefix):])
+		if err != nil || json.Unmarshal(content, &state) != nil {
+			return "", false
+		}
+		return state.Region, state.Page <= 1 && state.Token == ""
+	}
+	region, page := "", marker
+	if idx := strings.Index(marker, ":"); idx >= 0 {
+		region, page = marker[:idx], marker[idx + 1:]
+	}
+	return region, page == "1"
+}
+
+func (pager *Paginator)regionIndex() int {
+	for i, region := range pager.Regions {
+		if region == pager.state.Region {
+			return i
+		}
+	}
+	return -1
+}
+
+// Done tells the marker was the end of the pagination.
+func (pager *Paginator)Done() bool {
+	return pager.done
+}
+
+func (pager *Paginator)Region() string {
+	return pager.state.Region
+}
+
+func (pager *Paginator)PageNumber() int32 {
+	return pager.state.Page
+}
+
+// NextToken returns the cursor of the current page, "" for the first one.
+func (pager *Paginator)NextToken() string {
+	return pager.state.Token
+}
+
+func (pager *Paginator)PageSize() int32 {
+	return pager.Limit
+}
+
+func (pager *Paginator)marker(state pageState) string {
+	content, _ := json.Marshal(state)
+	return markerPrefix + base64.RawURLEncoding.EncodeToString(content)
+}
+
+// Next records the TotalCount and NextToken of the current page response
+// and returns the marker of the following page, "" at the end.
+func (pager *Paginator)Next(total int32, nextToken string) (string, error) {
+	state := pager.state
+	first := state.Page == 1 && state.Token == ""
+	// Legacy markers do not carry the total
+	if !first && state.Total != 0 && state.Total != total && pager.Style == PageNumberStyle {
+		return "", ErrTotalChanged
+	}
+	state.Total = total
+	regionDone := false
+	switch pager.Style {
+	case PageNumberStyle:
+		regionDone = state.Page * pager.Limit >= total
+		state.Page += 1
+	default:
+		regionDone = nextToken == ""
+		state.Token = nextToken
+		state.Page += 1
+	}
+	if !regionDone {
+		return pager.marker(state), nil
+	}
+	idx := pager.regionIndex()
+	if idx + 1 >= len(pager.Regions) {
+		return "", nil
+	}
+	return pager.marker(pageState{
+		Version: markerVersion,
+		Region: pager.Regions[idx + 1],
+		Page: 1,
+	}), nil
+}
@@ -0,0 +1,121 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var pageRegions = []string{"cn-hangzhou", "cn-beijing"}
+
+func TestPaginator(t *testing.T) {
+	marker := "1"
+	var visited []string
+	for calls := 0; marker != ""; calls++ {
+		if calls == 10 {
+			t.Fatal("pagination does not end")
+		}
+		pager, err := NewPaginator(PageNumberStyle, marker, pageRegions, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		visited = append(visited, fmt.Sprintf("%v:%v", pager.Region(), pager.PageNumber()))
+		// 5 items in the first region, 1 in the second
+		total := int32(5)
+		if pager.Region() == "cn-beijing" {
+			total = 1
+		}
+		if marker, err = pager.Next(total, ""); err != nil {
+			t.Fatal(err)
+		}
+		if marker != "" && !strings.HasPrefix(marker, markerPrefix) {
+			t.Errorf("marker %v of an old version", marker)
+		}
+	}
+	want := "cn-hangzhou:1 cn-hangzhou:2 cn-hangzhou:3 cn-beijing:1"
+	if got := strings.Join(visited, " "); got != want {
+		t.Errorf("visited %v, want %v", got, want)
+	}
+	pager, err := NewPaginator(PageNumberStyle, "", pageRegions, 2)
+	if err != nil || !pager.Done() {
+		t.Errorf("empty marker is not the end: %v", err)
+	}
+}
+
+func TestPaginatorLegacy(t *testing.T) {
+	pager, err := NewPaginator(PageNumberStyle, "2", pageRegions, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pager.Region() != "cn-hangzhou" || pager.PageNumber() != 2 {
+		t.Errorf("marker 2 is page %v of %v", pager.PageNumber(), pager.Region())
+	}
+	marker, err := pager.Next(100, "")
+	if err != nil {
+		t.Fatalf("legacy marker past the first page: %v", err)
+	}
+	if pager, err = NewPaginator(PageNumberStyle, marker, pageRegions, 10); err != nil || pager.PageNumber() != 3 {
+		t.Errorf("marker %v is page %v: %v", marker, pager.PageNumber(), err)
+	}
+	pager, err = NewPaginator(PageNumberStyle, "cn-beijing:3", pageRegions, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pager.Region() != "cn-beijing" || pager.PageNumber() != 3 {
+		t.Errorf("marker cn-beijing:3 is page %v of %v", pager.PageNumber(), pager.Region())
+	}
+	if marker, err = pager.Next(25, ""); err != nil || marker != "" {
+		t.Errorf("last page of the last region gives %q, %v", marker, err)
+	}
+	pager, err = NewPaginator(NextTokenStyle, "raw-token", pageRegions, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pager.Region() != "cn-hangzhou" || pager.NextToken() != "raw-token" {
+		t.Errorf("raw token marker is %v in %v", pager.NextToken(), pager.Region())
+	}
+	if marker, err = pager.Next(0, ""); err != nil {
+		t.Fatal(err)
+	}
+	if pager, err = NewPaginator(NextTokenStyle, marker, pageRegions, 10); err != nil || pager.Region() != "cn-beijing" || pager.NextToken() != "" {
+		t.Errorf("marker %v after the last token of a region: %v", marker, err)
+	}
+	for _, bad := range []string{"0", "x", "cn-shanghai:1", "v1.%%%", "v1.eyJ2IjoyfQ"} {
+		if _, err = NewPaginator(PageNumberStyle, bad, pageRegions, 10); ErrorCodeOf(err) != InvalidParam {
+			t.Errorf("got %v for marker %v, want an InvalidParam error", err, bad)
+		}
+	}
+}
+
+func TestPaginatorTotalChanged(t *testing.T) {
+	pager, err := NewPaginator(PageNumberStyle, "1", pageRegions, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	marker, err := pager.Next(5, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pager, err = NewPaginator(PageNumberStyle, marker, pageRegions, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = pager.Next(6, ""); err != ErrTotalChanged {
+		t.Errorf("got %v for a changed total, want ErrTotalChanged", err)
+	}
+	// Cursors do not depend on the total
+	if pager, err = NewPaginator(MaxResultsStyle, "1", pageRegions, 2); err != nil {
+		t.Fatal(err)
+	}
+	if marker, err = pager.Next(5, "token-2"); err != nil {
+		t.Fatal(err)
+	}
+	if pager, err = NewPaginator(MaxResultsStyle, marker, pageRegions, 2); err != nil {
+		t.Fatal(err)
+	}
+	if pager.NextToken() != "token-2" {
+		t.Errorf("marker %v restores token %v", marker, pager.NextToken())
+	}
+	if _, err = pager.Next(6, "token-3"); err != nil {
+		t.Errorf("got %v for a changed total of a cursor", err)
+	}
+}
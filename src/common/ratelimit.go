@@ -0,0 +1,247 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EnvRateLimitStateFile sets the file sharing the buckets between the
+// provider processes, or RateLimitStateOff to keep them in memory
+const EnvRateLimitStateFile string = "ALIBABA_CLOUD_RATE_LIMIT_STATE_FILE"
+
+const RateLimitStateOff string = "off"
+
+// Entries of the state file left untouched for this long are dropped, their
+// bucket is full again anyway
+const rateLimitStateTTL = time.Hour
+
+// DefaultQuota is the QPS of the actions missing in DefaultQuotas
+const DefaultQuota float64 = 10
+
+// QPS allowed per account for each "product/Action"
+var DefaultQuotas = map[string]float64{
+	"ecs/DescribeRegions": 5,
+	"ecs/DescribeInstances": 20,
+	"ecs/DescribeDisks": 20,
+	"ecs/DescribeImages": 10,
+	"vpc/DescribeVpcs": 10,
+	"vpc/DescribeVSwitches": 10,
+	"ecs/DescribeNetworkInterfaces": 10,
+	"ecs/DescribeSecurityGroups": 10,
+	"vpc/DescribeEipAddresses": 10,
+	"cms/DescribeMetricLast": 20,
+	"cms/DescribeMetricList": 20,
+	"cms/DescribeMetricMetaList": 10,
+	"bss/DescribeInstanceBill": 10,
+	"resourcemanager/ListResourceGroups": 10,
+	"sts/GetCallerIdentity": 10,
+	"sts/AssumeRole": 10,
+}
+
+// TokenBucket lets rate calls per second through, with bursts of burst calls.
+// With a path, the tokens are kept in that file under its lock, shared by
+// every process using the same file and key.
+type TokenBucket struct {
+	lock sync.Mutex
+	rate float64
+	burst float64
+	tokens float64
+	last time.Time
+	path string
+	key string
+}
+
+func NewTokenBucket(rate float64) *TokenBucket {
+	bucket := &TokenBucket{last: time.Now()}
+	bucket.SetRate(rate)
+	bucket.tokens = bucket.burst
+	return bucket
+}
+
+// SetRate changes the rate of the bucket, the burst follows the rate.
+func (bucket *TokenBucket)SetRate(rate float64) {
+	bucket.lock.Lock()
+	defer bucket.lock.Unlock()
+	bucket.rate = rate
+	bucket.burst = rate
+	if bucket.burst < 1 {
+		bucket.burst = 1
+	}
+	if bucket.tokens > bucket.burst {
+		bucket.tokens = bucket.burst
+	}
+}
+
+// take refills tokens since last and takes one, returning how long to wait
+// before using it.
+func take(tokens *float64, last *time.Time, now time.Time, rate, burst float64) time.Duration {
+	*tokens += now.Sub(*last).Seconds() * rate
+	if *tokens > burst {
+		*tokens = burst
+	}
+	*last = now
+	*tokens -= 1
+	if *tokens >= 0 || rate <= 0 {
+		return 0
+	}
+	return time.Duration(-*tokens / rate * float64(time.Second))
+}
+
+// reserve takes a token and returns how long to wait before using it.
+func (bucket *TokenBucket)reserve() time.Duration {
+	bucket.lock.Lock()
+	defer bucket.lock.Unlock()
+	if bucket.path != "" && bucket.rate > 0 {
+		delay, err := bucket.reserveShared()
+		if err == nil {
+			return delay
+		}
+		// The state file is not usable, limit this process alone
+		bucket.path = ""
+	}
+	return take(&bucket.tokens, &bucket.last, time.Now(), bucket.rate, bucket.burst)
+}
+
+type bucketState struct {
+	Tokens float64 `json:"tokens"`
+	Last time.Time `json:"last"`
+}
+
+// reserveShared takes a token of the bucket in its state file.
+func (bucket *TokenBucket)reserveShared() (time.Duration, error) {
+	release, err := LockFile(bucket.path)
+	if err != nil {
+		return 0, err
+	}
+	defer release()
+	states := map[string]*bucketState{}
+	content, err := ioutil.ReadFile(bucket.path)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	if err == nil && json.Unmarshal(content, &states) != nil {
+		// A corrupt file only loses the past tokens
+		states = map[string]*bucketState{}
+	}
+	now := time.Now()
+	for key, state := range states {
+		if state == nil || now.Sub(state.Last) > rateLimitStateTTL {
+			delete(states, key)
+		}
+	}
+	state, ok := states[bucket.key]
+	if !ok {
+		state = &bucketState{Tokens: bucket.burst, Last: now}
+		states[bucket.key] = state
+	}
+	delay := take(&state.Tokens, &state.Last, now, bucket.rate, bucket.burst)
+	if content, err = json.Marshal(states); err != nil {
+		return 0, err
+	}
+	return delay, WriteFileAtomic(bucket.path, content)
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (bucket *TokenBucket)Wait(ctx context.Context) error {
+	delay := bucket.reserve()
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+type limiterKey struct {
+	AccountId string
+	Product string
+	Action string
+}
+
+var limiterLock = sync.Mutex{}
+
+var limiters = map[limiterKey]*TokenBucket{}
+
+// RateLimitStateFile returns the file sharing the buckets between the
+// provider processes, "" to keep them in memory.
+func RateLimitStateFile() string {
+	path := os.Getenv(EnvRateLimitStateFile)
+	if path == RateLimitStateOff {
+		return ""
+	}
+	if path != "" {
+		return path
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "provider-aliyun", "ratelimit.json")
+}
+
+// Limiter returns the bucket of action on product for the account, created
+// or updated with qps. A non positive qps disables the limit. The bucket is
+// shared with the other provider processes through RateLimitStateFile.
+func Limiter(accountId, product, action string, qps float64) *TokenBucket {
+	key := limiterKey{
+		AccountId: accountId,
+		Product: product,
+		Action: action,
+	}
+	path := RateLimitStateFile()
+	limiterLock.Lock()
+	defer limiterLock.Unlock()
+	bucket, ok := limiters[key]
+	if !ok {
+		bucket = NewTokenBucket(qps)
+		bucket.key = accountId + "/" + product + "/" + action
+		limiters[key] = bucket
+	} else if bucket.rate != qps {
+		bucket.SetRate(qps)
+	}
+	bucket.lock.Lock()
+	bucket.path = path
+	bucket.lock.Unlock()
+	return bucket
+}
+
+// LoadQuotas merges the quotas of a JSON file, an object of
+// "product/Action": qps, into quotas.
+func LoadQuotas(path string, quotas map[string]float64) error {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	fileQuotas := map[string]float64{}
+	if err = json.Unmarshal(content, &fileQuotas); err != nil {
+		return fmt.Errorf("bad rate limit file %v: %v", path, err)
+	}
+	for action, qps := range fileQuotas {
+		quotas[action] = qps
+	}
+	return nil
+}
+
+// MergeQuotas merges quotas given as a params map into quotas.
+func MergeQuotas(args map[string]interface{}, quotas map[string]float64) error {
+	for action, val := range args {
+		qps, err := strconv.ParseFloat(fmt.Sprint(val), 64)
+		if err != nil {
+			return fmt.Errorf("bad rate limit %v of %v", val, action)
+		}
+		quotas[action] = qps
+	}
+	return nil
+}
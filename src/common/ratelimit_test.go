@@ -0,0 +1,124 @@
+package common
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func sharedBucket(path string, rate float64) *TokenBucket {
+	bucket := NewTokenBucket(rate)
+	bucket.path = path
+	bucket.key = "1/ecs/DescribeInstances"
+	return bucket
+}
+
+func TestTokenBucket(t *testing.T) {
+	bucket := NewTokenBucket(5)
+	for i := 0; i < 5; i++ {
+		if delay := bucket.reserve(); delay != 0 {
+			t.Fatalf("token %v of the burst waits %v", i, delay)
+		}
+	}
+	if delay := bucket.reserve(); delay < 150 * time.Millisecond || delay > 200 * time.Millisecond {
+		t.Errorf("token past the burst waits %v, want about 200ms", delay)
+	}
+}
+
+func TestTokenBucketShared(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+	// Two processes sharing the file
+	buckets := []*TokenBucket{sharedBucket(path, 5), sharedBucket(path, 5)}
+	var delay time.Duration
+	for i := 0; i < 10; i++ {
+		delay = buckets[i % 2].reserve()
+	}
+	if delay < 900 * time.Millisecond || delay > time.Second {
+		t.Errorf("10th token of 5 QPS waits %v, want about 1s", delay)
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := map[string]*bucketState{}
+	if err = json.Unmarshal(content, &states); err != nil {
+		t.Fatal(err)
+	}
+	if state := states["1/ecs/DescribeInstances"]; state == nil || state.Tokens > -4 {
+		t.Errorf("unexpected state %+v", states)
+	}
+}
+
+func TestTokenBucketSharedConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+	wg := sync.WaitGroup{}
+	lock := sync.Mutex{}
+	var longest time.Duration
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			bucket := sharedBucket(path, 10)
+			for j := 0; j < 10; j++ {
+				delay := bucket.reserve()
+				lock.Lock()
+				if delay > longest {
+					longest = delay
+				}
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	// 40 tokens at 10 QPS with a burst of 10
+	if longest < 2500 * time.Millisecond || longest > 3 * time.Second {
+		t.Errorf("last of 40 tokens waits %v, want about 3s", longest)
+	}
+}
+
+func TestTokenBucketCorruptState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+	if err := ioutil.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	bucket := sharedBucket(path, 5)
+	if delay := bucket.reserve(); delay != 0 {
+		t.Errorf("first token waits %v", delay)
+	}
+	if bucket.path != path {
+		t.Error("a corrupt state file should be replaced")
+	}
+	unusable := sharedBucket(filepath.Join(path, "ratelimit.json"), 5)
+	if delay := unusable.reserve(); delay != 0 {
+		t.Errorf("first token waits %v without state file", delay)
+	}
+}
+
+func TestLimiterStateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ratelimit.json")
+	setEnv(t, EnvRateLimitStateFile, path)
+	if file := RateLimitStateFile(); file != path {
+		t.Errorf("state file %v, want %v", file, path)
+	}
+	bucket := Limiter("limiter-test", ProductEcs, "DescribeDisks", 20)
+	if bucket.path != path || bucket.key != "limiter-test/ecs/DescribeDisks" {
+		t.Errorf("unexpected bucket %v %v", bucket.path, bucket.key)
+	}
+	bucket.reserve()
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	states := map[string]*bucketState{}
+	json.Unmarshal(content, &states)
+	if _, ok := states["limiter-test/ecs/DescribeDisks"]; !ok {
+		t.Errorf("bucket missing in %s", content)
+	}
+	setEnv(t, EnvRateLimitStateFile, RateLimitStateOff)
+	if bucket = Limiter("limiter-test", ProductEcs, "DescribeDisks", 20); bucket.path != "" {
+		t.Errorf("bucket kept in %v when off", bucket.path)
+	}
+}
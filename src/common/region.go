@@ -0,0 +1,85 @@
+package common
+
+import (
+	"fmt"
+	ecs20140526 "github.com/alibabacloud-go/ecs-20140526/v2/client"
+	"github.com/hahaps/common-provider/src/common/utils"
+	"github.com/hahaps/common-provider/src/input"
+	"strings"
+	"sync"
+)
+
+// AllRegions makes a resource walk every region opened to the account
+const AllRegions string = "all"
+
+var regionLock = sync.Mutex{}
+
+var accountRegions = map[string][]string{}
+
+// NormalizeRegion turns a region list given in args into the comma
+// separated form accepted by the region schemes.
+func NormalizeRegion(args map[string]interface{}) {
+	regions, ok := args["region"].([]interface{})
+	if !ok {
+		return
+	}
+	names := make([]string, 0, len(regions))
+	for _, region := range regions {
+		names = append(names, strings.TrimSpace(fmt.Sprint(region)))
+	}
+	args["region"] = strings.Join(names, ",")
+}
+
+// Regions expands the region param, a region id, a comma separated list of
+// region ids, or "all" to discover the regions with ECS DescribeRegions.
+func Regions(invoker *Invoker, credential input.Credential, region string) ([]string, error) {
+	if strings.TrimSpace(region) == AllRegions {
+		return DescribeRegions(invoker, credential)
+	}
+	var regions []string
+	for _, r := range strings.Split(region, ",") {
+		if r = strings.TrimSpace(r); r != "" {
+			regions = append(regions, r)
+		}
+	}
+	if len(regions) == 0 {
+		return nil, NewError(InvalidParam, "Param[region] could not be empty")
+	}
+	return regions, nil
+}
+
+// DescribeRegions lists the ECS regions of the account through invoker,
+// cached for the lifetime of the provider.
+func DescribeRegions(invoker *Invoker, credential input.Credential) ([]string, error) {
+	key, err := GetAccessKey(invoker, credential)
+	if err != nil {
+		return nil, CredentialError(err)
+	}
+	regionLock.Lock()
+	defer regionLock.Unlock()
+	if regions, ok := accountRegions[key.Identity]; ok {
+		return regions, nil
+	}
+	cli, err := EcsClient(invoker, credential, "")
+	if err != nil {
+		return nil, err
+	}
+	defer ReleaseClient(cli)
+	var resp *ecs20140526.DescribeRegionsResponse
+	err = invoker.Invoke(ProductEcs, "", "DescribeRegions", func() (err error) {
+		resp, err = cli.DescribeRegions(&ecs20140526.DescribeRegionsRequest{})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if resp.Body.Regions == nil || len(resp.Body.Regions.Region) == 0 {
+		return nil, NewError(Upstream, "bad response for query regions")
+	}
+	var regions []string
+	for _, region := range resp.Body.Regions.Region {
+		regions = append(regions, utils.SafeString(region.RegionId))
+	}
+	accountRegions[key.Identity] = regions
+	return regions, nil
+}
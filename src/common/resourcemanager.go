@@ -0,0 +1,66 @@
+package common
+
+import (
+	openapi "github.com/alibabacloud-go/darabonba-openapi/client"
+	util "github.com/alibabacloud-go/tea-utils/service"
+	"github.com/alibabacloud-go/tea/tea"
+)
+
+const ResourceManagerVersion string = "2020-03-31"
+
+// ResourceManagerApiClient is a minimal client of the Resource Manager
+// OpenAPI.
+type ResourceManagerApiClient struct {
+	openapi.Client
+}
+
+func NewResourceManagerApiClient(config *openapi.Config) (*ResourceManagerApiClient, error) {
+	client := new(ResourceManagerApiClient)
+	err := client.Init(config)
+	return client, err
+}
+
+type ListResourceGroupsRequest struct {
+	PageNumber *int32 `json:"PageNumber,omitempty" xml:"PageNumber,omitempty"`
+	PageSize *int32 `json:"PageSize,omitempty" xml:"PageSize,omitempty"`
+	Status *string `json:"Status,omitempty" xml:"Status,omitempty"`
+}
+
+type ListResourceGroupsResponseBodyResourceGroupsResourceGroup struct {
+	Id *string `json:"Id,omitempty" xml:"Id,omitempty"`
+	Name *string `json:"Name,omitempty" xml:"Name,omitempty"`
+	DisplayName *string `json:"DisplayName,omitempty" xml:"DisplayName,omitempty"`
+	Status *string `json:"Status,omitempty" xml:"Status,omitempty"`
+	AccountId *string `json:"AccountId,omitempty" xml:"AccountId,omitempty"`
+	CreateDate *string `json:"CreateDate,omitempty" xml:"CreateDate,omitempty"`
+}
+
+type ListResourceGroupsResponseBodyResourceGroups struct {
+	ResourceGroup []*ListResourceGroupsResponseBodyResourceGroupsResourceGroup `json:"ResourceGroup,omitempty" xml:"ResourceGroup,omitempty" type:"Repeated"`
+}
+
+type ListResourceGroupsResponseBody struct {
+	RequestId *string `json:"RequestId,omitempty" xml:"RequestId,omitempty"`
+	TotalCount *int32 `json:"TotalCount,omitempty" xml:"TotalCount,omitempty"`
+	PageNumber *int32 `json:"PageNumber,omitempty" xml:"PageNumber,omitempty"`
+	PageSize *int32 `json:"PageSize,omitempty" xml:"PageSize,omitempty"`
+	ResourceGroups *ListResourceGroupsResponseBodyResourceGroups `json:"ResourceGroups,omitempty" xml:"ResourceGroups,omitempty" type:"Struct"`
+}
+
+type ListResourceGroupsResponse struct {
+	Headers map[string]*string `json:"headers,omitempty" xml:"headers,omitempty" require:"true"`
+	Body *ListResourceGroupsResponseBody `json:"body,omitempty" xml:"body,omitempty" require:"true"`
+}
+
+func (client *ResourceManagerApiClient)ListResourceGroups(request *ListResourceGroupsRequest) (_result *ListResourceGroupsResponse, _err error) {
+	req := &openapi.OpenApiRequest{
+		Body: util.ToMap(request),
+	}
+	_result = &ListResourceGroupsResponse{}
+	_body, _err := client.DoRPCRequest(tea.String("ListResourceGroups"), tea.String(ResourceManagerVersion), tea.String("HTTPS"), tea.String("POST"), tea.String("AK"), tea.String("json"), req, &util.RuntimeOptions{})
+	if _err != nil {
+		return _result, _err
+	}
+	_err = tea.Convert(_body, &_result)
+	return _result, _err
+}
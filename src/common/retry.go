@@ -0,0 +1,130 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"github.com/alibabacloud-go/tea/tea"
+	"io"
+	"math/rand"
+	"net"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+type RetryPolicy struct {
+	// Attempts of one invocation, the first one included
+	MaxAttempts int
+	BaseDelay time.Duration
+	MaxDelay time.Duration
+	// Time allowed for one invocation and its retries, no limit when zero
+	Budget time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay: 200 * time.Millisecond,
+	MaxDelay: 10 * time.Second,
+	Budget: 60 * time.Second,
+}
+
+// Error codes of Aliyun APIs worth retrying
+var RetryableCodes = map[string]bool{
+	"Throttling": true,
+	"Throttling.User": true,
+	"Throttling.Api": true,
+	"Throttling.Concurrent": true,
+	"ServiceUnavailable": true,
+	"ServiceUnavailableTemporary": true,
+	"InternalError": true,
+	"UnknownError": true,
+	"RequestTimeout": true,
+	"LastRequestNotFinished": true,
+}
+
+var statusPattern = regexp.MustCompile(`^code: (\d{3}),`)
+
+// SDKStatusCode returns the HTTP status of err, 0 if unknown.
+func SDKStatusCode(err *tea.SDKError) int {
+	if err.StatusCode != nil {
+		return *err.StatusCode
+	}
+	match := statusPattern.FindStringSubmatch(tea.StringValue(err.Message))
+	if match == nil {
+		return 0
+	}
+	status, _ := strconv.Atoi(match[1])
+	return status
+}
+
+// IsRetryable tells throttling, server side and network failures from
+// the errors that would fail again.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	sdkErr := &tea.SDKError{}
+	if errors.As(err, &sdkErr) {
+		if RetryableCodes[tea.StringValue(sdkErr.Code)] {
+			return true
+		}
+		status := SDKStatusCode(sdkErr)
+		return status == 429 || status >= 500
+	}
+	netErr := net.Error(nil)
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	opErr := &net.OpError{}
+	if errors.As(err, &opErr) {
+		return true
+	}
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// Backoff returns the jittered delay before retry attempt.
+func (policy RetryPolicy)Backoff(attempt int) time.Duration {
+	delay := policy.BaseDelay
+	for i := 1; i < attempt && delay < policy.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay / 2 + time.Duration(rand.Int63n(int64(delay / 2) + 1))
+}
+
+// Do runs fn until it succeeds, fails with a non retryable error, or the
+// attempts, the budget or ctx run out. The last error is returned.
+// onRetry, if not nil, is called before each retry. ctx only stops the
+// waits between attempts: a running fn is not cancelled, the SDK calls
+// take no context and are bounded by their own HTTP timeouts.
+func (policy RetryPolicy)Do(ctx context.Context, fn func() error, onRetry func(attempt int, err error)) error {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || !IsRetryable(err) || attempt >= policy.MaxAttempts {
+			return err
+		}
+		delay := policy.Backoff(attempt)
+		if policy.Budget > 0 && time.Since(start) + delay > policy.Budget {
+			return err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return err
+		}
+		if onRetry != nil {
+			onRetry(attempt, err)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
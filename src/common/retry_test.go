@@ -0,0 +1,184 @@
+package common_test
+
+import (
+	"context"
+	ecs20140526 "github.com/alibabacloud-go/ecs-20140526/v2/client"
+	"github.com/hahaps/common-provider/src/input"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"testing"
+	"time"
+)
+
+const throttled = "Request was denied due to request throttling."
+
+// describeRegions invokes ECS DescribeRegions against server through invoker
+func describeRegions(t *testing.T, invoker *common.Invoker) error {
+	credential := input.Credential{
+		SecretId: "fake-id",
+		SecretKey: "fake-key",
+		AccountId: invoker.AccountId,
+	}
+	cli, err := common.EcsClient(invoker, credential, fakeapi.Region)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return invoker.Invoke(common.ProductEcs, fakeapi.Region, "DescribeRegions", func() error {
+		_, err := cli.DescribeRegions(&ecs20140526.DescribeRegionsRequest{})
+		return err
+	})
+}
+
+func retryInvoker(t *testing.T, policy common.RetryPolicy) *common.Invoker {
+	invoker := common.BackgroundInvoker("retry-" + t.Name())
+	invoker.Retry = policy
+	return invoker
+}
+
+func fakeEcs(t *testing.T) *fakeapi.Server {
+	server := fakeapi.Start(t)
+	common.UseLog(common.LogOff)
+	return server
+}
+
+func TestRetryRecovers(t *testing.T) {
+	server := fakeEcs(t)
+	server.Fail("DescribeRegions", 2, 400, "Throttling", throttled)
+	invoker := retryInvoker(t, common.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay: 10 * time.Millisecond,
+		MaxDelay: 50 * time.Millisecond,
+		Budget: 5 * time.Second,
+	})
+	if err := describeRegions(t, invoker); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(server.Requests("DescribeRegions")); n != 3 {
+		t.Errorf("%v requests, want 3", n)
+	}
+}
+
+func TestRetryAttemptsSpent(t *testing.T) {
+	server := fakeEcs(t)
+	server.Fail("DescribeRegions", 10, 400, "Throttling", throttled)
+	invoker := retryInvoker(t, common.RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay: 10 * time.Millisecond,
+		MaxDelay: 50 * time.Millisecond,
+		Budget: 5 * time.Second,
+	})
+	err := describeRegions(t, invoker)
+	if common.ErrorCodeOf(err) != common.Throttled {
+		t.Errorf("got %v, want a Throttled error", err)
+	}
+	if n := len(server.Requests("DescribeRegions")); n != 3 {
+		t.Errorf("%v requests, want 3", n)
+	}
+}
+
+func TestRetryBudgetSpent(t *testing.T) {
+	server := fakeEcs(t)
+	server.Fail("DescribeRegions", 10, 400, "Throttling", throttled)
+	invoker := retryInvoker(t, common.RetryPolicy{
+		MaxAttempts: 10,
+		BaseDelay: 40 * time.Millisecond,
+		MaxDelay: 40 * time.Millisecond,
+		Budget: 100 * time.Millisecond,
+	})
+	start := time.Now()
+	err := describeRegions(t, invoker)
+	if common.ErrorCodeOf(err) != common.Throttled {
+		t.Errorf("got %v, want a Throttled error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("retried for %v past the budget", elapsed)
+	}
+	if n := len(server.Requests("DescribeRegions")); n < 2 || n >= 10 {
+		t.Errorf("%v requests, want a retry or more within the budget", n)
+	}
+}
+
+func TestRetryDeadline(t *testing.T) {
+	server := fakeEcs(t)
+	server.Fail("DescribeRegions", 10, 503, "ServiceUnavailable", "The request has failed due to a temporary failure of the server.")
+	invoker := retryInvoker(t, common.RetryPolicy{
+		MaxAttempts: 10,
+		BaseDelay: 100 * time.Millisecond,
+		MaxDelay: 100 * time.Millisecond,
+		Budget: time.Minute,
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 200 * time.Millisecond)
+	defer cancel()
+	invoker.Ctx = ctx
+	start := time.Now()
+	err := describeRegions(t, invoker)
+	if common.ErrorCodeOf(err) != common.Upstream {
+		t.Errorf("got %v, want an Upstream error", err)
+	}
+	// No retry is started when its delay ends past the deadline
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("retried for %v past the deadline", elapsed)
+	}
+	if n := len(server.Requests("DescribeRegions")); n < 2 || n > 5 {
+		t.Errorf("%v requests, want 2 to 5 before the deadline", n)
+	}
+	<-ctx.Done()
+	if err = describeRegions(t, invoker); err == nil {
+		t.Error("invoking past the deadline should fail")
+	}
+}
+
+func TestRetryCancelled(t *testing.T) {
+	server := fakeEcs(t)
+	server.Fail("DescribeRegions", 10, 400, "Throttling", throttled)
+	invoker := retryInvoker(t, common.RetryPolicy{
+		MaxAttempts: 10,
+		BaseDelay: 5 * time.Second,
+		MaxDelay: 5 * time.Second,
+		Budget: time.Minute,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	invoker.Ctx = ctx
+	time.AfterFunc(50 * time.Millisecond, cancel)
+	start := time.Now()
+	err := describeRegions(t, invoker)
+	if common.ErrorCodeOf(err) != common.Throttled {
+		t.Errorf("got %v, want a Throttled error", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("waited %v for a retry after the cancellation", elapsed)
+	}
+	if n := len(server.Requests("DescribeRegions")); n != 1 {
+		t.Errorf("%v requests, want 1", n)
+	}
+}
+
+func TestRetryNotRetryable(t *testing.T) {
+	server := fakeEcs(t)
+	server.Fail("DescribeRegions", 1, 403, "Forbidden.RAM", "User not authorized to operate on the specified resource.")
+	invoker := retryInvoker(t, common.DefaultRetryPolicy)
+	err := describeRegions(t, invoker)
+	if common.ErrorCodeOf(err) != common.Forbidden {
+		t.Errorf("got %v, want a Forbidden error", err)
+	}
+	if n := len(server.Requests("DescribeRegions")); n != 1 {
+		t.Errorf("%v requests, want 1", n)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := common.RetryPolicy{
+		BaseDelay: 100 * time.Millisecond,
+		MaxDelay: time.Second,
+	}
+	for attempt := 1; attempt < 10; attempt++ {
+		want := 100 * time.Millisecond << uint(attempt - 1)
+		if want > time.Second {
+			want = time.Second
+		}
+		delay := policy.Backoff(attempt)
+		if delay < want / 2 || delay > want {
+			t.Errorf("Backoff(%v) = %v, want within [%v, %v]", attempt, delay, want / 2, want)
+		}
+	}
+}
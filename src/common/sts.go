@@ -0,0 +1,90 @@
+package common
+
+import (
+	openapi "github.com/alibabacloud-go/darabonba-openapi/client"
+	util "github.com/alibabacloud-go/tea-utils/service"
+	"github.com/alibabacloud-go/tea/tea"
+)
+
+const StsVersion string = "2015-04-01"
+
+// StsClient is a minimal client of the STS OpenAPI, built the same way as
+// the generated product SDKs.
+type StsClient struct {
+	openapi.Client
+}
+
+func NewStsClient(config *openapi.Config) (*StsClient, error) {
+	client := new(StsClient)
+	err := client.Init(config)
+	return client, err
+}
+
+type AssumeRoleRequest struct {
+	RoleArn *string `json:"RoleArn,omitempty" xml:"RoleArn,omitempty"`
+	RoleSessionName *string `json:"RoleSessionName,omitempty" xml:"RoleSessionName,omitempty"`
+	DurationSeconds *int64 `json:"DurationSeconds,omitempty" xml:"DurationSeconds,omitempty"`
+	Policy *string `json:"Policy,omitempty" xml:"Policy,omitempty"`
+}
+
+type AssumeRoleResponseBodyCredentials struct {
+	AccessKeyId *string `json:"AccessKeyId,omitempty" xml:"AccessKeyId,omitempty"`
+	AccessKeySecret *string `json:"AccessKeySecret,omitempty" xml:"AccessKeySecret,omitempty"`
+	SecurityToken *string `json:"SecurityToken,omitempty" xml:"SecurityToken,omitempty"`
+	Expiration *string `json:"Expiration,omitempty" xml:"Expiration,omitempty"`
+}
+
+type AssumeRoleResponseBodyAssumedRoleUser struct {
+	Arn *string `json:"Arn,omitempty" xml:"Arn,omitempty"`
+	AssumedRoleId *string `json:"AssumedRoleId,omitempty" xml:"AssumedRoleId,omitempty"`
+}
+
+type AssumeRoleResponseBody struct {
+	RequestId *string `json:"RequestId,omitempty" xml:"RequestId,omitempty"`
+	Credentials *AssumeRoleResponseBodyCredentials `json:"Credentials,omitempty" xml:"Credentials,omitempty"`
+	AssumedRoleUser *AssumeRoleResponseBodyAssumedRoleUser `json:"AssumedRoleUser,omitempty" xml:"AssumedRoleUser,omitempty"`
+}
+
+type AssumeRoleResponse struct {
+	Headers map[string]*string `json:"headers,omitempty" xml:"headers,omitempty" require:"true"`
+	Body *AssumeRoleResponseBody `json:"body,omitempty" xml:"body,omitempty" require:"true"`
+}
+
+func (client *StsClient)AssumeRole(request *AssumeRoleRequest) (_result *AssumeRoleResponse, _err error) {
+	req := &openapi.OpenApiRequest{
+		Body: util.ToMap(request),
+	}
+	_result = &AssumeRoleResponse{}
+	_body, _err := client.DoRPCRequest(tea.String("AssumeRole"), tea.String(StsVersion), tea.String("HTTPS"), tea.String("POST"), tea.String("AK"), tea.String("json"), req, &util.RuntimeOptions{})
+	if _err != nil {
+		return _result, _err
+	}
+	_err = tea.Convert(_body, &_result)
+	return _result, _err
+}
+
+type GetCallerIdentityResponseBody struct {
+	RequestId *string `json:"RequestId,omitempty" xml:"RequestId,omitempty"`
+	AccountId *string `json:"AccountId,omitempty" xml:"AccountId,omitempty"`
+	Arn *string `json:"Arn,omitempty" xml:"Arn,omitempty"`
+	IdentityType *string `json:"IdentityType,omitempty" xml:"IdentityType,omitempty"`
+	PrincipalId *string `json:"PrincipalId,omitempty" xml:"PrincipalId,omitempty"`
+	UserId *string `json:"UserId,omitempty" xml:"UserId,omitempty"`
+	RoleId *string `json:"RoleId,omitempty" xml:"RoleId,omitempty"`
+}
+
+type GetCallerIdentityResponse struct {
+	Headers map[string]*string `json:"headers,omitempty" xml:"headers,omitempty" require:"true"`
+	Body *GetCallerIdentityResponseBody `json:"body,omitempty" xml:"body,omitempty" require:"true"`
+}
+
+func (client *StsClient)GetCallerIdentity() (_result *GetCallerIdentityResponse, _err error) {
+	req := &openapi.OpenApiRequest{}
+	_result = &GetCallerIdentityResponse{}
+	_body, _err := client.DoRPCRequest(tea.String("GetCallerIdentity"), tea.String(StsVersion), tea.String("HTTPS"), tea.String("POST"), tea.String("AK"), tea.String("json"), req, &util.RuntimeOptions{})
+	if _err != nil {
+		return _result, _err
+	}
+	_err = tea.Convert(_body, &_result)
+	return _result, _err
+}
@@ -0,0 +1,85 @@
+package common
+
+import (
+	"fmt"
+	"github.com/hahaps/common-provider/src/common/utils"
+	"sort"
+	"strings"
+)
+
+// MaxTagFilters is the most tags a Describe request filters on.
+const MaxTagFilters = 20
+
+var tagEscaper = strings.NewReplacer(`\`, `\\`, `;`, `\;`, `=`, `\=`)
+
+type Tag struct {
+	Key string
+	Value string
+}
+
+type Tags []Tag
+
+// Add appends the tag of the SDK key and value.
+func (tags *Tags)Add(key, value *string) {
+	*tags = append(*tags, Tag{Key: utils.SafeString(key), Value: utils.SafeString(value)})
+}
+
+// String is the legacy "k=v;k=v" form, with "\", ";" and "=" escaped by a
+// backslash.
+func (tags Tags)String() string {
+	pairs := make([]string, len(tags))
+	for i, tag := range tags {
+		pairs[i] = tagEscaper.Replace(tag.Key) + "=" + tagEscaper.Replace(tag.Value)
+	}
+	return strings.Join(pairs, ";")
+}
+
+// Map returns the tags keyed by tag key, the structured form stored in the
+// "Tags" field of Extra.
+func (tags Tags)Map() map[string]string {
+	result := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		result[tag.Key] = tag.Value
+	}
+	return result
+}
+
+// TagFilters reads the optional "tags" param of the inventory resources,
+// an object of tag keys to values matched on the server side, sorted by
+// key. An empty value matches any value of the key.
+func TagFilters(args map[string]interface{}) (Tags, error) {
+	filters := Tags{}
+	values, ok := args["tags"].(map[string]interface{})
+	if !ok {
+		return filters, nil
+	}
+	if len(values) > MaxTagFilters {
+		return nil, NewError(InvalidParam, "at most %v tags are allowed, got %v", MaxTagFilters, len(values))
+	}
+	for key, value := range values {
+		if key == "" {
+			return nil, NewError(InvalidParam, "tag key should not be empty")
+		}
+		if value == nil {
+			value = ""
+		}
+		str, ok := value.(string)
+		if !ok {
+			return nil, NewError(InvalidParam, "value of tag %v should be a string, got %v", key, fmt.Sprint(value))
+		}
+		filters = append(filters, Tag{Key: key, Value: str})
+	}
+	sort.Slice(filters, func(i, j int) bool {
+		return filters[i].Key < filters[j].Key
+	})
+	return filters, nil
+}
+
+// OptionalString returns nil for an empty value, to leave it out of a
+// request.
+func OptionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
@@ -0,0 +1,47 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTagsString(t *testing.T) {
+	cases := []struct {
+		tags Tags
+		want string
+	}{
+		{Tags{}, ""},
+		{Tags{{Key: "env", Value: "prod"}}, "env=prod"},
+		{Tags{{Key: "env", Value: "prod"}, {Key: "team", Value: ""}}, "env=prod;team="},
+		{Tags{{Key: "a;b", Value: "c=d"}}, `a\;b=c\=d`},
+		{Tags{{Key: "k=v", Value: "x;y"}, {Key: "z", Value: "1"}}, `k\=v=x\;y;z=1`},
+		{Tags{{Key: `back\slash`, Value: `\;`}}, `back\\slash=\\\;`},
+	}
+	for _, c := range cases {
+		if got := c.tags.String(); got != c.want {
+			t.Errorf("%v got %q, want %q", c.tags.Map(), got, c.want)
+		}
+	}
+}
+
+func TestTagFilters(t *testing.T) {
+	filters, err := TagFilters(map[string]interface{}{
+		"tags": map[string]interface{}{"team": nil, "env": "prod"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Tags{{Key: "env", Value: "prod"}, {Key: "team", Value: ""}}
+	if !reflect.DeepEqual(filters, want) {
+		t.Errorf("got %v, want %v", filters, want)
+	}
+	bad := []map[string]interface{}{
+		{"": "prod"},
+		{"env": 1},
+	}
+	for _, tags := range bad {
+		if _, err := TagFilters(map[string]interface{}{"tags": tags}); ErrorCodeOf(err) != InvalidParam {
+			t.Errorf("tags %v got %v, want an InvalidParam error", tags, err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package common
+
+import (
+	openapi "github.com/alibabacloud-go/darabonba-openapi/client"
+	util "github.com/alibabacloud-go/tea-utils/service"
+	"github.com/alibabacloud-go/tea/tea"
+)
+
+const VpcVersion string = "2016-04-28"
+
+// VpcApiClient is a minimal client of the VPC OpenAPI. Unlike their ECS
+// counterparts, its Describe actions filter on tags.
+type VpcApiClient struct {
+	openapi.Client
+}
+
+func NewVpcApiClient(config *openapi.Config) (*VpcApiClient, error) {
+	client := new(VpcApiClient)
+	err := client.Init(config)
+	return client, err
+}
+
+type VpcRequestTag struct {
+	Key *string `json:"Key,omitempty" xml:"Key,omitempty"`
+	Value *string `json:"Value,omitempty" xml:"Value,omitempty"`
+}
+
+type VpcResponseTags struct {
+	Tag []*VpcResponseTag `json:"Tag,omitempty" xml:"Tag,omitempty" type:"Repeated"`
+}
+
+type VpcResponseTag struct {
+	Key *string `json:"Key,omitempty" xml:"Key,omitempty"`
+	Value *string `json:"Value,omitempty" xml:"Value,omitempty"`
+}
+
+type DescribeVpcsRequest struct {
+	RegionId *string `json:"RegionId,omitempty" xml:"RegionId,omitempty"`
+	PageNumber *int32 `json:"PageNumber,omitempty" xml:"PageNumber,omitempty"`
+	PageSize *int32 `json:"PageSize,omitempty" xml:"PageSize,omitempty"`
+	ResourceGroupId *string `json:"ResourceGroupId,omitempty" xml:"ResourceGroupId,omitempty"`
+	Tag []*VpcRequestTag `json:"Tag,omitempty" xml:"Tag,omitempty" type:"Repeated"`
+}
+
+type DescribeVpcsResponseBodyVpcsVpc struct {
+	VpcId *string `json:"VpcId,omitempty" xml:"VpcId,omitempty"`
+	VpcName *string `json:"VpcName,omitempty" xml:"VpcName,omitempty"`
+	Status *string `json:"Status,omitempty" xml:"Status,omitempty"`
+	CreationTime *string `json:"CreationTime,omitempty" xml:"CreationTime,omitempty"`
+	Description *string `json:"Description,omitempty" xml:"Description,omitempty"`
+	CidrBlock *string `json:"CidrBlock,omitempty" xml:"CidrBlock,omitempty"`
+	VRouterId *string `json:"VRouterId,omitempty" xml:"VRouterId,omitempty"`
+	IsDefault *bool `json:"IsDefault,omitempty" xml:"IsDefault,omitempty"`
+	ResourceGroupId *string `json:"ResourceGroupId,omitempty" xml:"ResourceGroupId,omitempty"`
+	Tags *VpcResponseTags `json:"Tags,omitempty" xml:"Tags,omitempty" type:"Struct"`
+}
+
+type DescribeVpcsResponseBodyVpcs struct {
+	Vpc []*DescribeVpcsResponseBodyVpcsVpc `json:"Vpc,omitempty" xml:"Vpc,omitempty" type:"Repeated"`
+}
+
+type DescribeVpcsResponseBody struct {
+	RequestId *string `json:"RequestId,omitempty" xml:"RequestId,omitempty"`
+	TotalCount *int32 `json:"TotalCount,omitempty" xml:"TotalCount,omitempty"`
+	PageNumber *int32 `json:"PageNumber,omitempty" xml:"PageNumber,omitempty"`
+	PageSize *int32 `json:"PageSize,omitempty" xml:"PageSize,omitempty"`
+	Vpcs *DescribeVpcsResponseBodyVpcs `json:"Vpcs,omitempty" xml:"Vpcs,omitempty" type:"Struct"`
+}
+
+type DescribeVpcsResponse struct {
+	Headers map[string]*string `json:"headers,omitempty" xml:"headers,omitempty" require:"true"`
+	Body *DescribeVpcsResponseBody `json:"body,omitempty" xml:"body,omitempty" require:"true"`
+}
+
+type DescribeVSwitchesRequest struct {
+	RegionId *string `json:"RegionId,omitempty" xml:"RegionId,omitempty"`
+	VpcId *string `json:"VpcId,omitempty" xml:"VpcId,omitempty"`
+	PageNumber *int32 `json:"PageNumber,omitempty" xml:"PageNumber,omitempty"`
+	PageSize *int32 `json:"PageSize,omitempty" xml:"PageSize,omitempty"`
+	ResourceGroupId *string `json:"ResourceGroupId,omitempty" xml:"ResourceGroupId,omitempty"`
+	Tag []*VpcRequestTag `json:"Tag,omitempty" xml:"Tag,omitempty" type:"Repeated"`
+}
+
+type DescribeVSwitchesResponseBodyVSwitchesVSwitch struct {
+	VSwitchId *string `json:"VSwitchId,omitempty" xml:"VSwitchId,omitempty"`
+	VSwitchName *string `json:"VSwitchName,omitempty" xml:"VSwitchName,omitempty"`
+	VpcId *string `json:"VpcId,omitempty" xml:"VpcId,omitempty"`
+	ZoneId *string `json:"ZoneId,omitempty" xml:"ZoneId,omitempty"`
+	Status *string `json:"Status,omitempty" xml:"Status,omitempty"`
+	CreationTime *string `json:"CreationTime,omitempty" xml:"CreationTime,omitempty"`
+	Description *string `json:"Description,omitempty" xml:"Description,omitempty"`
+	CidrBlock *string `json:"CidrBlock,omitempty" xml:"CidrBlock,omitempty"`
+	ResourceGroupId *string `json:"ResourceGroupId,omitempty" xml:"ResourceGroupId,omitempty"`
+	Tags *VpcResponseTags `json:"Tags,omitempty" xml:"Tags,omitempty" type:"Struct"`
+}
+
+type DescribeVSwitchesResponseBodyVSwitches struct {
+	VSwitch []*DescribeVSwitchesResponseBodyVSwitchesVSwitch `json:"VSwitch,omitempty" xml:"VSwitch,omitempty" type:"Repeated"`
+}
+
+type DescribeVSwitchesResponseBody struct {
+	RequestId *string `json:"RequestId,omitempty" xml:"RequestId,omitempty"`
+	TotalCount *int32 `json:"TotalCount,omitempty" xml:"TotalCount,omitempty"`
+	PageNumber *int32 `json:"PageNumber,omitempty" xml:"PageNumber,omitempty"`
+	PageSize *int32 `json:"PageSize,omitempty" xml:"PageSize,omitempty"`
+	VSwitches *DescribeVSwitchesResponseBodyVSwitches `json:"VSwitches,omitempty" xml:"VSwitches,omitempty" type:"Struct"`
+}
+
+type DescribeVSwitchesResponse struct {
+	Headers map[string]*string `json:"headers,omitempty" xml:"headers,omitempty" require:"true"`
+	Body *DescribeVSwitchesResponseBody `json:"body,omitempty" xml:"body,omitempty" require:"true"`
+}
+
+type DescribeEipAddressesRequest struct {
+	RegionId *string `json:"RegionId,omitempty" xml:"RegionId,omitempty"`
+	PageNumber *int32 `json:"PageNumber,omitempty" xml:"PageNumber,omitempty"`
+	PageSize *int32 `json:"PageSize,omitempty" xml:"PageSize,omitempty"`
+	ResourceGroupId *string `json:"ResourceGroupId,omitempty" xml:"ResourceGroupId,omitempty"`
+	Tag []*VpcRequestTag `json:"Tag,omitempty" xml:"Tag,omitempty" type:"Repeated"`
+}
+
+type DescribeEipAddressesResponseBodyEipAddressesEipAddress struct {
+	AllocationId *string `json:"AllocationId,omitempty" xml:"AllocationId,omitempty"`
+	Name *string `json:"Name,omitempty" xml:"Name,omitempty"`
+	IpAddress *string `json:"IpAddress,omitempty" xml:"IpAddress,omitempty"`
+	Status *string `json:"Status,omitempty" xml:"Status,omitempty"`
+	AllocationTime *string `json:"AllocationTime,omitempty" xml:"AllocationTime,omitempty"`
+	ExpiredTime *string `json:"ExpiredTime,omitempty" xml:"ExpiredTime,omitempty"`
+	Bandwidth *string `json:"Bandwidth,omitempty" xml:"Bandwidth,omitempty"`
+	ChargeType *string `json:"ChargeType,omitempty" xml:"ChargeType,omitempty"`
+	InternetChargeType *string `json:"InternetChargeType,omitempty" xml:"InternetChargeType,omitempty"`
+	InstanceId *string `json:"InstanceId,omitempty" xml:"InstanceId,omitempty"`
+	InstanceType *string `json:"InstanceType,omitempty" xml:"InstanceType,omitempty"`
+	ResourceGroupId *string `json:"ResourceGroupId,omitempty" xml:"ResourceGroupId,omitempty"`
+	Tags *VpcResponseTags `json:"Tags,omitempty" xml:"Tags,omitempty" type:"Struct"`
+}
+
+type DescribeEipAddressesResponseBodyEipAddresses struct {
+	EipAddress []*DescribeEipAddressesResponseBodyEipAddressesEipAddress `json:"EipAddress,omitempty" xml:"EipAddress,omitempty" type:"Repeated"`
+}
+
+type DescribeEipAddressesResponseBody struct {
+	RequestId *string `json:"RequestId,omitempty" xml:"RequestId,omitempty"`
+	TotalCount *int32 `json:"TotalCount,omitempty" xml:"TotalCount,omitempty"`
+	PageNumber *int32 `json:"PageNumber,omitempty" xml:"PageNumber,omitempty"`
+	PageSize *int32 `json:"PageSize,omitempty" xml:"PageSize,omitempty"`
+	EipAddresses *DescribeEipAddressesResponseBodyEipAddresses `json:"EipAddresses,omitempty" xml:"EipAddresses,omitempty" type:"Struct"`
+}
+
+type DescribeEipAddressesResponse struct {
+	Headers map[string]*string `json:"headers,omitempty" xml:"headers,omitempty" require:"true"`
+	Body *DescribeEipAddressesResponseBody `json:"body,omitempty" xml:"body,omitempty" require:"true"`
+}
+
+func (client *VpcApiClient)DescribeVpcs(request *DescribeVpcsRequest) (_result *DescribeVpcsResponse, _err error) {
+	_result = &DescribeVpcsResponse{}
+	_err = client.doRPCRequest("DescribeVpcs", request, &_result)
+	return _result, _err
+}
+
+func (client *VpcApiClient)DescribeVSwitches(request *DescribeVSwitchesRequest) (_result *DescribeVSwitchesResponse, _err error) {
+	_result = &DescribeVSwitchesResponse{}
+	_err = client.doRPCRequest("DescribeVSwitches", request, &_result)
+	return _result, _err
+}
+
+func (client *VpcApiClient)DescribeEipAddresses(request *DescribeEipAddressesRequest) (_result *DescribeEipAddressesResponse, _err error) {
+	_result = &DescribeEipAddressesResponse{}
+	_err = client.doRPCRequest("DescribeEipAddresses", request, &_result)
+	return _result, _err
+}
+
+func (client *VpcApiClient)doRPCRequest(action string, request interface{}, result interface{}) error {
+	req := &openapi.OpenApiRequest{
+		Body: util.ToMap(request),
+	}
+	_body, _err := client.DoRPCRequest(tea.String(action), tea.String(VpcVersion), tea.String("HTTPS"), tea.String("POST"), tea.String("AK"), tea.String("json"), req, &util.RuntimeOptions{})
+	if _err != nil {
+		return _err
+	}
+	return tea.Convert(_body, result)
+}
+
+// VpcTags converts the tags of a VPC response.
+func VpcTags(tags *VpcResponseTags) Tags {
+	result := Tags{}
+	if tags == nil {
+		return result
+	}
+	for _, tag := range tags.Tag {
+		result.Add(tag.Key, tag.Value)
+	}
+	return result
+}
+
+// VpcTagFilters converts the "tags" param to VPC request tags.
+func VpcTagFilters(filters Tags) []*VpcRequestTag {
+	var result []*VpcRequestTag
+	for _, filter := range filters {
+		result = append(result, &VpcRequestTag{
+			Key: tea.String(filter.Key),
+			Value: OptionalString(filter.Value),
+		})
+	}
+	return result
+}
@@ -1,16 +1,13 @@
 package compute
 
 import (
-	"errors"
-	"fmt"
-	openapi "github.com/alibabacloud-go/darabonba-openapi/client"
 	ecs20140526 "github.com/alibabacloud-go/ecs-20140526/v2/client"
 	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hahaps/common-provider/src/common/model"
 	"github.com/hahaps/common-provider/src/common/utils"
 	"github.com/hahaps/common-provider/src/input"
 	"github.com/hahaps/common-provider/src/models/compute"
 	"github.com/hahaps/input-provider-aliyun/src/common"
-	"strconv"
 )
 
 var ImageSchemes = []utils.Scheme {
@@ -37,6 +34,17 @@ var ImageSchemes = []utils.Scheme {
 		Type: utils.String,
 		Default: "1",
 	},
+	utils.Scheme{
+		Param: "tags",
+		Required: false,
+		Type: utils.Map,
+	},
+	utils.Scheme{
+		Param: "resource_group_id",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
 }
 
 type Image struct {
@@ -45,46 +53,49 @@ type Image struct {
 	input.Resource
 }
 
-func (image *Image)init(credential input.Credential) error {
-	config := &openapi.Config{
-		AccessKeyId: &credential.SecretId,
-		AccessKeySecret: &credential.SecretKey,
-	}
-	config.Endpoint = tea.String("ecs.aliyuncs.com")
-	cli, err := ecs20140526.NewClient(config)
-	if err != nil {
-		return err
-	}
-	image.credential = credential
-	image.client = cli
-	return nil
+// NewModel returns an empty model of the resource.
+func (Image)NewModel() model.BaseModel {
+	return compute.NewImageModel()
 }
 
 func (Image)Call(params input.Params, replay *input.Replay) error {
 	image := &Image{}
-	var next string
 	var err error
 	var images []interface{}
+	common.NormalizeRegion(params.Args)
 	params.Args, err = utils.CheckParam(params.Args, ImageSchemes)
+	if err != nil {
+		return common.ParamError(err)
+	}
+	tagFilters, err := common.TagFilters(params.Args)
 	if err != nil {
 		return err
 	}
-	err = image.init(params.Credential)
+	invoker, err := common.NewInvoker(params)
+	if err != nil {
+		return err
+	}
+	defer invoker.Close()
+	regions, err := common.Regions(invoker, params.Credential, params.Args["region"].(string))
 	if err != nil {
 		return err
 	}
 	limit := int32(params.Args["limit"].(int))
-	next = params.Args["marker"].(string)
-	imageOwner := params.Args["image_owner"].(string)
-	region := params.Args["region"].(string)
-	if next == "" {
+	pager, err := common.NewPaginator(common.PageNumberStyle, params.Args["marker"].(string), regions, limit)
+	if err != nil {
+		return err
+	}
+	if pager.Done() {
 		return nil
 	}
-	number, err := strconv.ParseInt(next,10,32)
+	region := pager.Region()
+	image.credential = params.Credential
+	image.client, err = common.EcsClient(invoker, params.Credential, region)
 	if err != nil {
-		return errors.New("bad page number[marker] info")
+		return err
 	}
-	pageNum := int32(number)
+	defer common.ReleaseClient(image.client)
+	imageOwner := params.Args["image_owner"].(string)
 	query := map[string]interface{} {
 		"RegionId": region,
 		"CloudType": common.CloudType,
@@ -93,15 +104,26 @@ func (Image)Call(params input.Params, replay *input.Replay) error {
 	request := &ecs20140526.DescribeImagesRequest{
 		RegionId: &region,
 		ImageOwnerAlias: &imageOwner,
-		PageSize: &limit,
-		PageNumber: &pageNum,
+		PageSize: tea.Int32(pager.PageSize()),
+		PageNumber: tea.Int32(pager.PageNumber()),
+	}
+	for _, tag := range tagFilters {
+		request.Tag = append(request.Tag, &ecs20140526.DescribeImagesRequestTag{
+			Key: tea.String(tag.Key),
+			Value: common.OptionalString(tag.Value),
+		})
 	}
-	resp, err := image.client.DescribeImages(request)
+	request.ResourceGroupId = common.OptionalString(params.Args["resource_group_id"].(string))
+	var resp *ecs20140526.DescribeImagesResponse
+	err = invoker.Invoke(common.ProductEcs, region, "DescribeImages", func() (err error) {
+		resp, err = image.client.DescribeImages(request)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 	if resp.Body.Images == nil || resp.Body.Images.Image == nil {
-		return errors.New("bad response for query images")
+		return common.NewError(common.Upstream, "bad response for query images")
 	}
 	for _, b := range resp.Body.Images.Image {
 		img := compute.NewImageModel()
@@ -113,7 +135,8 @@ func (Image)Call(params input.Params, replay *input.Replay) error {
 		img.ProviderId = utils.SafeString(b.ImageId)
 		img.OSType = utils.SafeString(b.OSType)
 		img.Status = utils.SafeString(b.Status)
-		img.Tags = getImageTags(b.Tags)
+		tags := getImageTags(b.Tags)
+		img.Tags = tags.String()
 		img.Size = utils.SafeInt32(b.Size)
 		img.Description = utils.SafeString(b.Description)
 		img.CreateTime = utils.SafeString(b.CreationTime)
@@ -125,43 +148,36 @@ func (Image)Call(params input.Params, replay *input.Replay) error {
 			"OSNameEn": utils.SafeString(b.OSNameEn),
 			"ImageVersion": utils.SafeString(b.ImageVersion),
 			"Platform": utils.SafeString(b.Platform),
+			"Tags": tags.Map(),
 		}
 		img.SetIndex()
 		img.SetChecksum()
 		checked, key := img.CheckRequired()
 		if !checked {
-			return errors.New(
-				fmt.Sprintf("Value[%v] should not be empty", key))
+			return common.NewError(common.Upstream, "Value[%v] should not be empty", key)
 		}
 		images = append(images, img)
 	}
 	if !utils.CheckQueryKeys(query, compute.ImageModel{}) {
-		return errors.New("query key is not attribute of ImageModel")
+		return common.NewError(common.Internal, "query key is not attribute of ImageModel")
 	}
-	total := utils.SafeInt32(resp.Body.TotalCount)
-	if pageNum * limit >= total {
-		next = ""
-	} else {
-		next = strconv.Itoa(int(pageNum + 1))
+	replay.Next, err = pager.Next(utils.SafeInt32(resp.Body.TotalCount), "")
+	if err != nil {
+		return err
 	}
-	replay.Next = next
 	replay.Query = query
 	replay.Result = images
 	return nil
 }
 
 
-func getImageTags(tags *ecs20140526.DescribeImagesResponseBodyImagesImageTags) string {
+func getImageTags(tags *ecs20140526.DescribeImagesResponseBodyImagesImageTags) common.Tags {
+	result := common.Tags{}
 	if tags == nil {
-		return ""
-	}
-	tgs := ""
-	l := len(tags.Tag)
-	for i, tg := range tags.Tag {
-		tgs += fmt.Sprintf("%v=%v", tg.TagKey, tg.TagValue)
-		if i + 1 < l {
-			tgs += ";"
-		}
+		return result
+	}
+	for _, tg := range tags.Tag {
+		result.Add(tg.TagKey, tg.TagValue)
 	}
-	return tgs
+	return result
 }
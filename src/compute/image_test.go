@@ -0,0 +1,94 @@
+package compute_test
+
+import (
+	"github.com/hahaps/common-provider/src/models/compute"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"net/url"
+	"testing"
+)
+
+func image(id string) map[string]interface{} {
+	return map[string]interface{}{
+		"ImageId": id,
+		"ImageName": "image-" + id,
+		"Status": "Available",
+		"OSType": "linux",
+		"Size": 20,
+		"Tags": map[string]interface{}{"Tag": []interface{}{}},
+	}
+}
+
+func TestImage(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Handle("DescribeImages", fakeapi.Pages{
+		List: "Images.Image",
+		Items: fakeapi.Items(image("m-1"), image("m-2"), image("m-3")),
+	}.Handler())
+	var ids []string
+	marker := "1"
+	for calls := 0; marker != ""; calls++ {
+		if calls == 3 {
+			t.Fatal("pagination does not end")
+		}
+		replay, err := fakeapi.Call("Image", map[string]interface{}{"region": fakeapi.Region, "limit": 2, "marker": marker})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, record := range replay.Result {
+			ids = append(ids, record.(*compute.ImageModel).ProviderId)
+		}
+		if replay.Query["RegionId"] != fakeapi.Region {
+			t.Errorf("unexpected query %v", replay.Query)
+		}
+		marker = replay.Next
+	}
+	if len(ids) != 3 || ids[0] != "m-1" || ids[2] != "m-3" {
+		t.Errorf("images %v, want [m-1 m-2 m-3]", ids)
+	}
+	requests := server.Requests("DescribeImages")
+	if len(requests) != 2 || requests[0].Get("PageNumber") != "1" || requests[1].Get("PageNumber") != "2" || requests[1].Get("PageSize") != "2" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+	if requests[0].Get("ImageOwnerAlias") != "self" {
+		t.Errorf("ImageOwnerAlias %q, want self", requests[0].Get("ImageOwnerAlias"))
+	}
+}
+
+func TestImageTotalChanged(t *testing.T) {
+	server := fakeapi.Start(t)
+	first := fakeapi.Pages{
+		List: "Images.Image",
+		Items: fakeapi.Items(image("m-1"), image("m-2"), image("m-3")),
+	}.Handler()
+	shrunk := fakeapi.Pages{
+		List: "Images.Image",
+		Items: fakeapi.Items(image("m-1"), image("m-3")),
+	}.Handler()
+	server.Handle("DescribeImages", func(params url.Values) (int, interface{}) {
+		if params.Get("PageNumber") == "1" {
+			return first(params)
+		}
+		return shrunk(params)
+	})
+	replay, err := fakeapi.Call("Image", map[string]interface{}{"region": fakeapi.Region, "limit": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = fakeapi.Call("Image", map[string]interface{}{"region": fakeapi.Region, "limit": 2, "marker": replay.Next})
+	if err != common.ErrTotalChanged {
+		t.Errorf("got %v, want %v", err, common.ErrTotalChanged)
+	}
+}
+
+func TestImageFail(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Fail("DescribeImages", 10, 503, "ServiceUnavailable", "The request has failed due to a temporary failure of the server.")
+	_, err := fakeapi.Call("Image", map[string]interface{}{"region": fakeapi.Region, "retry_max_attempts": 2})
+	if common.ErrorCodeOf(err) != common.Upstream {
+		t.Errorf("got %v, want an Upstream error", err)
+	}
+	if n := len(server.Requests("DescribeImages")); n != 2 {
+		t.Errorf("%v requests, want 2", n)
+	}
+}
@@ -0,0 +1,10 @@
+package compute_test
+
+import (
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	fakeapi.Main(m)
+}
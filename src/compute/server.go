@@ -1,16 +1,13 @@
 package compute
 
 import (
-	"errors"
-	"fmt"
-	openapi "github.com/alibabacloud-go/darabonba-openapi/client"
 	ecs20140526 "github.com/alibabacloud-go/ecs-20140526/v2/client"
 	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hahaps/common-provider/src/common/model"
 	"github.com/hahaps/common-provider/src/common/utils"
 	"github.com/hahaps/common-provider/src/input"
 	"github.com/hahaps/common-provider/src/models/compute"
 	"github.com/hahaps/input-provider-aliyun/src/common"
-	"strconv"
 )
 
 var ServerSchemes = []utils.Scheme {
@@ -31,6 +28,17 @@ var ServerSchemes = []utils.Scheme {
 		Type: utils.String,
 		Default: "1",
 	},
+	utils.Scheme{
+		Param: "tags",
+		Required: false,
+		Type: utils.Map,
+	},
+	utils.Scheme{
+		Param: "resource_group_id",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
 }
 
 type Server struct {
@@ -39,56 +47,72 @@ type Server struct {
 	input.Resource
 }
 
-func (server *Server)init(credential input.Credential) error {
-	config := &openapi.Config{
-		AccessKeyId: &credential.SecretId,
-		AccessKeySecret: &credential.SecretKey,
-	}
-	config.Endpoint = tea.String("ecs-cn-hangzhou.aliyuncs.com")
-	cli, err := ecs20140526.NewClient(config)
-	if err != nil {
-		return err
-	}
-	server.credential = credential
-	server.client = cli
-	return nil
+// NewModel returns an empty model of the resource.
+func (Server)NewModel() model.BaseModel {
+	return compute.NewServerModel()
 }
 
 func (Server)Call(params input.Params, replay *input.Replay) error {
 	server := &Server{}
-	var next string
 	var err error
 	var servers []interface{}
+	common.NormalizeRegion(params.Args)
 	params.Args, err = utils.CheckParam(params.Args, ServerSchemes)
+	if err != nil {
+		return common.ParamError(err)
+	}
+	tagFilters, err := common.TagFilters(params.Args)
 	if err != nil {
 		return err
 	}
-	err = server.init(params.Credential)
+	invoker, err := common.NewInvoker(params)
+	if err != nil {
+		return err
+	}
+	defer invoker.Close()
+	regions, err := common.Regions(invoker, params.Credential, params.Args["region"].(string))
 	if err != nil {
 		return err
 	}
 	limit := int32(params.Args["limit"].(int))
-	next = params.Args["marker"].(string)
-	region := params.Args["region"].(string)
-	query := map[string]interface{} {
-		"RegionId": region,
-		"CloudType": common.CloudType,
-		"AccountId": server.credential.AccountId,
+	pager, err := common.NewPaginator(common.MaxResultsStyle, params.Args["marker"].(string), regions, limit)
+	if err != nil {
+		return err
 	}
-	if next == "" {
+	if pager.Done() {
 		return nil
 	}
-	number, err := strconv.ParseInt(next,10,32)
+	region := pager.Region()
+	server.credential = params.Credential
+	server.client, err = common.EcsClient(invoker, params.Credential, region)
 	if err != nil {
-		return errors.New("bad page number[marker] info")
+		return err
+	}
+	defer common.ReleaseClient(server.client)
+	query := map[string]interface{} {
+		"RegionId": region,
+		"CloudType": common.CloudType,
+		"AccountId": server.credential.AccountId,
 	}
-	pageNum := int32(number)
 	request := &ecs20140526.DescribeInstancesRequest{
 		RegionId: tea.String(region),
-		PageSize: &limit,
-		PageNumber: &pageNum,
+		MaxResults: tea.Int32(pager.PageSize()),
+	}
+	for _, tag := range tagFilters {
+		request.Tag = append(request.Tag, &ecs20140526.DescribeInstancesRequestTag{
+			Key: tea.String(tag.Key),
+			Value: common.OptionalString(tag.Value),
+		})
 	}
-	resp, err := server.client.DescribeInstances(request)
+	request.ResourceGroupId = common.OptionalString(params.Args["resource_group_id"].(string))
+	if pager.NextToken() != "" {
+		request.NextToken = tea.String(pager.NextToken())
+	}
+	var resp *ecs20140526.DescribeInstancesResponse
+	err = invoker.Invoke(common.ProductEcs, region, "DescribeInstances", func() (err error) {
+		resp, err = server.client.DescribeInstances(request)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -132,7 +156,8 @@ func (Server)Call(params input.Params, replay *input.Replay) error {
 			instance.EipAddress, instance.PublicIpAddress)
 		serv.SecondaryNics = getSecondaryNics(instance.NetworkInterfaces)
 		serv.SecurityGroups = getSecurityGroups(instance.SecurityGroupIds)
-		serv.Tags = getTags(instance.Tags)
+		tags := getTags(instance.Tags)
+		serv.Tags = tags.String()
 		serv.Extra = map[string]interface{}{
 			"StoppedMode": utils.SafeString(instance.StoppedMode),
 			"DeletionProtection": utils.SafeBool(instance.DeletionProtection, false),
@@ -141,26 +166,23 @@ func (Server)Call(params input.Params, replay *input.Replay) error {
 			"InstanceTypeFamily": utils.SafeString(instance.InstanceTypeFamily),
 			"ZoneId": utils.SafeString(instance.ZoneId),
 			"ResourceGroup": utils.SafeString(instance.ResourceGroupId),
+			"Tags": tags.Map(),
 		}
 		serv.SetIndex()
 		serv.SetChecksum()
 		checked, key := serv.CheckRequired()
 		if !checked {
-			return errors.New(
-				fmt.Sprintf("Value[%v] should not be empty", key))
+			return common.NewError(common.Upstream, "Value[%v] should not be empty", key)
 		}
 		servers = append(servers, serv)
 	}
 	if !utils.CheckQueryKeys(query, compute.ServerModel{}) {
-		return errors.New("query key is not attribute of ServerModel")
+		return common.NewError(common.Internal, "query key is not attribute of ServerModel")
 	}
-	total := utils.SafeInt32(resp.Body.TotalCount)
-	if pageNum * limit >= total {
-		next = ""
-	} else {
-		next = strconv.Itoa(int(pageNum + 1))
+	replay.Next, err = pager.Next(utils.SafeInt32(resp.Body.TotalCount), utils.SafeString(resp.Body.NextToken))
+	if err != nil {
+		return err
 	}
-	replay.Next = next
 	replay.Query = query
 	replay.Result = servers
 	return nil
@@ -191,19 +213,15 @@ func getSecurityGroups(sgs *ecs20140526.DescribeInstancesResponseBodyInstancesIn
 	return secg
 }
 
-func getTags(tags *ecs20140526.DescribeInstancesResponseBodyInstancesInstanceTags) string {
+func getTags(tags *ecs20140526.DescribeInstancesResponseBodyInstancesInstanceTags) common.Tags {
+	result := common.Tags{}
 	if tags == nil {
-		return ""
-	}
-	tgs := ""
-	l := len(tags.Tag)
-	for i, tg := range tags.Tag {
-		tgs += fmt.Sprintf("%v=%v", tg.TagKey, tg.TagValue)
-		if i + 1 < l {
-			tgs += ";"
-		}
+		return result
+	}
+	for _, tg := range tags.Tag {
+		result.Add(tg.TagKey, tg.TagValue)
 	}
-	return tgs
+	return result
 }
 
 func getFloatingIp(eIp *ecs20140526.DescribeInstancesResponseBodyInstancesInstanceEipAddress,
@@ -0,0 +1,142 @@
+package compute_test
+
+import (
+	"github.com/hahaps/common-provider/src/models"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func metrics(t *testing.T, result []interface{}) []*models.MetricModel {
+	var metrs []*models.MetricModel
+	for _, record := range result {
+		metr, ok := record.(**models.MetricModel)
+		if !ok {
+			t.Fatalf("unexpected record %#v", record)
+		}
+		metrs = append(metrs, *metr)
+	}
+	return metrs
+}
+
+func TestServerMetric(t *testing.T) {
+	server := fakeapi.Start(t)
+	args := map[string]interface{}{
+		"region": fakeapi.Region,
+		"metric_names": []interface{}{"CPUUtilization", "memory_usedutilization"},
+		"statistics": []interface{}{"Average"},
+	}
+	replay, err := fakeapi.Call("ServerMetric", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, metr := range metrics(t, replay.Result) {
+		names = append(names, metr.Name)
+		if metr.InstanceId != "i-fake0001" || metr.Value != "1" || metr.AccountId != fakeapi.AccountId {
+			t.Errorf("unexpected metric %+v", metr)
+		}
+		if metr.Extra["InstanceName"] != "fake-server" || metr.Extra["Region"] != fakeapi.Region {
+			t.Errorf("unexpected extra %v", metr.Extra)
+		}
+	}
+	sort.Strings(names)
+	if strings.Join(names, " ") != "CPUUtilization.Average memory_usedutilization.Average" {
+		t.Errorf("metrics %v", names)
+	}
+	if replay.Next != "" || replay.Query["AccountId"] != fakeapi.AccountId {
+		t.Errorf("unexpected Next %q and query %v", replay.Next, replay.Query)
+	}
+	requests := server.Requests("DescribeMetricLast")
+	if len(requests) != 2 {
+		t.Fatalf("%v DescribeMetricLast requests, want 2", len(requests))
+	}
+	for _, request := range requests {
+		if request.Get("Namespace") != "acs_ecs_dashboard" || !strings.Contains(request.Get("Dimensions"), "i-fake0001") {
+			t.Errorf("unexpected request %v", request)
+		}
+	}
+}
+
+func TestServerMetricPages(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Handle("DescribeInstances", fakeapi.Pages{
+		List: "Instances.Instance",
+		Items: fakeapi.Items(instance("i-1"), instance("i-2"), instance("i-3")),
+	}.Handler())
+	args := map[string]interface{}{
+		"region": fakeapi.Region,
+		"metric_names": []interface{}{"CPUUtilization"},
+		"statistics": []interface{}{"Maximum"},
+		"limit": 2,
+	}
+	replay, err := fakeapi.Call("ServerMetric", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrs := metrics(t, replay.Result); len(metrs) != 2 || metrs[0].InstanceId != "i-1" || metrs[1].InstanceId != "i-2" {
+		t.Errorf("unexpected metrics of the first page %+v", metrs)
+	}
+	if replay.Next == "" {
+		t.Fatal("no Next after the first inventory page")
+	}
+	args["marker"] = replay.Next
+	replay, err = fakeapi.Call("ServerMetric", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrs := metrics(t, replay.Result); len(metrs) != 1 || metrs[0].InstanceId != "i-3" {
+		t.Errorf("unexpected metrics of the last page %+v", metrs)
+	}
+	if replay.Next != "" {
+		t.Errorf("Next %q after the last page", replay.Next)
+	}
+	if n := len(server.Requests("DescribeMetricLast")); n != 2 {
+		t.Errorf("%v DescribeMetricLast requests, want 2", n)
+	}
+}
+
+func TestServerMetricFail(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Fail("DescribeMetricLast", 1, 403, "Forbidden", "User not authorized to operate on the specified resource.")
+	args := map[string]interface{}{
+		"region": fakeapi.Region,
+		"metric_names": []interface{}{"CPUUtilization"},
+	}
+	if _, err := fakeapi.Call("ServerMetric", args); common.ErrorCodeOf(err) != common.Forbidden {
+		t.Errorf("got %v, want a Forbidden error", err)
+	}
+	args["metric_names"] = []interface{}{"NoSuchMetric"}
+	if _, err := fakeapi.Call("ServerMetric", args); common.ErrorCodeOf(err) != common.InvalidParam {
+		t.Errorf("got %v, want an InvalidParam error", err)
+	}
+}
+
+func TestServerMetricSeries(t *testing.T) {
+	fakeapi.Start(t)
+	want := "VPC_PublicIP_InternetInRate.Average/ip" +
+		" diskusage_utilization.Average/device=/dev/vda1,mountpoint=/" +
+		" diskusage_utilization.Average/device=/dev/vdb1,mountpoint=/data"
+	for _, catalog := range []string{"auto", "builtin"} {
+		args := map[string]interface{}{
+			"region": fakeapi.Region,
+			"metric_names": []interface{}{"VPC_PublicIP_InternetInRate", "diskusage_utilization"},
+			"statistics": []interface{}{"Average"},
+			"metric_catalog": catalog,
+		}
+		replay, err := fakeapi.Call("ServerMetric", args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, metr := range metrics(t, replay.Result) {
+			names = append(names, metr.Name)
+		}
+		sort.Strings(names)
+		if strings.Join(names, " ") != want {
+			t.Errorf("%v catalog metrics %v, want %v", catalog, names, want)
+		}
+	}
+}
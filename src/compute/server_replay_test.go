@@ -0,0 +1,65 @@
+package compute_test
+
+import (
+	"flag"
+	"github.com/hahaps/input-provider-aliyun/src/cassette"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"os"
+	"testing"
+)
+
+var record = flag.Bool("record", false, "record the testdata cassettes again against fakeapi")
+
+const serverCassette = "testdata/server.json"
+
+// recordServer records two pages of DescribeInstances, the first one
+// throttled once.
+func recordServer(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Handle("DescribeInstances", fakeapi.Pages{
+		List: "Instances.Instance",
+		Items: fakeapi.Items(instance("i-1"), instance("i-2"), instance("i-3")),
+	}.Handler())
+	server.Fail("DescribeInstances", 1, 400, "Throttling", "Request was denied due to request throttling.")
+	os.Remove(serverCassette)
+	defer common.UseCassette("", "")
+	args := map[string]interface{}{"region": fakeapi.Region, "limit": 2, "cassette_mode": cassette.Record, "cassette": serverCassette}
+	for marker := "1"; marker != ""; {
+		args["marker"] = marker
+		replay, err := fakeapi.Call("Server", args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		marker = replay.Next
+	}
+}
+
+func TestServerReplay(t *testing.T) {
+	if *record {
+		recordServer(t)
+	}
+	c, err := cassette.Load(serverCassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaks := c.Leaks(); len(leaks) != 0 {
+		t.Fatalf("%v holds secrets %v", serverCassette, leaks)
+	}
+	defer common.UseCassette("", "")
+	args := map[string]interface{}{"region": fakeapi.Region, "limit": 2, "cassette_mode": cassette.Replay, "cassette": serverCassette}
+	replay, err := fakeapi.Call("Server", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := serverIds(t, replay.Result); len(ids) != 2 || ids[0] != "i-1" || ids[1] != "i-2" || replay.Next == "" {
+		t.Fatalf("first page %v, Next %q", ids, replay.Next)
+	}
+	args["marker"] = replay.Next
+	if replay, err = fakeapi.Call("Server", args); err != nil {
+		t.Fatal(err)
+	}
+	if ids := serverIds(t, replay.Result); len(ids) != 1 || ids[0] != "i-3" || replay.Next != "" {
+		t.Errorf("last page %v, Next %q", ids, replay.Next)
+	}
+}
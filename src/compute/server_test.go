@@ -0,0 +1,215 @@
+package compute_test
+
+import (
+	"github.com/hahaps/common-provider/src/models/compute"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"path/filepath"
+	"testing"
+)
+
+func instance(id string, tags ...map[string]interface{}) map[string]interface{} {
+	tagList := []interface{}{}
+	for _, tag := range tags {
+		tagList = append(tagList, tag)
+	}
+	return map[string]interface{}{
+		"InstanceId": id,
+		"InstanceName": "server-" + id,
+		"Status": "Running",
+		"InstanceType": "ecs.g6.large",
+		"Cpu": 2,
+		"Memory": 8192,
+		"CpuOptions": map[string]interface{}{"CoreCount": 1, "ThreadsPerCore": 2},
+		"VpcAttributes": map[string]interface{}{
+			"VpcId": "vpc-fake0001",
+			"VSwitchId": "vsw-fake0001",
+			"PrivateIpAddress": map[string]interface{}{"IpAddress": []string{"172.16.0.10"}},
+		},
+		"SecurityGroupIds": map[string]interface{}{"SecurityGroupId": []string{"sg-fake0001"}},
+		"Tags": map[string]interface{}{"Tag": tagList},
+	}
+}
+
+func serverIds(t *testing.T, result []interface{}) []string {
+	var ids []string
+	for _, record := range result {
+		server, ok := record.(*compute.ServerModel)
+		if !ok {
+			t.Fatalf("unexpected record %#v", record)
+		}
+		ids = append(ids, server.ProviderId)
+	}
+	return ids
+}
+
+func TestServer(t *testing.T) {
+	fakeapi.Start(t)
+	replay, err := fakeapi.Call("Server", map[string]interface{}{"region": fakeapi.Region})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.Result) != 1 || replay.Next != "" {
+		t.Fatalf("got %v records and Next %q, want 1 record and no Next", len(replay.Result), replay.Next)
+	}
+	server := replay.Result[0].(*compute.ServerModel)
+	if server.ProviderId != "i-fake0001" || server.RegionId != fakeapi.Region || server.AccountId != fakeapi.AccountId {
+		t.Errorf("unexpected server %+v", server)
+	}
+	if server.Tags != "env=test" || server.PrimaryNetworkId != "vpc-fake0001" || server.FlavorVCPU != 2 {
+		t.Errorf("unexpected server %+v", server)
+	}
+	if server.Index == "" || server.Checksum == "" {
+		t.Errorf("server without index or checksum %+v", server)
+	}
+	if replay.Query["RegionId"] != fakeapi.Region || replay.Query["AccountId"] != fakeapi.AccountId || replay.Query["CloudType"] != common.CloudType {
+		t.Errorf("unexpected query %v", replay.Query)
+	}
+}
+
+func TestServerPages(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Handle("DescribeInstances", fakeapi.Pages{
+		List: "Instances.Instance",
+		Items: fakeapi.Items(instance("i-1"), instance("i-2"), instance("i-3")),
+	}.Handler())
+	args := map[string]interface{}{"region": fakeapi.Region, "limit": 2}
+	replay, err := fakeapi.Call("Server", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := serverIds(t, replay.Result); len(ids) != 2 || ids[0] != "i-1" || ids[1] != "i-2" {
+		t.Errorf("first page %v, want [i-1 i-2]", ids)
+	}
+	if replay.Next == "" {
+		t.Fatal("no Next after the first page")
+	}
+	args = map[string]interface{}{"region": fakeapi.Region, "limit": 2, "marker": replay.Next}
+	replay, err = fakeapi.Call("Server", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := serverIds(t, replay.Result); len(ids) != 1 || ids[0] != "i-3" {
+		t.Errorf("second page %v, want [i-3]", ids)
+	}
+	if replay.Next != "" {
+		t.Errorf("Next %q after the last page", replay.Next)
+	}
+	requests := server.Requests("DescribeInstances")
+	if len(requests) != 2 {
+		t.Fatalf("%v requests, want 2", len(requests))
+	}
+	if requests[0].Get("MaxResults") != "2" || requests[0].Get("NextToken") != "" || requests[1].Get("NextToken") != "2" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
+
+func TestServerFilters(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Handle("DescribeInstances", fakeapi.Pages{
+		List: "Instances.Instance",
+		Items: fakeapi.Items(
+			instance("i-1", map[string]interface{}{"TagKey": "env", "TagValue": "prod"}),
+			instance("i-2", map[string]interface{}{"TagKey": "env", "TagValue": "test"}),
+		),
+	}.Handler())
+	args := map[string]interface{}{
+		"region": fakeapi.Region,
+		"tags": map[string]interface{}{"env": "prod"},
+		"resource_group_id": "rg-1",
+	}
+	if _, err := fakeapi.Call("Server", args); err != nil {
+		t.Fatal(err)
+	}
+	request := server.Requests("DescribeInstances")[0]
+	// The ECS SDK sends Tag.N.key
+	if request.Get("Tag.1.key") != "env" || request.Get("Tag.1.Value") != "prod" || request.Get("ResourceGroupId") != "rg-1" {
+		t.Errorf("unexpected request %v", request)
+	}
+	delete(args, "resource_group_id")
+	replay, err := fakeapi.Call("Server", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := serverIds(t, replay.Result); len(ids) != 1 || ids[0] != "i-1" {
+		t.Errorf("servers tagged env=prod %v, want [i-1]", ids)
+	}
+}
+
+func TestServerIncremental(t *testing.T) {
+	server := fakeapi.Start(t)
+	args := map[string]interface{}{
+		"region": fakeapi.Region,
+		"incremental": true,
+		"state_file": filepath.Join(t.TempDir(), "state.json"),
+	}
+	instances := fakeapi.Pages{
+		List: "Instances.Instance",
+		Items: fakeapi.Items(instance("i-1"), instance("i-2")),
+	}
+	server.Handle("DescribeInstances", instances.Handler())
+	replay, err := fakeapi.Call("Server", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := serverIds(t, replay.Result); len(ids) != 2 {
+		t.Errorf("first call got %v, want every server", ids)
+	}
+	replay, err = fakeapi.Call("Server", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.Result) != 0 {
+		t.Errorf("unchanged servers emitted again: %v", serverIds(t, replay.Result))
+	}
+	counts := replay.Query["Incremental"].(map[string]interface{})
+	if counts["Changed"] != 0 || counts["Unchanged"] != 2 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+	changed := instance("i-2")
+	changed["Status"] = "Stopped"
+	instances.Items = fakeapi.Items(instance("i-1"), changed)
+	server.Handle("DescribeInstances", instances.Handler())
+	replay, err = fakeapi.Call("Server", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := serverIds(t, replay.Result); len(ids) != 1 || ids[0] != "i-2" {
+		t.Errorf("changed servers %v, want [i-2]", ids)
+	}
+	counts = replay.Query["Incremental"].(map[string]interface{})
+	if counts["Changed"] != 1 || counts["Unchanged"] != 1 {
+		t.Errorf("unexpected counts %v", counts)
+	}
+}
+
+func TestServerAllRegions(t *testing.T) {
+	server := fakeapi.Start(t)
+	replay, err := fakeapi.Call("Server", map[string]interface{}{"region": common.AllRegions})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := serverIds(t, replay.Result); len(ids) != 1 || replay.Query["RegionId"] != fakeapi.Region {
+		t.Errorf("got %v in %v, want the server of %v", ids, replay.Query["RegionId"], fakeapi.Region)
+	}
+	if requests := server.Requests("DescribeInstances"); len(requests) != 1 || requests[0].Get("RegionId") != fakeapi.Region {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
+
+func TestServerFail(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Fail("DescribeInstances", 1, 403, "Forbidden.RAM", "User not authorized to operate on the specified resource.")
+	_, err := fakeapi.Call("Server", map[string]interface{}{"region": fakeapi.Region})
+	if common.ErrorCodeOf(err) != common.Forbidden {
+		t.Errorf("got %v, want a Forbidden error", err)
+	}
+	server.Fail("DescribeInstances", 1, 404, "InvalidRegionId.NotFound", "The specified region does not exist.")
+	_, err = fakeapi.Call("Server", map[string]interface{}{"region": fakeapi.Region})
+	if common.ErrorCodeOf(err) != common.RegionUnavailable {
+		t.Errorf("got %v, want a RegionUnavailable error", err)
+	}
+	if _, err = fakeapi.Call("Server", map[string]interface{}{"region": fakeapi.Region, "marker": "v1.bad"}); common.ErrorCodeOf(err) != common.InvalidParam {
+		t.Errorf("got %v, want an InvalidParam error", err)
+	}
+}
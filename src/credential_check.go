@@ -0,0 +1,417 @@
+package src
+
+import (
+	"fmt"
+	bssopenapi20171214 "github.com/alibabacloud-go/bssopenapi-20171214/client"
+	cms20190101 "github.com/alibabacloud-go/cms-20190101/v2/client"
+	ecs20140526 "github.com/alibabacloud-go/ecs-20140526/v2/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hahaps/common-provider/src/common/utils"
+	"github.com/hahaps/common-provider/src/input"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/compute"
+	"github.com/hahaps/input-provider-aliyun/src/network"
+	"strings"
+	"time"
+)
+
+// Status of a PermissionProbe
+const (
+	ProbeAllowed string = "allowed"
+	ProbeDenied string = "denied"
+	ProbeRegionUnavailable string = "region-unavailable"
+	// Failed for another reason, see its Code
+	ProbeFailed string = "failed"
+)
+
+var CredentialCheckSchemes = []utils.Scheme {
+	utils.Scheme{
+		Param: "region",
+		Required: true,
+		Type: utils.String,
+	},
+	utils.Scheme{
+		Param: "resources",
+		Required: false,
+		Type: utils.Slice,
+		Default: []interface{}{},
+	},
+}
+
+// Probe calls the API action of a resource with a minimal request.
+type Probe struct {
+	Product string
+	Action string
+	// Regional probes run in every region of the "region" param, the
+	// others once
+	Regional bool
+	Call func(invoker *common.Invoker, credential input.Credential, region string) error
+}
+
+// Probes of the resources of ResourceMap calling an API
+var ProbeMap = map[string]Probe {
+	"Server": Probe{
+		Product: common.ProductEcs,
+		Action: "DescribeInstances",
+		Regional: true,
+		Call: func(invoker *common.Invoker, credential input.Credential, region string) error {
+			cli, err := common.EcsClient(invoker, credential, region)
+			if err != nil {
+				return err
+			}
+			defer common.ReleaseClient(cli)
+			_, err = cli.DescribeInstances(&ecs20140526.DescribeInstancesRequest{
+				RegionId: tea.String(region),
+				MaxResults: tea.Int32(10),
+			})
+			return err
+		},
+	},
+	"ServerMetric": Probe{
+		Product: common.ProductCms,
+		Action: "DescribeMetricLast",
+		Regional: true,
+		Call: func(invoker *common.Invoker, credential input.Credential, region string) error {
+			return probeMetric(invoker, credential, region, compute.ServerMetricNamespace, "CPUUtilization")
+		},
+	},
+	"Image": Probe{
+		Product: common.ProductEcs,
+		Action: "DescribeImages",
+		Regional: true,
+		Call: func(invoker *common.Invoker, credential input.Credential, region string) error {
+			cli, err := common.EcsClient(invoker, credential, region)
+			if err != nil {
+				return err
+			}
+			defer common.ReleaseClient(cli)
+			_, err = cli.DescribeImages(&ecs20140526.DescribeImagesRequest{
+				RegionId: tea.String(region),
+				PageSize: tea.Int32(1),
+			})
+			return err
+		},
+	},
+	"Disk": Probe{
+		Product: common.ProductEcs,
+		Action: "DescribeDisks",
+		Regional: true,
+		Call: func(invoker *common.Invoker, credential input.Credential, region string) error {
+			cli, err := common.EcsClient(invoker, credential, region)
+			if err != nil {
+				return err
+			}
+			defer common.ReleaseClient(cli)
+			_, err = cli.DescribeDisks(&ecs20140526.DescribeDisksRequest{
+				RegionId: tea.String(region),
+				MaxResults: tea.Int32(10),
+			})
+			return err
+		},
+	},
+	"Network": Probe{
+		Product: common.ProductVpc,
+		Action: "DescribeVpcs",
+		Regional: true,
+		Call: func(invoker *common.Invoker, credential input.Credential, region string) error {
+			cli, err := common.VpcClient(invoker, credential, region)
+			if err != nil {
+				return err
+			}
+			defer common.ReleaseClient(cli)
+			_, err = cli.DescribeVpcs(&common.DescribeVpcsRequest{
+				RegionId: tea.String(region),
+				PageSize: tea.Int32(1),
+			})
+			return err
+		},
+	},
+	"Subnet": Probe{
+		Product: common.ProductVpc,
+		Action: "DescribeVSwitches",
+		Regional: true,
+		Call: func(invoker *common.Invoker, credential input.Credential, region string) error {
+			cli, err := common.VpcClient(invoker, credential, region)
+			if err != nil {
+				return err
+			}
+			defer common.ReleaseClient(cli)
+			_, err = cli.DescribeVSwitches(&common.DescribeVSwitchesRequest{
+				RegionId: tea.String(region),
+				PageSize: tea.Int32(1),
+			})
+			return err
+		},
+	},
+	"Nic": Probe{
+		Product: common.ProductEcs,
+		Action: "DescribeNetworkInterfaces",
+		Regional: true,
+		Call: func(invoker *common.Invoker, credential input.Credential, region string) error {
+			cli, err := common.EcsClient(invoker, credential, region)
+			if err != nil {
+				return err
+			}
+			defer common.ReleaseClient(cli)
+			_, err = cli.DescribeNetworkInterfaces(&ecs20140526.DescribeNetworkInterfacesRequest{
+				RegionId: tea.String(region),
+				MaxResults: tea.Int32(10),
+			})
+			return err
+		},
+	},
+	"SecurityGroup": Probe{
+		Product: common.ProductEcs,
+		Action: "DescribeSecurityGroups",
+		Regional: true,
+		Call: func(invoker *common.Invoker, credential input.Credential, region string) error {
+			cli, err := common.EcsClient(invoker, credential, region)
+			if err != nil {
+				return err
+			}
+			defer common.ReleaseClient(cli)
+			_, err = cli.DescribeSecurityGroups(&ecs20140526.DescribeSecurityGroupsRequest{
+				RegionId: tea.String(region),
+				PageSize: tea.Int32(1),
+			})
+			return err
+		},
+	},
+	"FloatingIp": Probe{
+		Product: common.ProductVpc,
+		Action: "DescribeEipAddresses",
+		Regional: true,
+		Call: func(invoker *common.Invoker, credential input.Credential, region string) error {
+			cli, err := common.VpcClient(invoker, credential, region)
+			if err != nil {
+				return err
+			}
+			defer common.ReleaseClient(cli)
+			_, err = cli.DescribeEipAddresses(&common.DescribeEipAddressesRequest{
+				RegionId: tea.String(region),
+				PageSize: tea.Int32(1),
+			})
+			return err
+		},
+	},
+	"FloatingIpMetric": Probe{
+		Product: common.ProductCms,
+		Action: "DescribeMetricLast",
+		Regional: true,
+		Call: func(invoker *common.Invoker, credential input.Credential, region string) error {
+			return probeMetric(invoker, credential, region, network.FloatingIpMetricNamespace, "net_rx.rate")
+		},
+	},
+	// The namespace of a Metric is only known when it is called, the RAM
+	// permission of DescribeMetricLast is the same for every namespace
+	"Metric": Probe{
+		Product: common.ProductCms,
+		Action: "DescribeMetricLast",
+		Regional: true,
+		Call: func(invoker *common.Invoker, credential input.Credential, region string) error {
+			return probeMetric(invoker, credential, region, compute.ServerMetricNamespace, "CPUUtilization")
+		},
+	},
+	"MetricCatalog": Probe{
+		Product: common.ProductCms,
+		Action: "DescribeMetricMetaList",
+		Regional: false,
+		Call: func(invoker *common.Invoker, credential input.Credential, region string) error {
+			cli, err := common.CmsClient(invoker, credential, "")
+			if err != nil {
+				return err
+			}
+			defer common.ReleaseClient(cli)
+			resp, err := cli.DescribeMetricMetaList(&cms20190101.DescribeMetricMetaListRequest{
+				Namespace: tea.String(compute.ServerMetricNamespace),
+				PageSize: tea.Int32(1),
+			})
+			if err != nil {
+				return err
+			}
+			if !tea.BoolValue(resp.Body.Success) {
+				return common.ResponseError(common.ProductCms, "", "DescribeMetricMetaList", utils.SafeString(resp.Body.Code),
+					utils.SafeString(resp.Body.Message), utils.SafeString(resp.Body.RequestId))
+			}
+			return nil
+		},
+	},
+	"InstanceBill": Probe{
+		Product: common.ProductBss,
+		Action: "DescribeInstanceBill",
+		Regional: false,
+		Call: func(invoker *common.Invoker, credential input.Credential, region string) error {
+			cli, err := common.BssClient(invoker, credential)
+			if err != nil {
+				return err
+			}
+			defer common.ReleaseClient(cli)
+			resp, err := cli.DescribeInstanceBill(&bssopenapi20171214.DescribeInstanceBillRequest{
+				BillingCycle: tea.String(time.Now().Format("2006-01")),
+				MaxResults: tea.Int32(1),
+			})
+			if err != nil {
+				return err
+			}
+			if !tea.BoolValue(resp.Body.Success) {
+				return common.ResponseError(common.ProductBss, "", "DescribeInstanceBill", utils.SafeString(resp.Body.Code),
+					utils.SafeString(resp.Body.Message), utils.SafeString(resp.Body.RequestId))
+			}
+			return nil
+		},
+	},
+	"ResourceGroup": Probe{
+		Product: common.ProductResourceManager,
+		Action: "ListResourceGroups",
+		Regional: false,
+		Call: func(invoker *common.Invoker, credential input.Credential, region string) error {
+			cli, err := common.ResourceManagerClient(invoker, credential)
+			if err != nil {
+				return err
+			}
+			defer common.ReleaseClient(cli)
+			_, err = cli.ListResourceGroups(&common.ListResourceGroupsRequest{
+				PageSize: tea.Int32(1),
+			})
+			return err
+		},
+	},
+}
+
+func probeMetric(invoker *common.Invoker, credential input.Credential, region, namespace, metricName string) error {
+	cli, err := common.CmsClient(invoker, credential, region)
+	if err != nil {
+		return err
+	}
+	defer common.ReleaseClient(cli)
+	resp, err := cli.DescribeMetricLast(&cms20190101.DescribeMetricLastRequest{
+		Namespace: tea.String(namespace),
+		MetricName: tea.String(metricName),
+		Length: tea.String("1"),
+	})
+	if err != nil {
+		return err
+	}
+	if !tea.BoolValue(resp.Body.Success) {
+		return common.ResponseError(common.ProductCms, region, "DescribeMetricLast", utils.SafeString(resp.Body.Code),
+			utils.SafeString(resp.Body.Message), utils.SafeString(resp.Body.RequestId))
+	}
+	return nil
+}
+
+// PermissionProbe is the outcome of the Probe of a resource in a region.
+type PermissionProbe struct {
+	Resource string
+	Product string
+	Action string
+	Region string
+	Status string
+	// ErrorCode of the failed probes
+	Code string
+	Message string
+}
+
+// CredentialCheck validates the credential: it resolves the account of the
+// AccessKey with STS GetCallerIdentity, then runs the Probe of each
+// resource, or of the "resources" param. The result has a PermissionProbe
+// per resource and region. The account is set to the "AccountId" key of
+// replay.Query, and "AccountMatched" tells whether it is the AccountId of
+// the credential.
+type CredentialCheck struct {
+	input.Resource
+}
+
+func (CredentialCheck)Call(params input.Params, replay *input.Replay) error {
+	var err error
+	common.NormalizeRegion(params.Args)
+	params.Args, err = utils.CheckParam(params.Args, CredentialCheckSchemes)
+	if err != nil {
+		return common.ParamError(err)
+	}
+	invoker, err := common.NewInvoker(params)
+	if err != nil {
+		return err
+	}
+	defer invoker.Close()
+	names := ResourceNames()
+	if resources := params.Args["resources"].([]interface{}); len(resources) > 0 {
+		names = nil
+		for _, resource := range resources {
+			name := strings.TrimSpace(fmt.Sprint(resource))
+			if _, ok := ProbeMap[name]; !ok {
+				return common.NewError(common.InvalidParam, "no probe for resource %v", name)
+			}
+			names = append(names, name)
+		}
+	}
+	config, err := common.NewConfig(invoker, params.Credential, common.ProductSts, "")
+	if err != nil {
+		return common.CredentialError(err)
+	}
+	sts, err := common.NewStsClient(config)
+	if err != nil {
+		return common.NewError(common.Internal, "%v", err)
+	}
+	var resp *common.GetCallerIdentityResponse
+	err = invoker.Invoke(common.ProductSts, "", "GetCallerIdentity", func() (err error) {
+		resp, err = sts.GetCallerIdentity()
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Body == nil || resp.Body.AccountId == nil {
+		return common.NewError(common.Upstream, "bad response for get caller identity")
+	}
+	accountId := utils.SafeString(resp.Body.AccountId)
+	regions, err := common.Regions(invoker, params.Credential, params.Args["region"].(string))
+	if err != nil {
+		return err
+	}
+	var probes []interface{}
+	for _, name := range names {
+		probe, ok := ProbeMap[name]
+		if !ok {
+			continue
+		}
+		probeRegions := regions
+		if !probe.Regional {
+			probeRegions = []string{""}
+		}
+		for _, region := range probeRegions {
+			result := &PermissionProbe{
+				Resource: name,
+				Product: probe.Product,
+				Action: probe.Action,
+				Region: region,
+				Status: ProbeAllowed,
+			}
+			err = invoker.Invoke(probe.Product, region, probe.Action, func() error {
+				return probe.Call(invoker, params.Credential, region)
+			})
+			if err != nil {
+				code := common.ErrorCodeOf(err)
+				switch code {
+				case common.Forbidden:
+					result.Status = ProbeDenied
+				case common.RegionUnavailable:
+					result.Status = ProbeRegionUnavailable
+				default:
+					result.Status = ProbeFailed
+				}
+				result.Code = string(code)
+				result.Message = err.Error()
+			}
+			probes = append(probes, result)
+		}
+	}
+	replay.Query = map[string]interface{} {
+		"CloudType": common.CloudType,
+		"AccountId": accountId,
+		"AccountMatched": params.Credential.AccountId == "" || params.Credential.AccountId == accountId,
+		"Arn": utils.SafeString(resp.Body.Arn),
+		"IdentityType": utils.SafeString(resp.Body.IdentityType),
+	}
+	replay.Result = probes
+	return nil
+}
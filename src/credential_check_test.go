@@ -0,0 +1,118 @@
+package src_test
+
+import (
+	"github.com/hahaps/common-provider/src/input"
+	"github.com/hahaps/input-provider-aliyun/src"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"strings"
+	"testing"
+)
+
+func TestCredentialCheck(t *testing.T) {
+	fakeapi.Start(t)
+	replay, err := fakeapi.Call("CredentialCheck", map[string]interface{}{
+		"region": fakeapi.Region,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	probed := map[string]bool{}
+	for _, record := range replay.Result {
+		probe := record.(*src.PermissionProbe)
+		probed[probe.Resource] = true
+		if probe.Status != src.ProbeAllowed {
+			t.Errorf("unexpected probe %+v", probe)
+		}
+	}
+	// Every resource calling the API has a probe
+	for _, name := range src.ResourceNames() {
+		if !probed[name] && name != "CredentialCheck" && name != "Schema" {
+			t.Errorf("resource %v not probed", name)
+		}
+	}
+	if replay.Query["AccountId"] != fakeapi.AccountId || replay.Query["AccountMatched"] != true {
+		t.Errorf("unexpected query %v", replay.Query)
+	}
+}
+
+// probes calls CredentialCheck of resources and returns its probes by
+// resource.
+func probes(t *testing.T, resources ...interface{}) map[string]*src.PermissionProbe {
+	t.Helper()
+	replay, err := fakeapi.Call("CredentialCheck", map[string]interface{}{
+		"region": fakeapi.Region,
+		"resources": resources,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := map[string]*src.PermissionProbe{}
+	for _, record := range replay.Result {
+		probe := record.(*src.PermissionProbe)
+		result[probe.Resource] = probe
+	}
+	if len(result) != len(resources) {
+		t.Errorf("got probes %v, want one of each of %v", result, resources)
+	}
+	return result
+}
+
+func TestCredentialCheckDenied(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Fail("DescribeInstances", 1, 403, "Forbidden.RAM", "User not authorized to operate on the specified resource.")
+	result := probes(t, "Server", "Disk")
+	denied := result["Server"]
+	if denied.Status != src.ProbeDenied || denied.Code != string(common.Forbidden) || denied.Action != "DescribeInstances" ||
+		denied.Region != fakeapi.Region || !strings.Contains(denied.Message, "Forbidden.RAM") {
+		t.Errorf("unexpected probe %+v", denied)
+	}
+	if result["Disk"].Status != src.ProbeAllowed || result["Disk"].Code != "" {
+		t.Errorf("unexpected probe %+v", result["Disk"])
+	}
+}
+
+func TestCredentialCheckRegionUnavailable(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Fail("DescribeVpcs", 1, 400, "UnsupportedRegion", "The region is not supported.")
+	probe := probes(t, "Network")["Network"]
+	if probe.Status != src.ProbeRegionUnavailable || probe.Code != string(common.RegionUnavailable) || probe.Product != common.ProductVpc {
+		t.Errorf("unexpected probe %+v", probe)
+	}
+}
+
+func TestCredentialCheckFailed(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Fail("ListResourceGroups", 1, 400, "InvalidParameter", "The parameter is invalid.")
+	probe := probes(t, "ResourceGroup")["ResourceGroup"]
+	if probe.Status != src.ProbeFailed || probe.Code != string(common.InvalidParam) || probe.Region != "" {
+		t.Errorf("unexpected probe %+v", probe)
+	}
+}
+
+func TestCredentialCheckAccount(t *testing.T) {
+	fakeapi.Start(t)
+	for accountId, matched := range map[string]bool{"": true, fakeapi.AccountId: true, "2": false} {
+		credential := fakeapi.Credential
+		credential.AccountId = accountId
+		replay := &input.Replay{}
+		params := input.Params{
+			Credential: credential,
+			Args: map[string]interface{}{"region": fakeapi.Region, "resources": []interface{}{"Server"}},
+		}
+		if err := src.ResourceMap["CredentialCheck"].Call(params, replay); err != nil {
+			t.Fatal(err)
+		}
+		if replay.Query["AccountId"] != fakeapi.AccountId || replay.Query["AccountMatched"] != matched {
+			t.Errorf("credential of account %q got query %v", accountId, replay.Query)
+		}
+	}
+}
+
+func TestCredentialCheckUnknown(t *testing.T) {
+	fakeapi.Start(t)
+	args := map[string]interface{}{"region": fakeapi.Region, "resources": []interface{}{"Unknown"}}
+	if _, err := fakeapi.Call("CredentialCheck", args); common.ErrorCodeOf(err) != common.InvalidParam {
+		t.Errorf("got %v, want an InvalidParam error", err)
+	}
+}
@@ -0,0 +1,442 @@
+package fakeapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// Region is the region holding the default fixtures.
+const Region = "cn-hangzhou"
+
+// AccountId is the account of the default fixtures.
+const AccountId = "1"
+
+// ResourceGroupId is the resource group of the default fixtures.
+const ResourceGroupId = "rg-fake0001"
+
+// MetricMetas are the metrics of each namespace listed by
+// DescribeMetricMetaList.
+var MetricMetas = map[string][]map[string]interface{}{
+	"acs_ecs_dashboard": []map[string]interface{}{
+		metricMeta("acs_ecs_dashboard", "CPUUtilization", "%", "Average,Minimum,Maximum", "userId,instanceId"),
+		metricMeta("acs_ecs_dashboard", "InternetInRate", "bit/s", "Average,Minimum,Maximum", "userId,instanceId"),
+		metricMeta("acs_ecs_dashboard", "InternetOutRate", "bit/s", "Average,Minimum,Maximum", "userId,instanceId"),
+		metricMeta("acs_ecs_dashboard", "VPC_PublicIP_InternetInRate", "bit/s", "Average,Minimum,Maximum", "userId,instanceId,ip"),
+		metricMeta("acs_ecs_dashboard", "memory_usedutilization", "%", "Average,Minimum,Maximum", "userId,instanceId"),
+		metricMeta("acs_ecs_dashboard", "load_5m", "Count", "Average,Minimum,Maximum", "userId,instanceId"),
+		metricMeta("acs_ecs_dashboard", "diskusage_utilization", "%", "Average,Minimum,Maximum", "userId,instanceId,device,mountpoint"),
+		metricMeta("acs_ecs_dashboard", "fs_inodeutilization", "%", "Average,Minimum,Maximum", "userId,instanceId,device,mountpoint"),
+		metricMeta("acs_ecs_dashboard", "net_tcpconnection", "Count", "Average,Minimum,Maximum", "userId,instanceId,state"),
+	},
+	"acs_rds_dashboard": []map[string]interface{}{
+		metricMeta("acs_rds_dashboard", "CpuUsage", "%", "Average,Minimum,Maximum", "userId,instanceId"),
+		metricMeta("acs_rds_dashboard", "MemoryUsage", "%", "Average,Minimum,Maximum", "userId,instanceId"),
+	},
+	"acs_slb_dashboard": []map[string]interface{}{
+		metricMeta("acs_slb_dashboard", "PacketRX", "Count/Second", "Average,Minimum,Maximum", "userId,instanceId,port,vip"),
+	},
+	"acs_vpc_eip": []map[string]interface{}{
+		metricMeta("acs_vpc_eip", "net_rx.rate", "bit/s", "Value", "userId,instanceId"),
+		metricMeta("acs_vpc_eip", "net_tx.rate", "bit/s", "Value", "userId,instanceId"),
+	},
+}
+
+// NamespaceInstances are the instances of the metric queries without
+// Dimensions, by namespace.
+var NamespaceInstances = map[string][]string{
+	"acs_rds_dashboard": []string{"rm-fake0001"},
+	"acs_slb_dashboard": []string{"lb-fake0001"},
+}
+
+// MetricSeries are the extra dimensions of each series of an instance, for
+// the metrics having several
+var MetricSeries = map[string][]map[string]interface{}{
+	"VPC_PublicIP_InternetInRate": []map[string]interface{}{
+		{"ip": "47.0.0.1"},
+	},
+	"PacketRX": []map[string]interface{}{
+		{"port": "80", "vip": "47.0.0.20"},
+		{"port": "443", "vip": "47.0.0.20"},
+	},
+	"diskusage_utilization": []map[string]interface{}{
+		{"device": "/dev/vda1", "mountpoint": "/"},
+		{"device": "/dev/vdb1", "mountpoint": "/data"},
+	},
+	"fs_inodeutilization": []map[string]interface{}{
+		{"device": "/dev/vda1", "mountpoint": "/"},
+		{"device": "/dev/vdb1", "mountpoint": "/data"},
+	},
+	"net_tcpconnection": []map[string]interface{}{
+		{"state": "ESTABLISHED"},
+		{"state": "TIME_WAIT"},
+		{"state": "TOTAL"},
+	},
+}
+
+var instanceIdPattern = regexp.MustCompile(`"?instanceId"?\s*:\s*"?([\w.-]+)`)
+
+// LoadDefaults serves one resource of every kind listed by the provider,
+// in Region.
+func (server *Server)LoadDefaults() {
+	server.Handle("DescribeRegions", Pages{
+		List: "Regions.Region",
+		Items: Items(map[string]interface{}{"RegionId": Region, "LocalName": "China (Hangzhou)"}),
+	}.Handler())
+	server.Handle("DescribeInstances", Pages{
+		List: "Instances.Instance",
+		Items: Items(map[string]interface{}{
+			"InstanceId": "i-fake0001",
+			"InstanceName": "fake-server",
+			"ResourceGroupId": ResourceGroupId,
+			"CreationTime": "2021-01-01T00:00Z",
+			"Status": "Running",
+			"InstanceChargeType": "PostPaid",
+			"InstanceType": "ecs.g6.large",
+			"Cpu": 2,
+			"Memory": 8192,
+			"CpuOptions": map[string]interface{}{"CoreCount": 1, "ThreadsPerCore": 2},
+			"ImageId": "ubuntu_20_04_x64",
+			"OSType": "linux",
+			"ZoneId": Region + "-h",
+			"VpcAttributes": map[string]interface{}{
+				"VpcId": "vpc-fake0001",
+				"VSwitchId": "vsw-fake0001",
+				"PrivateIpAddress": map[string]interface{}{"IpAddress": []string{"172.16.0.10"}},
+			},
+			"EipAddress": map[string]interface{}{"AllocationId": "eip-fake0001", "IpAddress": "47.0.0.10"},
+			"SecurityGroupIds": map[string]interface{}{"SecurityGroupId": []string{"sg-fake0001"}},
+			"NetworkInterfaces": map[string]interface{}{"NetworkInterface": []interface{}{}},
+			"Tags": map[string]interface{}{"Tag": []interface{}{
+				map[string]interface{}{"TagKey": "env", "TagValue": "test"},
+			}},
+		}),
+	}.Handler())
+	server.Handle("DescribeImages", Pages{
+		List: "Images.Image",
+		Items: Items(map[string]interface{}{
+			"ImageId": "ubuntu_20_04_x64",
+			"ImageName": "ubuntu_20_04_x64",
+			"ResourceGroupId": ResourceGroupId,
+			"CreationTime": "2021-01-01T00:00Z",
+			"Status": "Available",
+			"OSType": "linux",
+			"Size": 20,
+			"Tags": map[string]interface{}{"Tag": []interface{}{}},
+		}),
+	}.Handler())
+	server.Handle("DescribeDisks", Pages{
+		List: "Disks.Disk",
+		Items: Items(map[string]interface{}{
+			"DiskId": "d-fake0001",
+			"DiskName": "fake-disk",
+			"ResourceGroupId": ResourceGroupId,
+			"CreationTime": "2021-01-01T00:00Z",
+			"Status": "In_use",
+			"Type": "system",
+			"Category": "cloud_essd",
+			"Size": 40,
+			"InstanceId": "i-fake0001",
+			"Attachments": map[string]interface{}{"Attachment": []interface{}{
+				map[string]interface{}{"InstanceId": "i-fake0001", "Device": "/dev/xvda",
+					"AttachedTime": "2021-01-01T00:00Z"},
+			}},
+			"Tags": map[string]interface{}{"Tag": []interface{}{}},
+		}),
+	}.Handler())
+	server.Handle("DescribeVpcs", Pages{
+		List: "Vpcs.Vpc",
+		Items: Items(map[string]interface{}{
+			"VpcId": "vpc-fake0001",
+			"VpcName": "fake-vpc",
+			"ResourceGroupId": ResourceGroupId,
+			"CreationTime": "2021-01-01T00:00Z",
+			"Status": "Available",
+			"CidrBlock": "172.16.0.0/12",
+			"Tags": map[string]interface{}{"Tag": []interface{}{
+				map[string]interface{}{"Key": "env", "Value": "test"},
+			}},
+		}),
+	}.Handler())
+	server.Handle("DescribeVSwitches", Pages{
+		List: "VSwitches.VSwitch",
+		Items: Items(map[string]interface{}{
+			"VSwitchId": "vsw-fake0001",
+			"VSwitchName": "fake-vswitch",
+			"ResourceGroupId": ResourceGroupId,
+			"VpcId": "vpc-fake0001",
+			"CreationTime": "2021-01-01T00:00Z",
+			"Status": "Available",
+			"CidrBlock": "172.16.0.0/24",
+			"ZoneId": Region + "-h",
+			"Tags": map[string]interface{}{"Tag": []interface{}{
+				map[string]interface{}{"Key": "env", "Value": "test"},
+			}},
+		}),
+	}.Handler())
+	server.Handle("DescribeNetworkInterfaces", Pages{
+		List: "NetworkInterfaceSets.NetworkInterfaceSet",
+		Items: Items(map[string]interface{}{
+			"NetworkInterfaceId": "eni-fake0001",
+			"NetworkInterfaceName": "fake-nic",
+			"ResourceGroupId": ResourceGroupId,
+			"InstanceId": "i-fake0001",
+			"VpcId": "vpc-fake0001",
+			"VSwitchId": "vsw-fake0001",
+			"PrivateIpAddress": "172.16.0.10",
+			"MacAddress": "00:16:3e:00:00:01",
+			"Status": "InUse",
+			"Type": "Primary",
+			"CreationTime": "2021-01-01T00:00Z",
+			"SecurityGroupIds": map[string]interface{}{"SecurityGroupId": []string{"sg-fake0001"}},
+			"Tags": map[string]interface{}{"Tag": []interface{}{}},
+		}),
+	}.Handler())
+	server.Handle("DescribeSecurityGroups", Pages{
+		List: "SecurityGroups.SecurityGroup",
+		Items: Items(map[string]interface{}{
+			"SecurityGroupId": "sg-fake0001",
+			"SecurityGroupName": "fake-sg",
+			"ResourceGroupId": ResourceGroupId,
+			"VpcId": "vpc-fake0001",
+			"CreationTime": "2021-01-01T00:00Z",
+			"Tags": map[string]interface{}{"Tag": []interface{}{}},
+		}),
+	}.Handler())
+	server.Handle("DescribeEipAddresses", Pages{
+		List: "EipAddresses.EipAddress",
+		Items: Items(map[string]interface{}{
+			"AllocationId": "eip-fake0001",
+			"Name": "fake-eip",
+			"ResourceGroupId": ResourceGroupId,
+			"IpAddress": "47.0.0.10",
+			"Status": "InUse",
+			"InstanceId": "i-fake0001",
+			"InstanceType": "EcsInstance",
+			"Bandwidth": "5",
+			"ChargeType": "PostPaid",
+			"AllocationTime": "2021-01-01T00:00Z",
+			"Tags": map[string]interface{}{"Tag": []interface{}{
+				map[string]interface{}{"Key": "env", "Value": "test"},
+			}},
+		}),
+	}.Handler())
+	server.Handle("DescribeInstanceBill", Pages{
+		List: "Data.Items",
+		Counters: "Data",
+		Items: Items(map[string]interface{}{
+			"InstanceID": "i-fake0001",
+			"ProductCode": "ecs",
+			"ProductType": "",
+			"Region": "China (Hangzhou)",
+			"BillingDate": "2021-01-01",
+			"PretaxAmount": 1.5,
+			"Currency": "CNY",
+		}),
+		Extra: map[string]interface{}{
+			"Success": true,
+			"Code": "Success",
+			"Message": "Successful!",
+			"Data": map[string]interface{}{"BillingCycle": "2021-01"},
+		},
+	}.Handler())
+	server.Handle("ListResourceGroups", Pages{
+		List: "ResourceGroups.ResourceGroup",
+		Items: Items(map[string]interface{}{
+			"Id": ResourceGroupId,
+			"Name": "fake-group",
+			"DisplayName": "Fake group",
+			"Status": "OK",
+			"AccountId": AccountId,
+			"CreateDate": "2021-01-01T00:00:00+08:00",
+		}),
+	}.Handler())
+	server.Handle("DescribeMetricLast", MetricLast)
+	server.Handle("DescribeMetricList", MetricList)
+	server.Handle("DescribeMetricMetaList", MetricMetaList)
+	server.Fixture("GetCallerIdentity", map[string]interface{}{
+		"AccountId": AccountId,
+		"Arn": "acs:ram::" + AccountId + ":user/fake",
+		"IdentityType": "RAMUser",
+		"PrincipalId": "200000000000000001",
+		"UserId": "200000000000000001",
+	})
+	server.Handle("AssumeRole", AssumeRole)
+}
+
+// AssumeRole answers AssumeRole with a token of the RoleArn of the request,
+// expiring after its DurationSeconds.
+func AssumeRole(params url.Values) (int, interface{}) {
+	duration, err := strconv.Atoi(params.Get("DurationSeconds"))
+	if err != nil || duration <= 0 {
+		duration = 3600
+	}
+	arn := params.Get("RoleArn")
+	return http.StatusOK, map[string]interface{}{
+		"Credentials": map[string]interface{}{
+			"AccessKeyId": "STS.fake-" + params.Get("RoleSessionName"),
+			"AccessKeySecret": "fake-secret",
+			"SecurityToken": "fake-token-" + arn,
+			"Expiration": time.Now().Add(time.Duration(duration) * time.Second).UTC().Format(time.RFC3339),
+		},
+		"AssumedRoleUser": map[string]interface{}{
+			"Arn": arn + "/" + params.Get("RoleSessionName"),
+			"AssumedRoleId": "300000000000000001:" + params.Get("RoleSessionName"),
+		},
+	}
+}
+
+// instanceIds returns the instances named in the Dimensions of a metric
+// query, or the NamespaceInstances of its Namespace without Dimensions.
+func instanceIds(params url.Values) []string {
+	if params.Get("Dimensions") == "" {
+		return NamespaceInstances[params.Get("Namespace")]
+	}
+	var ids []string
+	for _, match := range instanceIdPattern.FindAllStringSubmatch(params.Get("Dimensions"), -1) {
+		ids = append(ids, match[1])
+	}
+	return ids
+}
+
+// datapointSeries returns a datapoint of the instance at timestamp for each
+// of the MetricSeries of the metric.
+func datapointSeries(metricName, instanceId string, timestamp int64) []map[string]interface{} {
+	series, ok := MetricSeries[metricName]
+	if !ok {
+		series = []map[string]interface{}{{}}
+	}
+	var datapoints []map[string]interface{}
+	for _, dimensions := range series {
+		datapoint := map[string]interface{}{
+			"instanceId": instanceId,
+			"timestamp": float64(timestamp),
+			"Average": 1.0,
+			"Minimum": 0.5,
+			"Maximum": 1.5,
+			"Value": 1.0,
+			"Sum": 2.0,
+		}
+		for dimension, value := range dimensions {
+			datapoint[dimension] = value
+		}
+		datapoints = append(datapoints, datapoint)
+	}
+	return datapoints
+}
+
+// MetricLast answers DescribeMetricLast with one datapoint per instance
+// named in the Dimensions of the request and series of the metric, Length
+// datapoints per page.
+func MetricLast(params url.Values) (int, interface{}) {
+	var datapoints []map[string]interface{}
+	for _, instanceId := range instanceIds(params) {
+		datapoints = append(datapoints, datapointSeries(params.Get("MetricName"), instanceId, time.Now().Unix() * 1000)...)
+	}
+	return http.StatusOK, metricPage(params, datapoints)
+}
+
+// MetricList answers DescribeMetricList with a datapoint per Period between
+// StartTime and EndTime for each instance named in the Dimensions of the
+// request and series of the metric, Length datapoints per page.
+func MetricList(params url.Values) (int, interface{}) {
+	start, _ := strconv.ParseInt(params.Get("StartTime"), 10, 64)
+	end, _ := strconv.ParseInt(params.Get("EndTime"), 10, 64)
+	period, err := strconv.ParseInt(params.Get("Period"), 10, 64)
+	if err != nil || period <= 0 {
+		period = 60
+	}
+	var datapoints []map[string]interface{}
+	for _, instanceId := range instanceIds(params) {
+		for timestamp := start; timestamp < end; timestamp += period * 1000 {
+			datapoints = append(datapoints, datapointSeries(params.Get("MetricName"), instanceId, timestamp)...)
+		}
+	}
+	return http.StatusOK, metricPage(params, datapoints)
+}
+
+// metricPage returns the body of the page of datapoints at the NextToken
+// of a metric query, Length datapoints, 1000 by default.
+func metricPage(params url.Values, datapoints []map[string]interface{}) map[string]interface{} {
+	length, err := strconv.Atoi(params.Get("Length"))
+	if err != nil || length <= 0 {
+		length = 1000
+	}
+	offset, _ := strconv.Atoi(params.Get("NextToken"))
+	body := map[string]interface{}{
+		"Success": true,
+		"Code": "200",
+		"Period": params.Get("Period"),
+	}
+	if offset > len(datapoints) {
+		offset = len(datapoints)
+	}
+	if offset + length < len(datapoints) {
+		body["NextToken"] = strconv.Itoa(offset + length)
+		datapoints = datapoints[offset:offset + length]
+	} else {
+		datapoints = datapoints[offset:]
+	}
+	if datapoints == nil {
+		datapoints = []map[string]interface{}{}
+	}
+	content, _ := json.Marshal(datapoints)
+	body["Datapoints"] = string(content)
+	return body
+}
+
+func metricMeta(namespace, metricName, unit, statistics, dimensions string) map[string]interface{} {
+	return map[string]interface{}{
+		"Namespace": namespace,
+		"MetricName": metricName,
+		"Description": metricName,
+		"Unit": unit,
+		"Statistics": statistics,
+		"Dimensions": dimensions,
+		"Periods": "60,300",
+	}
+}
+
+// MetricMetaList answers DescribeMetricMetaList with the MetricMetas of the
+// Namespace of the request, by PageNumber and PageSize. CMS returns its
+// TotalCount as a string.
+func MetricMetaList(params url.Values) (int, interface{}) {
+	metas := MetricMetas[params.Get("Namespace")]
+	page, err := strconv.Atoi(params.Get("PageNumber"))
+	if err != nil || page <= 0 {
+		page = 1
+	}
+	size, err := strconv.Atoi(params.Get("PageSize"))
+	if err != nil || size <= 0 {
+		size = 30
+	}
+	start, end := (page - 1) * size, page * size
+	if start > len(metas) {
+		start = len(metas)
+	}
+	if end > len(metas) {
+		end = len(metas)
+	}
+	resources := []map[string]interface{}{}
+	resources = append(resources, metas[start:end]...)
+	return http.StatusOK, map[string]interface{}{
+		"Success": true,
+		"Code": "200",
+		"TotalCount": strconv.Itoa(len(metas)),
+		"Resources": map[string]interface{}{
+			"Resource": resources,
+		},
+	}
+}
+
+// Items turns objects into the Items of Pages.
+func Items(values ...map[string]interface{}) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, value := range values {
+		result[i] = value
+	}
+	return result
+}
@@ -0,0 +1,156 @@
+package fakeapi
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Pages serves Items page by page, following PageNumber and PageSize, or
+// NextToken and MaxResults when the request carries MaxResults. Items are
+// filtered by the Tag.N.Key, Tag.N.Value and ResourceGroupId of the request.
+type Pages struct {
+	// Dotted path of the list in the response, e.g. "Instances.Instance".
+	List string
+	// Dotted path of the object holding TotalCount and NextToken, empty
+	// for the response root.
+	Counters string
+	Items []interface{}
+	// Extra fields merged into the response root.
+	Extra map[string]interface{}
+}
+
+func (pages Pages)Handler() Handler {
+	return func(params url.Values) (int, interface{}) {
+		body := map[string]interface{}{}
+		for key, value := range pages.Extra {
+			body[key] = copyBody(value)
+		}
+		counters := lookup(body, pages.Counters)
+		items := filterTags(pages.Items, params)
+		if groupId := params.Get("ResourceGroupId"); groupId != "" {
+			items = filterField(items, "ResourceGroupId", groupId)
+		}
+		total := len(items)
+		start, size := 0, 10
+		if maxResults := params.Get("MaxResults"); maxResults != "" {
+			size = atoi(maxResults, size)
+			start = atoi(params.Get("NextToken"), 0)
+		} else {
+			size = atoi(params.Get("PageSize"), size)
+			number := atoi(params.Get("PageNumber"), 1)
+			start = (number - 1) * size
+			counters["PageNumber"] = number
+		}
+		if size < 1 {
+			return http.StatusBadRequest, ErrorBody("InvalidParameter", "page size should be positive")
+		}
+		if start < 0 || start > total {
+			start = total
+		}
+		end := start + size
+		if end > total {
+			end = total
+		}
+		counters["TotalCount"] = total
+		counters["PageSize"] = size
+		if params.Get("MaxResults") != "" {
+			counters["MaxResults"] = size
+			counters["NextToken"] = ""
+			if end < total {
+				counters["NextToken"] = strconv.Itoa(end)
+			}
+		}
+		path := strings.Split(pages.List, ".")
+		parent := lookup(body, strings.Join(path[:len(path) - 1], "."))
+		parent[path[len(path) - 1]] = copyBody(items[start:end])
+		return http.StatusOK, body
+	}
+}
+
+func filterTags(items []interface{}, params url.Values) []interface{} {
+	filters := map[string]string{}
+	for n := 1; ; n++ {
+		key := params.Get(fmt.Sprintf("Tag.%v.Key", n))
+		if key == "" {
+			key = params.Get(fmt.Sprintf("Tag.%v.key", n))
+		}
+		if key == "" {
+			break
+		}
+		filters[key] = params.Get(fmt.Sprintf("Tag.%v.Value", n))
+	}
+	if len(filters) == 0 {
+		return items
+	}
+	result := []interface{}{}
+	for _, item := range items {
+		tags := itemTags(item)
+		matched := true
+		for key, value := range filters {
+			if actual, ok := tags[key]; !ok || (value != "" && actual != value) {
+				matched = false
+			}
+		}
+		if matched {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func filterField(items []interface{}, field, value string) []interface{} {
+	result := []interface{}{}
+	for _, item := range items {
+		obj, _ := item.(map[string]interface{})
+		if actual, _ := obj[field].(string); actual == value {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// itemTags reads the Tags.Tag list of an item, in the TagKey and TagValue
+// form of ECS or the Key and Value form of VPC.
+func itemTags(item interface{}) map[string]string {
+	tags := map[string]string{}
+	obj, _ := item.(map[string]interface{})
+	wrapper, _ := obj["Tags"].(map[string]interface{})
+	list, _ := wrapper["Tag"].([]interface{})
+	for _, entry := range list {
+		tag, _ := entry.(map[string]interface{})
+		for _, names := range [][2]string{{"TagKey", "TagValue"}, {"Key", "Value"}} {
+			if key, ok := tag[names[0]].(string); ok {
+				tags[key], _ = tag[names[1]].(string)
+			}
+		}
+	}
+	return tags
+}
+
+// lookup returns the object at the dotted path of body, creating the
+// missing levels.
+func lookup(body map[string]interface{}, path string) map[string]interface{} {
+	if path == "" {
+		return body
+	}
+	for _, key := range strings.Split(path, ".") {
+		next, ok := body[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			body[key] = next
+		}
+		body = next
+	}
+	return body
+}
+
+func atoi(value string, fallback int) int {
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback
+	}
+	return number
+}
@@ -0,0 +1,57 @@
+package fakeapi
+
+import (
+	"github.com/hahaps/common-provider/src/input"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/state"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Credential is a credential of AccountId accepted by the server.
+var Credential = input.Credential{
+	SecretId: "fake-id",
+	SecretKey: "fake-key",
+	AccountId: AccountId,
+}
+
+// Sandbox points the cache and state files of the provider to a temp dir,
+// turns the invocation log off and clears the metrics and cassette env vars,
+// so that tests never touch the files of the user. It returns the restore
+// of the environment.
+func Sandbox() (func(), error) {
+	dir, err := ioutil.TempDir("", "fakeapi")
+	if err != nil {
+		return nil, err
+	}
+	envs := map[string]string{
+		common.EnvRateLimitStateFile: filepath.Join(dir, "ratelimit.json"),
+		common.EnvRoleCacheFile: filepath.Join(dir, "roles.json"),
+		common.EnvMetricCatalogFile: filepath.Join(dir, "metric_catalog.json"),
+		state.EnvStateFile: filepath.Join(dir, "state.json"),
+		common.EnvLog: common.LogOff,
+		common.EnvMetricsAddr: "",
+		common.EnvMetricsFile: "",
+		common.EnvCassetteMode: "",
+		common.EnvCassette: "",
+	}
+	previous := map[string]*string{}
+	for env, value := range envs {
+		previous[env] = nil
+		if current, ok := os.LookupEnv(env); ok {
+			previous[env] = &current
+		}
+		os.Setenv(env, value)
+	}
+	return func() {
+		for env, value := range previous {
+			if value != nil {
+				os.Setenv(env, *value)
+			} else {
+				os.Unsetenv(env)
+			}
+		}
+		os.RemoveAll(dir)
+	}, nil
+}
@@ -0,0 +1,157 @@
+package fakeapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+)
+
+// Handler returns the HTTP status and JSON body answering the params of a
+// request, query and form merged.
+type Handler func(params url.Values) (int, interface{})
+
+// Server is an httptest server speaking the RPC style OpenAPI protocol of
+// ECS, VPC, CMS, BSS and STS. Responses are served by action.
+type Server struct {
+	*httptest.Server
+	lock sync.Mutex
+	handlers map[string]Handler
+	failures map[string][]Handler
+	requests map[string][]url.Values
+	installed bool
+}
+
+func NewServer() *Server {
+	server := &Server{
+		handlers: map[string]Handler{},
+		failures: map[string][]Handler{},
+		requests: map[string][]url.Values{},
+	}
+	server.Server = httptest.NewServer(http.HandlerFunc(server.serve))
+	return server
+}
+
+func (server *Server)serve(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	action := r.Form.Get("Action")
+	server.lock.Lock()
+	server.requests[action] = append(server.requests[action], r.Form)
+	handler, ok := server.handlers[action]
+	if failures := server.failures[action]; len(failures) > 0 {
+		handler, ok = failures[0], true
+		server.failures[action] = failures[1:]
+	}
+	server.lock.Unlock()
+	status, body := http.StatusNotFound, interface{}(ErrorBody("InvalidAction.NotFound",
+		"Specified api is not found, please check your url and method."))
+	if ok {
+		status, body = handler(r.Form)
+	}
+	if obj, isMap := body.(map[string]interface{}); isMap {
+		if _, hasId := obj["RequestId"]; !hasId {
+			obj["RequestId"] = fmt.Sprintf("fake-%v-%v", action, len(server.Requests(action)))
+		}
+	}
+	content, err := json.Marshal(body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(content)
+}
+
+// Handle serves action with handler.
+func (server *Server)Handle(action string, handler Handler) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	server.handlers[action] = handler
+}
+
+// Fixture serves action with a static body, a JSON string or any value
+// marshalled to JSON.
+func (server *Server)Fixture(action string, body interface{}) {
+	if raw, ok := body.(string); ok {
+		obj := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(raw), &obj); err != nil {
+			panic(fmt.Sprintf("bad fixture of %v: %v", action, err))
+		}
+		body = obj
+	}
+	server.Handle(action, func(url.Values) (int, interface{}) {
+		return http.StatusOK, copyBody(body)
+	})
+}
+
+// Fail makes the next times requests of action fail with an API error,
+// before the handler of action is used again.
+func (server *Server)Fail(action string, times int, status int, code, message string) {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	for i := 0; i < times; i++ {
+		server.failures[action] = append(server.failures[action], func(url.Values) (int, interface{}) {
+			return status, ErrorBody(code, message)
+		})
+	}
+}
+
+// Requests returns the params of every request received for action.
+func (server *Server)Requests(action string) []url.Values {
+	server.lock.Lock()
+	defer server.lock.Unlock()
+	return append([]url.Values(nil), server.requests[action]...)
+}
+
+// Install overrides the endpoint of every product with the server, and
+// drops the pooled clients.
+func (server *Server)Install() {
+	for product := range common.EndpointRules {
+		common.SetEndpoint(product, "", server.URL)
+	}
+	common.ResetClients()
+	server.installed = true
+}
+
+// Uninstall restores the endpoints changed by Install.
+func (server *Server)Uninstall() {
+	if !server.installed {
+		return
+	}
+	for product := range common.EndpointRules {
+		common.SetEndpoint(product, "", "")
+	}
+	common.ResetClients()
+	server.installed = false
+}
+
+// Close uninstalls and shuts down the server.
+func (server *Server)Close() {
+	server.Uninstall()
+	server.Server.Close()
+}
+
+// ErrorBody is the body of an API error.
+func ErrorBody(code, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"Code": code,
+		"Message": message,
+	}
+}
+
+func copyBody(body interface{}) interface{} {
+	content, err := json.Marshal(body)
+	if err != nil {
+		return body
+	}
+	var copied interface{}
+	json.Unmarshal(content, &copied)
+	return copied
+}
@@ -0,0 +1,38 @@
+package fakeapi
+
+import (
+	"github.com/hahaps/common-provider/src/input"
+	"github.com/hahaps/input-provider-aliyun/src"
+	"os"
+	"testing"
+)
+
+// Main runs the tests of a package in a Sandbox, as its TestMain.
+func Main(m *testing.M) {
+	restore, err := Sandbox()
+	if err != nil {
+		panic(err)
+	}
+	code := m.Run()
+	restore()
+	os.Exit(code)
+}
+
+// Start serves the defaults for the test t, installed until it ends.
+func Start(t *testing.T) *Server {
+	server := NewServer()
+	server.LoadDefaults()
+	server.Install()
+	t.Cleanup(server.Close)
+	return server
+}
+
+// Call runs the resource of src.ResourceMap with args and the Credential.
+func Call(resource string, args map[string]interface{}) (*input.Replay, error) {
+	replay := &input.Replay{}
+	params := input.Params{
+		Credential: Credential,
+		Args: args,
+	}
+	return replay, src.ResourceMap[resource].Call(params, replay)
+}
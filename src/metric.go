@@ -0,0 +1,70 @@
+package src
+
+import (
+	"github.com/hahaps/common-provider/src/common/utils"
+	"github.com/hahaps/common-provider/src/input"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/compute"
+	"github.com/hahaps/input-provider-aliyun/src/monitor"
+	"github.com/hahaps/input-provider-aliyun/src/network"
+)
+
+var MetricSchemes = append([]utils.Scheme {
+	utils.Scheme{
+		Param: "namespace",
+		Required: true,
+		Type: utils.String,
+	},
+	utils.Scheme{
+		Param: "dimensions",
+		Required: false,
+		Type: utils.Slice,
+		Default: []interface{}{},
+	},
+	utils.Scheme{
+		Param: "inventory",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
+}, monitor.InventorySchemes(compute.ServerSchemes, network.FloatingIpSchemes)...)
+
+// Inventories accepted by the "inventory" param of Metric
+var InventoryMap = map[string]*monitor.Inventory {
+	"Server": compute.ServerInventory,
+	"FloatingIp": network.FloatingIpInventory,
+}
+
+// Metric collects the metrics of any CloudMonitor namespace, as
+// acs_rds_dashboard or acs_slb_dashboard. The instances are the
+// "dimensions" param, a list as [{"instanceId": "rm-1"}], or the records of
+// the "inventory" resource, a page per call. Without either, CMS returns
+// every instance of the namespace.
+type Metric struct {
+	input.Resource
+}
+
+func (Metric)Call(params input.Params, replay *input.Replay) error {
+	var err error
+	common.NormalizeRegion(params.Args)
+	params.Args, err = utils.CheckParam(params.Args, MetricSchemes)
+	if err != nil {
+		return common.ParamError(err)
+	}
+	namespace := params.Args["namespace"].(string)
+	collector := &monitor.Collector{
+		Namespace: namespace,
+		Builtin: MetricMaps[namespace],
+	}
+	if name := params.Args["inventory"].(string); name != "" {
+		inventory, ok := InventoryMap[name]
+		if !ok {
+			return common.NewError(common.InvalidParam, "bad inventory %v", name)
+		}
+		if len(params.Args["dimensions"].([]interface{})) > 0 {
+			return common.NewError(common.InvalidParam, "Param[dimensions] and Param[inventory] are exclusive")
+		}
+		collector.Inventory = inventory
+	}
+	return collector.Collect(params, replay)
+}
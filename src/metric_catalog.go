@@ -0,0 +1,61 @@
+package src
+
+import (
+	"github.com/hahaps/common-provider/src/common/utils"
+	"github.com/hahaps/common-provider/src/input"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/compute"
+	"github.com/hahaps/input-provider-aliyun/src/network"
+)
+
+var MetricCatalogSchemes = append([]utils.Scheme {
+	utils.Scheme{
+		Param: "namespace",
+		Required: true,
+		Type: utils.String,
+	},
+}, common.MetricCatalogSchemes...)
+
+// Built-in metric tables of the namespaces, used when the catalog cannot be
+// loaded from the API
+var MetricMaps = map[string]map[string]map[string]string {
+	compute.ServerMetricNamespace: compute.MetricMap,
+	network.FloatingIpMetricNamespace: network.MetricMap,
+}
+
+// MetricCatalog lists the metrics of the "namespace" param, with their
+// unit, statistics and dimensions, as a *common.MetricMeta per metric. The
+// "Source" key of replay.Query tells where the catalog was loaded from.
+type MetricCatalog struct {
+	input.Resource
+}
+
+func (MetricCatalog)Call(params input.Params, replay *input.Replay) error {
+	var err error
+	params.Args, err = utils.CheckParam(params.Args, MetricCatalogSchemes)
+	if err != nil {
+		return common.ParamError(err)
+	}
+	invoker, err := common.NewInvoker(params)
+	if err != nil {
+		return err
+	}
+	defer invoker.Close()
+	namespace := params.Args["namespace"].(string)
+	catalog, err := common.LoadMetricCatalog(invoker, params.Credential, namespace, MetricMaps[namespace], params.Args)
+	if err != nil {
+		return err
+	}
+	var metas []interface{}
+	for _, name := range catalog.Names() {
+		metas = append(metas, catalog.Metrics[name])
+	}
+	replay.Query = map[string]interface{} {
+		"CloudType": common.CloudType,
+		"AccountId": params.Credential.AccountId,
+		"Namespace": namespace,
+		"Source": catalog.Source,
+	}
+	replay.Result = metas
+	return nil
+}
@@ -0,0 +1,360 @@
+package monitor
+
+import (
+	"fmt"
+	cms20190101 "github.com/alibabacloud-go/cms-20190101/v2/client"
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hahaps/common-provider/src/common/utils"
+	"github.com/hahaps/common-provider/src/input"
+	"github.com/hahaps/common-provider/src/models"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Params of every metric resource
+var MetricSchemes = append([]utils.Scheme {
+	utils.Scheme{
+		Param: "region",
+		Required: true,
+		Type: utils.String,
+	},
+	utils.Scheme{
+		Param: "metric_names",
+		Required: true,
+		Type: utils.Slice,
+	},
+	utils.Scheme{
+		Param: "statistics",
+		Required: false,
+		Type: utils.Slice,
+		Default: []interface{}{},
+	},
+	utils.Scheme{
+		Param: "period",
+		Required: false,
+		Type: utils.Int,
+		Default: 60,
+	},
+	utils.Scheme{
+		Param: "concurrency",
+		Required: false,
+		Type: utils.Int,
+		Default: common.DefaultMetricConcurrency,
+	},
+	utils.Scheme{
+		Param: "start_time",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
+	utils.Scheme{
+		Param: "end_time",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
+}, common.MetricCatalogSchemes...)
+
+// InventorySchemes returns MetricSchemes followed by the schemes of the
+// inventory resources missing in them, e.g. limit, marker and tags, for a
+// Collector paging through these inventories.
+func InventorySchemes(inventories ...[]utils.Scheme) []utils.Scheme {
+	schemes := append([]utils.Scheme{}, MetricSchemes...)
+	for _, inventory := range inventories {
+		for _, scheme := range inventory {
+			found := false
+			for _, declared := range schemes {
+				if declared.Param == scheme.Param {
+					found = true
+				}
+			}
+			if !found {
+				schemes = append(schemes, scheme)
+			}
+		}
+	}
+	return schemes
+}
+
+// IndexKeys of the datapoints of a time window, the last datapoints being
+// indexed by the default "InstanceId, Name" of MetricModel.
+const RangedIndexKeys = "InstanceId, Name, MetricTime"
+
+// Inventory expands the records of an inventory resource into the
+// dimensions of their metrics.
+type Inventory struct {
+	Resource input.Resource
+	// Dimensions returns the dimensions of the metrics of a record, as
+	// {"instanceId": "i-1"}
+	Dimensions func(record interface{}) map[string]string
+	// Extra returns the info of a record added to the Extra of its metrics
+	Extra func(record interface{}) map[string]interface{}
+}
+
+// Collector collects the metrics of a namespace as MetricModel records.
+type Collector struct {
+	Namespace string
+	// Built-in metric table of the namespace, see common.BuiltinMetricCatalog
+	Builtin map[string]map[string]string
+	// Inventory queried a page per call for the dimensions. Without it, the
+	// dimensions are the "dimensions" arg, or none for every instance of
+	// the namespace.
+	Inventory *Inventory
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Dimensions reads the "dimensions" arg, a list of objects as
+// {"instanceId": "rm-1"}.
+func Dimensions(args map[string]interface{}) ([]map[string]string, error) {
+	items, _ := args["dimensions"].([]interface{})
+	var dimensions []map[string]string
+	for _, item := range items {
+		values, ok := item.(map[string]interface{})
+		if !ok || len(values) == 0 {
+			return nil, common.NewError(common.InvalidParam, "bad dimensions %v", item)
+		}
+		dimension := map[string]string{}
+		for key, value := range values {
+			dimension[key] = fmt.Sprint(value)
+		}
+		dimensions = append(dimensions, dimension)
+	}
+	return dimensions, nil
+}
+
+// Collect queries the "metric_names" of the namespace, with args checked
+// against MetricSchemes. The last datapoints of every metric and chunk of
+// dimensions are returned at once, a time window one DescribeMetricList page
+// per call, see common.MetricCursor.
+func (collector *Collector)Collect(params input.Params, replay *input.Replay) error {
+	invoker, err := common.NewInvoker(params)
+	if err != nil {
+		return err
+	}
+	defer invoker.Close()
+	catalog, err := common.LoadMetricCatalog(invoker, params.Credential, collector.Namespace, collector.Builtin, params.Args)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, mn := range params.Args["metric_names"].([]interface{}) {
+		metricName := strings.TrimSpace(fmt.Sprint(mn))
+		if _, ok := catalog.Lookup(metricName); !ok {
+			return common.NewError(common.InvalidParam, "bad metric %v", metricName)
+		}
+		names = append(names, metricName)
+	}
+	var statistics []string
+	statisticArgs, _ := params.Args["statistics"].([]interface{})
+	for _, st := range statisticArgs {
+		stat := strings.TrimSpace(fmt.Sprint(st))
+		for _, metricName := range names {
+			meta, _ := catalog.Lookup(metricName)
+			if !contains(meta.Statistics, stat) {
+				return common.NewError(common.InvalidParam, "bad statistic %v of metric %v", stat, metricName)
+			}
+		}
+		statistics = append(statistics, stat)
+	}
+	start, end, ranged, err := common.MetricWindow(params.Args)
+	if err != nil {
+		return err
+	}
+	var cursor common.MetricCursor
+	if ranged {
+		marker, _ := params.Args["marker"].(string)
+		cursor, err = common.ParseMetricCursor(marker)
+		if err != nil {
+			return err
+		}
+		if cursor.Metric >= len(names) {
+			return common.NewError(common.InvalidParam, "bad marker %v", marker)
+		}
+		params.Args["marker"] = cursor.Marker
+	}
+	var region string
+	var dimensions []map[string]string
+	var extras []map[string]interface{}
+	inventoryNext := ""
+	if collector.Inventory != nil {
+		err = collector.Inventory.Resource.Call(params, replay)
+		if err != nil {
+			return err
+		}
+		inventoryNext = replay.Next
+		if len(replay.Result) == 0 {
+			if ranged {
+				replay.Next = cursor.Next("", 0, 0, inventoryNext)
+			}
+			return nil
+		}
+		region = replay.Query["RegionId"].(string)
+		for _, record := range replay.Result {
+			dimensions = append(dimensions, collector.Inventory.Dimensions(record))
+			extras = append(extras, collector.Inventory.Extra(record))
+		}
+	} else {
+		regions, err := common.Regions(invoker, params.Credential, params.Args["region"].(string))
+		if err != nil {
+			return err
+		}
+		if len(regions) != 1 {
+			return common.NewError(common.InvalidParam, "Param[region] should be one region without an inventory")
+		}
+		region = regions[0]
+		dimensions, err = Dimensions(params.Args)
+		if err != nil {
+			return err
+		}
+	}
+	client, err := common.CmsClient(invoker, params.Credential, region)
+	if err != nil {
+		return err
+	}
+	defer common.ReleaseClient(client)
+	// Without dimensions, CMS returns every instance of the namespace
+	chunks := []string{""}
+	if len(dimensions) > 0 {
+		chunks = common.MetricDimensions(dimensions, common.MaxMetricDimensions)
+	}
+	period := strconv.Itoa(params.Args["period"].(int))
+	timestamp := time.Now().Unix()
+	query := map[string]interface{} {
+		"CloudType": common.CloudType,
+		"AccountId": params.Credential.AccountId,
+		"Index": strconv.FormatInt(timestamp, 10),
+	}
+	var queries []common.MetricQuery
+	var results [][]map[string]interface{}
+	nextToken := ""
+	if ranged {
+		// The chunk of the cursor is gone when the inventory page shrank
+		if cursor.Chunk < len(chunks) {
+			queries = []common.MetricQuery{{MetricName: names[cursor.Metric], Dimensions: chunks[cursor.Chunk]}}
+			request := &cms20190101.DescribeMetricListRequest{
+				Period: tea.String(period),
+				Namespace: tea.String(collector.Namespace),
+				MetricName: tea.String(queries[0].MetricName),
+				StartTime: tea.String(strconv.FormatInt(start, 10)),
+				EndTime: tea.String(strconv.FormatInt(end, 10)),
+				Length: tea.String(common.MetricListLength),
+			}
+			if queries[0].Dimensions != "" {
+				request.Dimensions = tea.String(queries[0].Dimensions)
+			}
+			if cursor.Token != "" {
+				request.NextToken = tea.String(cursor.Token)
+			}
+			dataPoints, token, err := common.DescribeMetricList(invoker, client, region, request)
+			if err != nil {
+				return err
+			}
+			results, nextToken = [][]map[string]interface{}{dataPoints}, token
+		}
+	} else {
+		queries = common.PlanMetricQueries(names, chunks)
+		concurrency := params.Args["concurrency"].(int)
+		if concurrency > len(queries) {
+			concurrency = len(queries)
+		}
+		clients := []*cms20190101.Client{client}
+		if concurrency > 1 {
+			workers, err := common.CmsClients(invoker, params.Credential, region, concurrency - 1)
+			if err != nil {
+				return err
+			}
+			for _, worker := range workers {
+				defer common.ReleaseClient(worker)
+			}
+			clients = append(clients, workers...)
+		}
+		results, err = common.RunMetricQueries(queries, len(clients), func(worker int, metricQuery common.MetricQuery) ([]map[string]interface{}, error) {
+			request := &cms20190101.DescribeMetricLastRequest{
+				Period: tea.String(period),
+				Namespace: tea.String(collector.Namespace),
+				MetricName: tea.String(metricQuery.MetricName),
+			}
+			if metricQuery.Dimensions != "" {
+				request.Dimensions = tea.String(metricQuery.Dimensions)
+			}
+			return common.DescribeMetricLast(invoker, clients[worker], region, request)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	var metrs []interface{}
+	for i, metricQuery := range queries {
+		meta, _ := catalog.Lookup(metricQuery.MetricName)
+		instanceDimension := meta.InstanceDimension()
+		infos := map[string]map[string]interface{}{}
+		for j, dimension := range dimensions {
+			if j < len(extras) {
+				infos[dimension[instanceDimension]] = extras[j]
+			}
+		}
+		stats := meta.Statistics
+		if len(statistics) > 0 {
+			stats = statistics
+		}
+		for _, b := range results[i] {
+			instanceId, _ := b[instanceDimension].(string)
+			if instanceId == "" {
+				return common.NewError(common.Upstream, "no %v in datapoint of metric %v", instanceDimension, meta.MetricName)
+			}
+			for _, stat := range stats {
+				metr := models.NewMetricModel()
+				metr.Deleted = 0
+				metr.CloudType = common.CloudType
+				metr.AccountId = params.Credential.AccountId
+				metr.InstanceId = instanceId
+				metr.Value = fmt.Sprint(b[stat])
+				metr.Unit = meta.Unit
+				metr.MetricTime, _ = b["timestamp"].(float64)
+				metr.Name = meta.SeriesName(stat, b)
+				metr.Extra = map[string]interface{}{
+					"Region": region,
+					"Namespace": collector.Namespace,
+				}
+				for key, value := range infos[instanceId] {
+					metr.Extra[key] = value
+				}
+				if dimensions := meta.DimensionValues(b); dimensions != nil {
+					metr.Extra["Dimensions"] = dimensions
+				}
+				if ranged {
+					// Every datapoint of a window is a record of its own
+					metr.IndexKeys = RangedIndexKeys
+				}
+				checked, key := metr.CheckRequired()
+				if !checked {
+					return common.NewError(common.Upstream, "Value[%v] should not be empty", key)
+				}
+				metr.SetIndex()
+				metrs = append(metrs, &metr)
+			}
+		}
+	}
+
+	if !utils.CheckQueryKeys(query, models.MetricModel{}) {
+		return common.NewError(common.Internal, "query key is not attribute of MetricModel")
+	}
+	// The last datapoints follow the pages of the inventory
+	replay.Next = inventoryNext
+	if ranged {
+		replay.Next = cursor.Next(nextToken, len(chunks), len(names), inventoryNext)
+	}
+	replay.Query = query
+	replay.Result = metrs
+	return nil
+}
@@ -0,0 +1,277 @@
+package monitor_test
+
+import (
+	"fmt"
+	"github.com/hahaps/common-provider/src/common/utils"
+	"github.com/hahaps/common-provider/src/input"
+	"github.com/hahaps/common-provider/src/models"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"github.com/hahaps/input-provider-aliyun/src/monitor"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	fakeapi.Main(m)
+}
+
+// collect runs a Collector of namespace without inventory
+func collect(namespace string, args map[string]interface{}) (*input.Replay, error) {
+	args, err := utils.CheckParam(args, append([]utils.Scheme{
+		utils.Scheme{
+			Param: "dimensions",
+			Required: false,
+			Type: utils.Slice,
+			Default: []interface{}{},
+		},
+	}, monitor.MetricSchemes...))
+	if err != nil {
+		return nil, err
+	}
+	replay := &input.Replay{}
+	params := input.Params{
+		Credential: fakeapi.Credential,
+		Args: args,
+	}
+	collector := &monitor.Collector{Namespace: namespace}
+	return replay, collector.Collect(params, replay)
+}
+
+func dimensions(n int) []interface{} {
+	var result []interface{}
+	for i := 1; i <= n; i++ {
+		result = append(result, map[string]interface{}{"instanceId": fmt.Sprintf("rm-%v", i)})
+	}
+	return result
+}
+
+func metrics(result []interface{}) []*models.MetricModel {
+	var metrs []*models.MetricModel
+	for _, record := range result {
+		metrs = append(metrs, *record.(**models.MetricModel))
+	}
+	return metrs
+}
+
+func TestCollect(t *testing.T) {
+	server := fakeapi.Start(t)
+	args := map[string]interface{}{
+		"region": fakeapi.Region,
+		"metric_names": []interface{}{"CpuUsage"},
+		"statistics": []interface{}{"Average", "Maximum"},
+		"dimensions": dimensions(2),
+	}
+	replay, err := collect("acs_rds_dashboard", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrs := metrics(replay.Result)
+	if len(metrs) != 4 {
+		t.Fatalf("%v metrics, want 4", len(metrs))
+	}
+	for i, want := range []string{"rm-1 CpuUsage.Average", "rm-1 CpuUsage.Maximum", "rm-2 CpuUsage.Average", "rm-2 CpuUsage.Maximum"} {
+		if got := metrs[i].InstanceId + " " + metrs[i].Name; got != want {
+			t.Errorf("metric %v is %v, want %v", i, got, want)
+		}
+	}
+	if metrs[0].Unit != "%" || metrs[0].Extra["Namespace"] != "acs_rds_dashboard" || metrs[0].MetricTime == 0 {
+		t.Errorf("unexpected metric %+v", metrs[0])
+	}
+	if replay.Next != "" || replay.Query["AccountId"] != fakeapi.AccountId || replay.Query["Index"] == "" {
+		t.Errorf("unexpected Next %q and query %v", replay.Next, replay.Query)
+	}
+	requests := server.Requests("DescribeMetricLast")
+	if len(requests) != 1 || !strings.Contains(requests[0].Get("Dimensions"), "rm-2") || requests[0].Get("Period") != "60" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+	if n := len(server.Requests("DescribeMetricMetaList")); n != 1 {
+		t.Errorf("%v DescribeMetricMetaList requests, want 1", n)
+	}
+}
+
+func TestCollectChunks(t *testing.T) {
+	server := fakeapi.Start(t)
+	args := map[string]interface{}{
+		"region": fakeapi.Region,
+		"metric_names": []interface{}{"CpuUsage", "MemoryUsage"},
+		"statistics": []interface{}{"Average"},
+		"dimensions": dimensions(common.MaxMetricDimensions + 10),
+	}
+	replay, err := collect("acs_rds_dashboard", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(replay.Result); n != 2 * (common.MaxMetricDimensions + 10) {
+		t.Errorf("%v metrics, want %v", n, 2 * (common.MaxMetricDimensions + 10))
+	}
+	// Two chunks of each metric
+	if n := len(server.Requests("DescribeMetricLast")); n != 4 {
+		t.Errorf("%v DescribeMetricLast requests, want 4", n)
+	}
+}
+
+func TestCollectNamespace(t *testing.T) {
+	fakeapi.Start(t)
+	args := map[string]interface{}{
+		"region": fakeapi.Region,
+		"metric_names": []interface{}{"CpuUsage"},
+		"statistics": []interface{}{"Average"},
+	}
+	replay, err := collect("acs_rds_dashboard", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metrs := metrics(replay.Result); len(metrs) != 1 || metrs[0].InstanceId != "rm-fake0001" {
+		t.Errorf("unexpected metrics %+v", metrs)
+	}
+	args["region"] = fakeapi.Region + ",cn-shanghai"
+	if _, err = collect("acs_rds_dashboard", args); common.ErrorCodeOf(err) != common.InvalidParam {
+		t.Errorf("got %v for two regions, want an InvalidParam error", err)
+	}
+}
+
+func TestCollectNamespacePages(t *testing.T) {
+	server := fakeapi.Start(t)
+	instances := fakeapi.NamespaceInstances["acs_rds_dashboard"]
+	fakeapi.NamespaceInstances["acs_rds_dashboard"] = []string{"rm-1", "rm-2", "rm-3"}
+	t.Cleanup(func() {
+		fakeapi.NamespaceInstances["acs_rds_dashboard"] = instances
+	})
+	// A datapoint per page
+	server.Handle("DescribeMetricLast", func(params url.Values) (int, interface{}) {
+		params.Set("Length", "1")
+		return fakeapi.MetricLast(params)
+	})
+	args := map[string]interface{}{
+		"region": fakeapi.Region,
+		"metric_names": []interface{}{"CpuUsage"},
+		"statistics": []interface{}{"Average"},
+	}
+	replay, err := collect("acs_rds_dashboard", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, metr := range metrics(replay.Result) {
+		ids = append(ids, metr.InstanceId)
+	}
+	if strings.Join(ids, " ") != "rm-1 rm-2 rm-3" {
+		t.Errorf("metrics of %v, want rm-1 rm-2 rm-3", ids)
+	}
+	requests := server.Requests("DescribeMetricLast")
+	if len(requests) != 3 || requests[0].Get("NextToken") != "" || requests[2].Get("NextToken") != "2" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
+
+// The series of an instance differing only by port keep their own index.
+func TestCollectSeries(t *testing.T) {
+	fakeapi.Start(t)
+	replay, err := collect("acs_slb_dashboard", map[string]interface{}{
+		"region": fakeapi.Region,
+		"metric_names": []interface{}{"PacketRX"},
+		"statistics": []interface{}{"Average"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	metrs := metrics(replay.Result)
+	if len(metrs) != 2 {
+		t.Fatalf("got %v metrics, want one per port", len(metrs))
+	}
+	want := []string{"PacketRX.Average/port=80,vip=47.0.0.20", "PacketRX.Average/port=443,vip=47.0.0.20"}
+	for i, metr := range metrs {
+		if metr.InstanceId != "lb-fake0001" || metr.Name != want[i] {
+			t.Errorf("metric %v of %v, want %v of lb-fake0001", metr.Name, metr.InstanceId, want[i])
+		}
+		if dims, _ := metr.Extra["Dimensions"].(map[string]string); dims["port"] == "" {
+			t.Errorf("no port in %v", metr.Extra)
+		}
+	}
+	if metrs[0].GetIndex() == metrs[1].GetIndex() {
+		t.Errorf("the ports share the index %v", metrs[0].GetIndex())
+	}
+}
+
+func TestCollectRange(t *testing.T) {
+	server := fakeapi.Start(t)
+	end := time.Now().Truncate(time.Minute)
+	args := map[string]interface{}{
+		"region": fakeapi.Region,
+		"metric_names": []interface{}{"CpuUsage"},
+		"statistics": []interface{}{"Average"},
+		"dimensions": dimensions(2),
+		"start_time": end.Add(-10 * time.Hour).Format(time.RFC3339),
+		"end_time": end.Format(time.RFC3339),
+	}
+	total := 0
+	indexes := map[string]bool{}
+	for calls := 0; ; calls++ {
+		if calls == 5 {
+			t.Fatal("metric window does not end")
+		}
+		replay, err := collect("acs_rds_dashboard", args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += len(replay.Result)
+		for _, record := range replay.Result {
+			metric := *record.(**models.MetricModel)
+			if indexes[metric.Index] {
+				t.Fatalf("datapoints share the index %v", metric.Index)
+			}
+			indexes[metric.Index] = true
+		}
+		if replay.Next == "" {
+			break
+		}
+		args["marker"] = replay.Next
+	}
+	// A datapoint per minute and instance, 1000 per page
+	if total != 1200 {
+		t.Errorf("%v datapoints, want 1200", total)
+	}
+	requests := server.Requests("DescribeMetricList")
+	if len(requests) != 2 || requests[0].Get("NextToken") != "" || requests[1].Get("NextToken") != "1000" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
+
+func TestCollectFail(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Fail("DescribeMetricLast", 10, 400, "Throttling", "Request was denied due to request throttling.")
+	args := map[string]interface{}{
+		"region": fakeapi.Region,
+		"metric_names": []interface{}{"CpuUsage"},
+		"dimensions": dimensions(1),
+		"retry_max_attempts": 1,
+	}
+	if _, err := collect("acs_rds_dashboard", args); common.ErrorCodeOf(err) != common.Throttled {
+		t.Errorf("got %v, want a Throttled error", err)
+	}
+	args["statistics"] = []interface{}{"Sum"}
+	if _, err := collect("acs_rds_dashboard", args); common.ErrorCodeOf(err) != common.InvalidParam {
+		t.Errorf("got %v for a bad statistic, want an InvalidParam error", err)
+	}
+}
+
+func TestDimensions(t *testing.T) {
+	dims, err := monitor.Dimensions(map[string]interface{}{"dimensions": []interface{}{
+		map[string]interface{}{"instanceId": "rm-1", "port": 3306},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dims) != 1 || dims[0]["instanceId"] != "rm-1" || dims[0]["port"] != "3306" {
+		t.Errorf("unexpected dimensions %v", dims)
+	}
+	for _, bad := range []interface{}{"rm-1", map[string]interface{}{}} {
+		_, err = monitor.Dimensions(map[string]interface{}{"dimensions": []interface{}{bad}})
+		if common.ErrorCodeOf(err) != common.InvalidParam {
+			t.Errorf("got %v for %v, want an InvalidParam error", err, bad)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package network_test
+
+import (
+	"github.com/hahaps/common-provider/src/models"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFloatingIpMetric(t *testing.T) {
+	server := fakeapi.Start(t)
+	args := map[string]interface{}{
+		"region": fakeapi.Region,
+		"metric_names": []interface{}{"net_rx.rate"},
+	}
+	replay, err := fakeapi.Call("FloatingIpMetric", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.Result) != 1 || replay.Next != "" {
+		t.Fatalf("got %v records and Next %q, want 1 record and no Next", len(replay.Result), replay.Next)
+	}
+	metr := *replay.Result[0].(**models.MetricModel)
+	if metr.InstanceId != "eip-fake0001" || metr.Name != "net_rx.rate.Value" || metr.Unit != "bit/s" {
+		t.Errorf("unexpected metric %+v", metr)
+	}
+	if metr.Extra["IpAddr"] != "47.0.0.10" || metr.Extra["Namespace"] != "acs_vpc_eip" {
+		t.Errorf("unexpected extra %v", metr.Extra)
+	}
+	request := server.Requests("DescribeMetricLast")[0]
+	if request.Get("Namespace") != "acs_vpc_eip" || !strings.Contains(request.Get("Dimensions"), "eip-fake0001") {
+		t.Errorf("unexpected request %v", request)
+	}
+}
+
+func TestFloatingIpMetricRange(t *testing.T) {
+	server := fakeapi.Start(t)
+	end := time.Now().Truncate(time.Minute)
+	args := map[string]interface{}{
+		"region": fakeapi.Region,
+		"metric_names": []interface{}{"net_rx.rate", "net_tx.rate"},
+		"start_time": end.Add(-5 * time.Minute).Format(time.RFC3339),
+		"end_time": end.Format(time.RFC3339),
+	}
+	var metrs []*models.MetricModel
+	for calls := 0; ; calls++ {
+		if calls == 10 {
+			t.Fatal("metric window does not end")
+		}
+		replay, err := fakeapi.Call("FloatingIpMetric", args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, record := range replay.Result {
+			metrs = append(metrs, *record.(**models.MetricModel))
+		}
+		if replay.Next == "" {
+			break
+		}
+		args["marker"] = replay.Next
+	}
+	// 5 datapoints of each metric
+	if len(metrs) != 10 {
+		t.Errorf("%v datapoints, want 10", len(metrs))
+	}
+	if n := len(server.Requests("DescribeMetricList")); n != 2 {
+		t.Errorf("%v DescribeMetricList requests, want 2", n)
+	}
+}
@@ -1,16 +1,12 @@
 package network
 
 import (
-	"errors"
-	"fmt"
-	openapi "github.com/alibabacloud-go/darabonba-openapi/client"
-	ecs20140526 "github.com/alibabacloud-go/ecs-20140526/v2/client"
 	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hahaps/common-provider/src/common/model"
 	"github.com/hahaps/common-provider/src/common/utils"
 	"github.com/hahaps/common-provider/src/input"
 	"github.com/hahaps/common-provider/src/models/network"
 	"github.com/hahaps/input-provider-aliyun/src/common"
-	"strconv"
 )
 
 var FloatingIpSchemes = []utils.Scheme {
@@ -31,69 +27,89 @@ var FloatingIpSchemes = []utils.Scheme {
 		Type: utils.String,
 		Default: "1",
 	},
+	utils.Scheme{
+		Param: "tags",
+		Required: false,
+		Type: utils.Map,
+	},
+	utils.Scheme{
+		Param: "resource_group_id",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
 }
 
 type FloatingIp struct {
-	client *ecs20140526.Client
+	client *common.VpcApiClient
 	credential input.Credential
 	input.Resource
 }
 
-func (fip *FloatingIp)init(credential input.Credential) error {
-	config := &openapi.Config{
-		AccessKeyId: &credential.SecretId,
-		AccessKeySecret: &credential.SecretKey,
-	}
-	config.Endpoint = tea.String("ecs.aliyuncs.com")
-	cli, err := ecs20140526.NewClient(config)
-	if err != nil {
-		return err
-	}
-	fip.credential = credential
-	fip.client = cli
-	return nil
+// NewModel returns an empty model of the resource.
+func (FloatingIp)NewModel() model.BaseModel {
+	return network.NewFloatingIpModel()
 }
 
 func (FloatingIp)Call(params input.Params, replay *input.Replay) error {
 	fip := &FloatingIp{}
-	var next string
 	var err error
 	var fips []interface{}
-	params.Args, err = utils.CheckParam(params.Args, SecurityGroupSchemes)
+	common.NormalizeRegion(params.Args)
+	params.Args, err = utils.CheckParam(params.Args, FloatingIpSchemes)
+	if err != nil {
+		return common.ParamError(err)
+	}
+	tagFilters, err := common.TagFilters(params.Args)
+	if err != nil {
+		return err
+	}
+	invoker, err := common.NewInvoker(params)
 	if err != nil {
 		return err
 	}
-	err = fip.init(params.Credential)
+	defer invoker.Close()
+	regions, err := common.Regions(invoker, params.Credential, params.Args["region"].(string))
 	if err != nil {
 		return err
 	}
 	limit := int32(params.Args["limit"].(int))
-	next = params.Args["marker"].(string)
-	region := params.Args["region"].(string)
-	if next == "" {
+	pager, err := common.NewPaginator(common.PageNumberStyle, params.Args["marker"].(string), regions, limit)
+	if err != nil {
+		return err
+	}
+	if pager.Done() {
 		return nil
 	}
-	number, err := strconv.ParseInt(next,10,32)
+	region := pager.Region()
+	fip.credential = params.Credential
+	fip.client, err = common.VpcClient(invoker, params.Credential, region)
 	if err != nil {
-		return errors.New("bad page number[marker] info")
+		return err
 	}
-	pageNum := int32(number)
+	defer common.ReleaseClient(fip.client)
 	query := map[string]interface{} {
 		"RegionId": region,
 		"CloudType": common.CloudType,
 		"AccountId": fip.credential.AccountId,
 	}
-	request := &ecs20140526.DescribeEipAddressesRequest{
+	request := &common.DescribeEipAddressesRequest{
 		RegionId: &region,
-		PageSize: &limit,
-		PageNumber: &pageNum,
+		PageSize: tea.Int32(pager.PageSize()),
+		PageNumber: tea.Int32(pager.PageNumber()),
 	}
-	resp, err := fip.client.DescribeEipAddresses(request)
+	request.Tag = common.VpcTagFilters(tagFilters)
+	request.ResourceGroupId = common.OptionalString(params.Args["resource_group_id"].(string))
+	var resp *common.DescribeEipAddressesResponse
+	err = invoker.Invoke(common.ProductVpc, region, "DescribeEipAddresses", func() (err error) {
+		resp, err = fip.client.DescribeEipAddresses(request)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 	if resp.Body.EipAddresses == nil || resp.Body.EipAddresses.EipAddress == nil {
-		return errors.New("bad response for query vSwitch")
+		return common.NewError(common.Upstream, "bad response for query eip addresses")
 	}
 	for _, b := range resp.Body.EipAddresses.EipAddress {
 		s := network.NewFloatingIpModel()
@@ -108,28 +124,28 @@ func (FloatingIp)Call(params input.Params, replay *input.Replay) error {
 		s.Bandwidth = utils.SafeString(b.Bandwidth)
 		s.BindResourceId = utils.SafeString(b.InstanceId)
 		s.BindResourceType = utils.SafeString(b.InstanceType)
+		tags := common.VpcTags(b.Tags)
+		s.Tags = tags.String()
 		s.Extra = map[string]interface{}{
 			"ChargeType": utils.SafeString(b.ChargeType),
+			"ResourceGroupId": utils.SafeString(b.ResourceGroupId),
+			"Tags": tags.Map(),
 		}
 		s.SetIndex()
 		s.SetChecksum()
 		checked, key := s.CheckRequired()
 		if !checked {
-			return errors.New(
-				fmt.Sprintf("Value[%v] should not be empty", key))
+			return common.NewError(common.Upstream, "Value[%v] should not be empty", key)
 		}
 		fips = append(fips, s)
 	}
-	if !utils.CheckQueryKeys(query, network.SecurityGroupModel{}) {
-		return errors.New("query key is not attribute of FIpModel")
+	if !utils.CheckQueryKeys(query, network.FloatingIpModel{}) {
+		return common.NewError(common.Internal, "query key is not attribute of FIpModel")
 	}
-	total := utils.SafeInt32(resp.Body.TotalCount)
-	if pageNum * limit >= total {
-		next = ""
-	} else {
-		next = strconv.Itoa(int(pageNum + 1))
+	replay.Next, err = pager.Next(utils.SafeInt32(resp.Body.TotalCount), "")
+	if err != nil {
+		return err
 	}
-	replay.Next = next
 	replay.Query = query
 	replay.Result = fips
 	return nil
@@ -0,0 +1,45 @@
+package network_test
+
+import (
+	"flag"
+	"github.com/hahaps/input-provider-aliyun/src/cassette"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"os"
+	"testing"
+)
+
+var record = flag.Bool("record", false, "record the testdata cassettes again against fakeapi")
+
+const floatingIpCassette = "testdata/floating_ip.json"
+
+// recordFloatingIp records the two pages of DescribeEipAddresses.
+func recordFloatingIp(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Handle("DescribeEipAddresses", fakeapi.Pages{
+		List: "EipAddresses.EipAddress",
+		Items: fakeapi.Items(eip("eip-1"), eip("eip-2"), eip("eip-3")),
+	}.Handler())
+	os.Remove(floatingIpCassette)
+	defer common.UseCassette("", "")
+	args := map[string]interface{}{"region": fakeapi.Region, "limit": 2, "cassette_mode": cassette.Record, "cassette": floatingIpCassette}
+	walk(t, "FloatingIp", args)
+}
+
+func TestFloatingIpReplay(t *testing.T) {
+	if *record {
+		recordFloatingIp(t)
+	}
+	c, err := cassette.Load(floatingIpCassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if leaks := c.Leaks(); len(leaks) != 0 {
+		t.Fatalf("%v holds secrets %v", floatingIpCassette, leaks)
+	}
+	defer common.UseCassette("", "")
+	args := map[string]interface{}{"region": fakeapi.Region, "limit": 2, "cassette_mode": cassette.Replay, "cassette": floatingIpCassette}
+	if ids := walk(t, "FloatingIp", args); !equal(ids, "eip-1", "eip-2", "eip-3") {
+		t.Errorf("floating ips %v", ids)
+	}
+}
@@ -0,0 +1,55 @@
+package network_test
+
+import (
+	"github.com/hahaps/common-provider/src/models/network"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"testing"
+)
+
+func eip(id string) map[string]interface{} {
+	return map[string]interface{}{
+		"AllocationId": id,
+		"IpAddress": "47.0.0.1",
+		"Status": "Available",
+	}
+}
+
+func TestFloatingIp(t *testing.T) {
+	fakeapi.Start(t)
+	replay, err := fakeapi.Call("FloatingIp", map[string]interface{}{"region": fakeapi.Region})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.Result) != 1 || replay.Next != "" {
+		t.Fatalf("got %v records and Next %q, want 1 record and no Next", len(replay.Result), replay.Next)
+	}
+	fip := replay.Result[0].(*network.FloatingIpModel)
+	if fip.ProviderId != "eip-fake0001" || fip.IpAddr != "47.0.0.10" || fip.BindResourceId != "i-fake0001" || fip.Tags != "env=test" {
+		t.Errorf("unexpected floating ip %+v", fip)
+	}
+}
+
+func TestFloatingIpPages(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Handle("DescribeEipAddresses", fakeapi.Pages{
+		List: "EipAddresses.EipAddress",
+		Items: fakeapi.Items(eip("eip-1"), eip("eip-2"), eip("eip-3")),
+	}.Handler())
+	ids := walk(t, "FloatingIp", map[string]interface{}{"region": fakeapi.Region, "limit": 2})
+	if !equal(ids, "eip-1", "eip-2", "eip-3") {
+		t.Errorf("floating ips %v", ids)
+	}
+	requests := server.Requests("DescribeEipAddresses")
+	if len(requests) != 2 || requests[0].Get("PageSize") != "2" || requests[1].Get("PageNumber") != "2" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
+
+func TestFloatingIpFail(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Fail("DescribeEipAddresses", 1, 403, "Forbidden.RAM", "User not authorized to operate on the specified resource.")
+	if _, err := fakeapi.Call("FloatingIp", map[string]interface{}{"region": fakeapi.Region}); common.ErrorCodeOf(err) != common.Forbidden {
+		t.Errorf("got %v, want a Forbidden error", err)
+	}
+}
@@ -0,0 +1,50 @@
+package network_test
+
+import (
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"reflect"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	fakeapi.Main(m)
+}
+
+// providerIds returns the ProviderId of each record
+func providerIds(t *testing.T, result []interface{}) []string {
+	var ids []string
+	for _, record := range result {
+		value := reflect.ValueOf(record)
+		if value.Kind() != reflect.Ptr || value.Elem().FieldByName("ProviderId").Kind() != reflect.String {
+			t.Fatalf("unexpected record %#v", record)
+		}
+		ids = append(ids, value.Elem().FieldByName("ProviderId").String())
+	}
+	return ids
+}
+
+// walk calls resource from the first page until Next is empty, and returns
+// the ProviderIds of every page.
+func walk(t *testing.T, resource string, args map[string]interface{}) []string {
+	var ids []string
+	args["marker"] = "1"
+	for calls := 0; args["marker"] != ""; calls++ {
+		if calls == 10 {
+			t.Fatalf("pagination of %v does not end", resource)
+		}
+		replay, err := fakeapi.Call(resource, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(replay.Result) > 0 && replay.Query["RegionId"] != fakeapi.Region {
+			t.Errorf("unexpected query %v", replay.Query)
+		}
+		ids = append(ids, providerIds(t, replay.Result)...)
+		args["marker"] = replay.Next
+	}
+	return ids
+}
+
+func equal(ids []string, want ...string) bool {
+	return reflect.DeepEqual(ids, want)
+}
@@ -1,16 +1,12 @@
 package network
 
 import (
-	"errors"
-	"fmt"
-	openapi "github.com/alibabacloud-go/darabonba-openapi/client"
-	ecs20140526 "github.com/alibabacloud-go/ecs-20140526/v2/client"
 	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hahaps/common-provider/src/common/model"
 	"github.com/hahaps/common-provider/src/common/utils"
 	"github.com/hahaps/common-provider/src/input"
 	"github.com/hahaps/common-provider/src/models/network"
 	"github.com/hahaps/input-provider-aliyun/src/common"
-	"strconv"
 )
 
 var NetworkSchemes = []utils.Scheme {
@@ -31,69 +27,89 @@ var NetworkSchemes = []utils.Scheme {
 		Type: utils.String,
 		Default: "1",
 	},
+	utils.Scheme{
+		Param: "tags",
+		Required: false,
+		Type: utils.Map,
+	},
+	utils.Scheme{
+		Param: "resource_group_id",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
 }
 
 type Network struct {
-	client *ecs20140526.Client
+	client *common.VpcApiClient
 	credential input.Credential
 	input.Resource
 }
 
-func (vpc *Network)init(credential input.Credential) error {
-	config := &openapi.Config{
-		AccessKeyId: &credential.SecretId,
-		AccessKeySecret: &credential.SecretKey,
-	}
-	config.Endpoint = tea.String("ecs.aliyuncs.com")
-	cli, err := ecs20140526.NewClient(config)
-	if err != nil {
-		return err
-	}
-	vpc.credential = credential
-	vpc.client = cli
-	return nil
+// NewModel returns an empty model of the resource.
+func (Network)NewModel() model.BaseModel {
+	return network.NewNetworkModel()
 }
 
 func (Network)Call(params input.Params, replay *input.Replay) error {
 	vpc := &Network{}
-	var next string
 	var err error
 	var nets []interface{}
+	common.NormalizeRegion(params.Args)
 	params.Args, err = utils.CheckParam(params.Args, NetworkSchemes)
+	if err != nil {
+		return common.ParamError(err)
+	}
+	tagFilters, err := common.TagFilters(params.Args)
+	if err != nil {
+		return err
+	}
+	invoker, err := common.NewInvoker(params)
 	if err != nil {
 		return err
 	}
-	err = vpc.init(params.Credential)
+	defer invoker.Close()
+	regions, err := common.Regions(invoker, params.Credential, params.Args["region"].(string))
 	if err != nil {
 		return err
 	}
 	limit := int32(params.Args["limit"].(int))
-	next = params.Args["marker"].(string)
-	region := params.Args["region"].(string)
-	if next == "" {
+	pager, err := common.NewPaginator(common.PageNumberStyle, params.Args["marker"].(string), regions, limit)
+	if err != nil {
+		return err
+	}
+	if pager.Done() {
 		return nil
 	}
-	number, err := strconv.ParseInt(next,10,32)
+	region := pager.Region()
+	vpc.credential = params.Credential
+	vpc.client, err = common.VpcClient(invoker, params.Credential, region)
 	if err != nil {
-		return errors.New("bad page number[marker] info")
+		return err
 	}
-	pageNum := int32(number)
+	defer common.ReleaseClient(vpc.client)
 	query := map[string]interface{} {
 		"RegionId": region,
 		"CloudType": common.CloudType,
 		"AccountId": vpc.credential.AccountId,
 	}
-	request := &ecs20140526.DescribeVpcsRequest{
+	request := &common.DescribeVpcsRequest{
 		RegionId: &region,
-		PageSize: &limit,
-		PageNumber: &pageNum,
+		PageSize: tea.Int32(pager.PageSize()),
+		PageNumber: tea.Int32(pager.PageNumber()),
 	}
-	resp, err := vpc.client.DescribeVpcs(request)
+	request.Tag = common.VpcTagFilters(tagFilters)
+	request.ResourceGroupId = common.OptionalString(params.Args["resource_group_id"].(string))
+	var resp *common.DescribeVpcsResponse
+	err = invoker.Invoke(common.ProductVpc, region, "DescribeVpcs", func() (err error) {
+		resp, err = vpc.client.DescribeVpcs(request)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 	if resp.Body.Vpcs == nil || resp.Body.Vpcs.Vpc == nil {
-		return errors.New("bad response for query networks")
+		return common.NewError(common.Upstream, "bad response for query networks")
 	}
 	for _, b := range resp.Body.Vpcs.Vpc {
 		net := network.NewNetworkModel()
@@ -108,28 +124,28 @@ func (Network)Call(params input.Params, replay *input.Replay) error {
 		net.CreateTime = utils.SafeString(b.CreationTime)
 		net.Description = utils.SafeString(b.Description)
 		net.CIDR = utils.SafeString(b.CidrBlock)
+		tags := common.VpcTags(b.Tags)
+		net.Tags = tags.String()
 		net.Extra = map[string]interface{}{
 			"VRouterId": utils.SafeString(b.VRouterId),
+			"ResourceGroupId": utils.SafeString(b.ResourceGroupId),
+			"Tags": tags.Map(),
 		}
 		net.SetIndex()
 		net.SetChecksum()
 		checked, key := net.CheckRequired()
 		if !checked {
-			return errors.New(
-				fmt.Sprintf("Value[%v] should not be empty", key))
+			return common.NewError(common.Upstream, "Value[%v] should not be empty", key)
 		}
 		nets = append(nets, net)
 	}
 	if !utils.CheckQueryKeys(query, network.NetworkModel{}) {
-		return errors.New("query key is not attribute of NetworkModel")
+		return common.NewError(common.Internal, "query key is not attribute of NetworkModel")
 	}
-	total := utils.SafeInt32(resp.Body.TotalCount)
-	if pageNum * limit >= total {
-		next = ""
-	} else {
-		next = strconv.Itoa(int(pageNum + 1))
+	replay.Next, err = pager.Next(utils.SafeInt32(resp.Body.TotalCount), "")
+	if err != nil {
+		return err
 	}
-	replay.Next = next
 	replay.Query = query
 	replay.Result = nets
 	return nil
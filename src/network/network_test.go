@@ -0,0 +1,106 @@
+package network_test
+
+import (
+	"github.com/hahaps/common-provider/src/models/network"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"testing"
+)
+
+func vpc(id string) map[string]interface{} {
+	return map[string]interface{}{
+		"VpcId": id,
+		"VpcName": "vpc-" + id,
+		"Status": "Available",
+		"CidrBlock": "172.16.0.0/12",
+	}
+}
+
+func TestNetwork(t *testing.T) {
+	fakeapi.Start(t)
+	replay, err := fakeapi.Call("Network", map[string]interface{}{"region": fakeapi.Region})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.Result) != 1 || replay.Next != "" {
+		t.Fatalf("got %v records and Next %q, want 1 record and no Next", len(replay.Result), replay.Next)
+	}
+	net := replay.Result[0].(*network.NetworkModel)
+	if net.ProviderId != "vpc-fake0001" || net.Name != "fake-vpc" || net.Tags != "env=test" || net.RegionId != fakeapi.Region {
+		t.Errorf("unexpected network %+v", net)
+	}
+	if replay.Query["AccountId"] != fakeapi.AccountId || replay.Query["CloudType"] != common.CloudType {
+		t.Errorf("unexpected query %v", replay.Query)
+	}
+}
+
+func TestNetworkPages(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Handle("DescribeVpcs", fakeapi.Pages{
+		List: "Vpcs.Vpc",
+		Items: fakeapi.Items(vpc("vpc-1"), vpc("vpc-2"), vpc("vpc-3")),
+	}.Handler())
+	ids := walk(t, "Network", map[string]interface{}{"region": fakeapi.Region, "limit": 2})
+	if !equal(ids, "vpc-1", "vpc-2", "vpc-3") {
+		t.Errorf("networks %v", ids)
+	}
+	requests := server.Requests("DescribeVpcs")
+	if len(requests) != 2 || requests[0].Get("PageNumber") != "1" || requests[1].Get("PageNumber") != "2" || requests[1].Get("PageSize") != "2" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
+
+func TestNetworkFail(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Fail("DescribeVpcs", 1, 403, "Forbidden.RAM", "User not authorized to operate on the specified resource.")
+	if _, err := fakeapi.Call("Network", map[string]interface{}{"region": fakeapi.Region}); common.ErrorCodeOf(err) != common.Forbidden {
+		t.Errorf("got %v, want a Forbidden error", err)
+	}
+}
+
+// The VPC actions listing networks, subnets and floating ips filter on tags
+// and resource group.
+func TestNetworkFilters(t *testing.T) {
+	server := fakeapi.Start(t)
+	actions := map[string]string{
+		"Network": "DescribeVpcs",
+		"Subnet": "DescribeVSwitches",
+		"FloatingIp": "DescribeEipAddresses",
+	}
+	for resource, action := range actions {
+		args := map[string]interface{}{
+			"region": fakeapi.Region,
+			"tags": map[string]interface{}{"env": "test", "team": ""},
+			"resource_group_id": fakeapi.ResourceGroupId,
+		}
+		replay, err := fakeapi.Call(resource, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(replay.Result) != 0 {
+			t.Errorf("%v tagged team got %v records, want none", resource, len(replay.Result))
+		}
+		requests := server.Requests(action)
+		request := requests[len(requests) - 1]
+		if request.Get("Version") != common.VpcVersion || request.Get("Tag.1.Key") != "env" || request.Get("Tag.1.Value") != "test" ||
+			request.Get("Tag.2.Key") != "team" || request.Get("Tag.2.Value") != "" || request.Get("ResourceGroupId") != fakeapi.ResourceGroupId {
+			t.Errorf("unexpected %v request %v", action, request)
+		}
+		args["tags"] = map[string]interface{}{"env": "test"}
+		replay, err = fakeapi.Call(resource, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(replay.Result) != 1 {
+			t.Errorf("%v tagged env=test got %v records, want 1", resource, len(replay.Result))
+		}
+		args["resource_group_id"] = "rg-other"
+		replay, err = fakeapi.Call(resource, args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(replay.Result) != 0 {
+			t.Errorf("%v of rg-other got %v records, want none", resource, len(replay.Result))
+		}
+	}
+}
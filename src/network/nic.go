@@ -1,16 +1,13 @@
 package network
 
 import (
-	"errors"
-	"fmt"
-	openapi "github.com/alibabacloud-go/darabonba-openapi/client"
 	ecs20140526 "github.com/alibabacloud-go/ecs-20140526/v2/client"
 	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hahaps/common-provider/src/common/model"
 	"github.com/hahaps/common-provider/src/common/utils"
 	"github.com/hahaps/common-provider/src/input"
 	"github.com/hahaps/common-provider/src/models/network"
 	"github.com/hahaps/input-provider-aliyun/src/common"
-	"strconv"
 )
 
 var NicSchemes = []utils.Scheme {
@@ -36,6 +33,17 @@ var NicSchemes = []utils.Scheme {
 		Type: utils.String,
 		Default: "1",
 	},
+	utils.Scheme{
+		Param: "tags",
+		Required: false,
+		Type: utils.Map,
+	},
+	utils.Scheme{
+		Param: "resource_group_id",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
 }
 
 type Nic struct {
@@ -44,49 +52,52 @@ type Nic struct {
 	input.Resource
 }
 
-func (nic *Nic)init(credential input.Credential) error {
-	config := &openapi.Config{
-		AccessKeyId: &credential.SecretId,
-		AccessKeySecret: &credential.SecretKey,
-	}
-	config.Endpoint = tea.String("ecs.aliyuncs.com")
-	cli, err := ecs20140526.NewClient(config)
-	if err != nil {
-		return err
-	}
-	nic.credential = credential
-	nic.client = cli
-	return nil
+// NewModel returns an empty model of the resource.
+func (Nic)NewModel() model.BaseModel {
+	return network.NewNicModel()
 }
 
 func (Nic)Call(params input.Params, replay *input.Replay) error {
 	nic := &Nic{}
-	var next string
 	var err error
 	var nics []interface{}
+	common.NormalizeRegion(params.Args)
 	params.Args, err = utils.CheckParam(params.Args, NicSchemes)
+	if err != nil {
+		return common.ParamError(err)
+	}
+	tagFilters, err := common.TagFilters(params.Args)
+	if err != nil {
+		return err
+	}
+	invoker, err := common.NewInvoker(params)
 	if err != nil {
 		return err
 	}
-	err = nic.init(params.Credential)
+	defer invoker.Close()
+	regions, err := common.Regions(invoker, params.Credential, params.Args["region"].(string))
 	if err != nil {
 		return err
 	}
 	limit := int32(params.Args["limit"].(int))
-	next = params.Args["marker"].(string)
-	sub := ""
-	if params.Args["subnet"] != nil {
-		sub = params.Args["subnet"].(string)
+	pager, err := common.NewPaginator(common.MaxResultsStyle, params.Args["marker"].(string), regions, limit)
+	if err != nil {
+		return err
 	}
-	region := params.Args["region"].(string)
-	if next == "" {
+	if pager.Done() {
 		return nil
 	}
-	number, err := strconv.ParseInt(next,10,32)
+	region := pager.Region()
+	nic.credential = params.Credential
+	nic.client, err = common.EcsClient(invoker, params.Credential, region)
 	if err != nil {
-		return errors.New("bad page number[marker] info")
+		return err
+	}
+	defer common.ReleaseClient(nic.client)
+	sub := ""
+	if params.Args["subnet"] != nil {
+		sub = params.Args["subnet"].(string)
 	}
-	pageNum := int32(number)
 	query := map[string]interface{} {
 		"RegionId": region,
 		"CloudType": common.CloudType,
@@ -94,18 +105,31 @@ func (Nic)Call(params input.Params, replay *input.Replay) error {
 	}
 	request := &ecs20140526.DescribeNetworkInterfacesRequest{
 		RegionId: &region,
-		PageSize: &limit,
-		PageNumber: &pageNum,
+		MaxResults: tea.Int32(pager.PageSize()),
+	}
+	for _, tag := range tagFilters {
+		request.Tag = append(request.Tag, &ecs20140526.DescribeNetworkInterfacesRequestTag{
+			Key: tea.String(tag.Key),
+			Value: common.OptionalString(tag.Value),
+		})
+	}
+	request.ResourceGroupId = common.OptionalString(params.Args["resource_group_id"].(string))
+	if pager.NextToken() != "" {
+		request.NextToken = tea.String(pager.NextToken())
 	}
 	if sub != "" {
 		request.VSwitchId = &sub
 	}
-	resp, err := nic.client.DescribeNetworkInterfaces(request)
+	var resp *ecs20140526.DescribeNetworkInterfacesResponse
+	err = invoker.Invoke(common.ProductEcs, region, "DescribeNetworkInterfaces", func() (err error) {
+		resp, err = nic.client.DescribeNetworkInterfaces(request)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 	if resp.Body.NetworkInterfaceSets == nil || resp.Body.NetworkInterfaceSets.NetworkInterfaceSet == nil {
-		return errors.New("bad response for query vSwitch")
+		return common.NewError(common.Upstream, "bad response for query network interfaces")
 	}
 	for _, b := range resp.Body.NetworkInterfaceSets.NetworkInterfaceSet {
 		s := network.NewNicModel()
@@ -121,13 +145,15 @@ func (Nic)Call(params input.Params, replay *input.Replay) error {
 		s.Description = utils.SafeString(b.Description)
 		s.NetworkId = utils.SafeString(b.VpcId)
 		s.SubnetId = utils.SafeString(b.VSwitchId)
-		s.Tags = getNicTags(b.Tags)
+		tags := getNicTags(b.Tags)
+		s.Tags = tags.String()
 		s.InstanceId = utils.SafeString(b.InstanceId)
 		s.Extra = map[string]interface{}{
 			"ZoneId": utils.SafeString(b.ZoneId),
 			"ResourceGroupId": utils.SafeString(b.ResourceGroupId),
 			"Type": utils.SafeString(b.Type),
 			"MacAddress": utils.SafeString(b.MacAddress),
+			"Tags": tags.Map(),
 		}
 		if b.AssociatedPublicIp != nil {
 			s.Extra["FloatingIp"] = b.AssociatedPublicIp.PublicIpAddress
@@ -136,37 +162,29 @@ func (Nic)Call(params input.Params, replay *input.Replay) error {
 		s.SetChecksum()
 		checked, key := s.CheckRequired()
 		if !checked {
-			return errors.New(
-				fmt.Sprintf("Value[%v] should not be empty", key))
+			return common.NewError(common.Upstream, "Value[%v] should not be empty", key)
 		}
 		nics = append(nics, s)
 	}
 	if !utils.CheckQueryKeys(query, network.NicModel{}) {
-		return errors.New("query key is not attribute of NicModel")
+		return common.NewError(common.Internal, "query key is not attribute of NicModel")
 	}
-	total := utils.SafeInt32(resp.Body.TotalCount)
-	if pageNum * limit >= total {
-		next = ""
-	} else {
-		next = strconv.Itoa(int(pageNum + 1))
+	replay.Next, err = pager.Next(utils.SafeInt32(resp.Body.TotalCount), utils.SafeString(resp.Body.NextToken))
+	if err != nil {
+		return err
 	}
-	replay.Next = next
 	replay.Query = query
 	replay.Result = nics
 	return nil
 }
 
-func getNicTags(tags *ecs20140526.DescribeNetworkInterfacesResponseBodyNetworkInterfaceSetsNetworkInterfaceSetTags) string {
+func getNicTags(tags *ecs20140526.DescribeNetworkInterfacesResponseBodyNetworkInterfaceSetsNetworkInterfaceSetTags) common.Tags {
+	result := common.Tags{}
 	if tags == nil {
-		return ""
-	}
-	tgs := ""
-	l := len(tags.Tag)
-	for i, tg := range tags.Tag {
-		tgs += fmt.Sprintf("%v=%v", tg.TagKey, tg.TagValue)
-		if i + 1 < l {
-			tgs += ";"
-		}
+		return result
+	}
+	for _, tg := range tags.Tag {
+		result.Add(tg.TagKey, tg.TagValue)
 	}
-	return tgs
+	return result
 }
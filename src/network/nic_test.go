@@ -0,0 +1,58 @@
+package network_test
+
+import (
+	"github.com/hahaps/common-provider/src/models/network"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"testing"
+)
+
+func nic(id string) map[string]interface{} {
+	return map[string]interface{}{
+		"NetworkInterfaceId": id,
+		"NetworkInterfaceName": "nic-" + id,
+		"VpcId": "vpc-fake0001",
+		"VSwitchId": "vsw-fake0001",
+		"Status": "Available",
+		"Type": "Secondary",
+	}
+}
+
+func TestNic(t *testing.T) {
+	fakeapi.Start(t)
+	replay, err := fakeapi.Call("Nic", map[string]interface{}{"region": fakeapi.Region})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.Result) != 1 || replay.Next != "" {
+		t.Fatalf("got %v records and Next %q, want 1 record and no Next", len(replay.Result), replay.Next)
+	}
+	n := replay.Result[0].(*network.NicModel)
+	if n.ProviderId != "eni-fake0001" || n.InstanceId != "i-fake0001" || n.SubnetId != "vsw-fake0001" || n.CIDR != "172.16.0.10" {
+		t.Errorf("unexpected nic %+v", n)
+	}
+}
+
+func TestNicPages(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Handle("DescribeNetworkInterfaces", fakeapi.Pages{
+		List: "NetworkInterfaceSets.NetworkInterfaceSet",
+		Items: fakeapi.Items(nic("eni-1"), nic("eni-2"), nic("eni-3")),
+	}.Handler())
+	ids := walk(t, "Nic", map[string]interface{}{"region": fakeapi.Region, "limit": 2})
+	if !equal(ids, "eni-1", "eni-2", "eni-3") {
+		t.Errorf("nics %v", ids)
+	}
+	requests := server.Requests("DescribeNetworkInterfaces")
+	if len(requests) != 2 || requests[0].Get("MaxResults") != "2" || requests[1].Get("NextToken") != "2" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
+
+func TestNicFail(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Fail("DescribeNetworkInterfaces", 1, 404, "InvalidAccessKeyId.NotFound", "Specified access key is not found.")
+	if _, err := fakeapi.Call("Nic", map[string]interface{}{"region": fakeapi.Region}); common.ErrorCodeOf(err) != common.AuthFailed {
+		t.Errorf("got %v, want an AuthFailed error", err)
+	}
+}
@@ -1,16 +1,13 @@
 package network
 
 import (
-	"errors"
-	"fmt"
-	openapi "github.com/alibabacloud-go/darabonba-openapi/client"
 	ecs20140526 "github.com/alibabacloud-go/ecs-20140526/v2/client"
 	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hahaps/common-provider/src/common/model"
 	"github.com/hahaps/common-provider/src/common/utils"
 	"github.com/hahaps/common-provider/src/input"
 	"github.com/hahaps/common-provider/src/models/network"
 	"github.com/hahaps/input-provider-aliyun/src/common"
-	"strconv"
 )
 
 var SecurityGroupSchemes = []utils.Scheme {
@@ -31,6 +28,17 @@ var SecurityGroupSchemes = []utils.Scheme {
 		Type: utils.String,
 		Default: "1",
 	},
+	utils.Scheme{
+		Param: "tags",
+		Required: false,
+		Type: utils.Map,
+	},
+	utils.Scheme{
+		Param: "resource_group_id",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
 }
 
 type SecurityGroup struct {
@@ -39,45 +47,48 @@ type SecurityGroup struct {
 	input.Resource
 }
 
-func (sg *SecurityGroup)init(credential input.Credential) error {
-	config := &openapi.Config{
-		AccessKeyId: &credential.SecretId,
-		AccessKeySecret: &credential.SecretKey,
-	}
-	config.Endpoint = tea.String("ecs.aliyuncs.com")
-	cli, err := ecs20140526.NewClient(config)
-	if err != nil {
-		return err
-	}
-	sg.credential = credential
-	sg.client = cli
-	return nil
+// NewModel returns an empty model of the resource.
+func (SecurityGroup)NewModel() model.BaseModel {
+	return network.NewSecurityGroupModel()
 }
 
 func (SecurityGroup)Call(params input.Params, replay *input.Replay) error {
 	sg := &SecurityGroup{}
-	var next string
 	var err error
 	var nics []interface{}
+	common.NormalizeRegion(params.Args)
 	params.Args, err = utils.CheckParam(params.Args, SecurityGroupSchemes)
+	if err != nil {
+		return common.ParamError(err)
+	}
+	tagFilters, err := common.TagFilters(params.Args)
 	if err != nil {
 		return err
 	}
-	err = sg.init(params.Credential)
+	invoker, err := common.NewInvoker(params)
+	if err != nil {
+		return err
+	}
+	defer invoker.Close()
+	regions, err := common.Regions(invoker, params.Credential, params.Args["region"].(string))
 	if err != nil {
 		return err
 	}
 	limit := int32(params.Args["limit"].(int))
-	next = params.Args["marker"].(string)
-	region := params.Args["region"].(string)
-	if next == "" {
+	pager, err := common.NewPaginator(common.PageNumberStyle, params.Args["marker"].(string), regions, limit)
+	if err != nil {
+		return err
+	}
+	if pager.Done() {
 		return nil
 	}
-	number, err := strconv.ParseInt(next,10,32)
+	region := pager.Region()
+	sg.credential = params.Credential
+	sg.client, err = common.EcsClient(invoker, params.Credential, region)
 	if err != nil {
-		return errors.New("bad page number[marker] info")
+		return err
 	}
-	pageNum := int32(number)
+	defer common.ReleaseClient(sg.client)
 	query := map[string]interface{} {
 		"RegionId": region,
 		"CloudType": common.CloudType,
@@ -85,15 +96,26 @@ func (SecurityGroup)Call(params input.Params, replay *input.Replay) error {
 	}
 	request := &ecs20140526.DescribeSecurityGroupsRequest{
 		RegionId: &region,
-		PageSize: &limit,
-		PageNumber: &pageNum,
+		PageSize: tea.Int32(pager.PageSize()),
+		PageNumber: tea.Int32(pager.PageNumber()),
 	}
-	resp, err := sg.client.DescribeSecurityGroups(request)
+	for _, tag := range tagFilters {
+		request.Tag = append(request.Tag, &ecs20140526.DescribeSecurityGroupsRequestTag{
+			Key: tea.String(tag.Key),
+			Value: common.OptionalString(tag.Value),
+		})
+	}
+	request.ResourceGroupId = common.OptionalString(params.Args["resource_group_id"].(string))
+	var resp *ecs20140526.DescribeSecurityGroupsResponse
+	err = invoker.Invoke(common.ProductEcs, region, "DescribeSecurityGroups", func() (err error) {
+		resp, err = sg.client.DescribeSecurityGroups(request)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 	if resp.Body.SecurityGroups == nil || resp.Body.SecurityGroups.SecurityGroup == nil {
-		return errors.New("bad response for query vSwitch")
+		return common.NewError(common.Upstream, "bad response for query security groups")
 	}
 	for _, b := range resp.Body.SecurityGroups.SecurityGroup {
 		s := network.NewSecurityGroupModel()
@@ -105,48 +127,42 @@ func (SecurityGroup)Call(params input.Params, replay *input.Replay) error {
 		s.ProviderId = utils.SafeString(b.SecurityGroupId)
 		s.CreateTime = utils.SafeString(b.CreationTime)
 		s.Description = utils.SafeString(b.Description)
-		s.Tags = getSGTags(b.Tags)
+		tags := getSGTags(b.Tags)
+		s.Tags = tags.String()
 		s.Extra = map[string]interface{}{
 			"ResourceGroupId": utils.SafeString(b.ResourceGroupId),
 			"EcsCount": utils.SafeInt32(b.EcsCount),
 			"VpcId": utils.SafeString(b.VpcId),
 			"SecurityGroupType": utils.SafeString(b.SecurityGroupType),
+			"Tags": tags.Map(),
 		}
 		s.SetIndex()
 		s.SetChecksum()
 		checked, key := s.CheckRequired()
 		if !checked {
-			return errors.New(
-				fmt.Sprintf("Value[%v] should not be empty", key))
+			return common.NewError(common.Upstream, "Value[%v] should not be empty", key)
 		}
 		nics = append(nics, s)
 	}
 	if !utils.CheckQueryKeys(query, network.SecurityGroupModel{}) {
-		return errors.New("query key is not attribute of SecurityGroupModel")
+		return common.NewError(common.Internal, "query key is not attribute of SecurityGroupModel")
 	}
-	total := utils.SafeInt32(resp.Body.TotalCount)
-	if pageNum * limit >= total {
-		next = ""
-	} else {
-		next = strconv.Itoa(int(pageNum + 1))
+	replay.Next, err = pager.Next(utils.SafeInt32(resp.Body.TotalCount), "")
+	if err != nil {
+		return err
 	}
-	replay.Next = next
 	replay.Query = query
 	replay.Result = nics
 	return nil
 }
 
-func getSGTags(tags *ecs20140526.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroupTags) string {
+func getSGTags(tags *ecs20140526.DescribeSecurityGroupsResponseBodySecurityGroupsSecurityGroupTags) common.Tags {
+	result := common.Tags{}
 	if tags == nil {
-		return ""
-	}
-	tgs := ""
-	l := len(tags.Tag)
-	for i, tg := range tags.Tag {
-		tgs += fmt.Sprintf("%v=%v", tg.TagKey, tg.TagValue)
-		if i + 1 < l {
-			tgs += ";"
-		}
+		return result
+	}
+	for _, tg := range tags.Tag {
+		result.Add(tg.TagKey, tg.TagValue)
 	}
-	return tgs
+	return result
 }
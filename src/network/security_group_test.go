@@ -0,0 +1,58 @@
+package network_test
+
+import (
+	"github.com/hahaps/common-provider/src/models/network"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"testing"
+)
+
+func securityGroup(id string) map[string]interface{} {
+	return map[string]interface{}{
+		"SecurityGroupId": id,
+		"SecurityGroupName": "sg-" + id,
+		"VpcId": "vpc-fake0001",
+	}
+}
+
+func TestSecurityGroup(t *testing.T) {
+	fakeapi.Start(t)
+	replay, err := fakeapi.Call("SecurityGroup", map[string]interface{}{"region": fakeapi.Region})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.Result) != 1 || replay.Next != "" {
+		t.Fatalf("got %v records and Next %q, want 1 record and no Next", len(replay.Result), replay.Next)
+	}
+	if sg := replay.Result[0].(*network.SecurityGroupModel); sg.ProviderId != "sg-fake0001" || sg.Name != "fake-sg" {
+		t.Errorf("unexpected security group %+v", sg)
+	}
+}
+
+func TestSecurityGroupPages(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Handle("DescribeSecurityGroups", fakeapi.Pages{
+		List: "SecurityGroups.SecurityGroup",
+		Items: fakeapi.Items(securityGroup("sg-1"), securityGroup("sg-2"), securityGroup("sg-3")),
+	}.Handler())
+	ids := walk(t, "SecurityGroup", map[string]interface{}{"region": fakeapi.Region, "limit": 2})
+	if !equal(ids, "sg-1", "sg-2", "sg-3") {
+		t.Errorf("security groups %v", ids)
+	}
+	requests := server.Requests("DescribeSecurityGroups")
+	if len(requests) != 2 || requests[1].Get("PageNumber") != "2" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
+
+func TestSecurityGroupFail(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Fail("DescribeSecurityGroups", 10, 400, "Throttling.User", "Request was denied due to user flow control.")
+	_, err := fakeapi.Call("SecurityGroup", map[string]interface{}{"region": fakeapi.Region, "retry_max_attempts": 2})
+	if common.ErrorCodeOf(err) != common.Throttled {
+		t.Errorf("got %v, want a Throttled error", err)
+	}
+	if n := len(server.Requests("DescribeSecurityGroups")); n != 2 {
+		t.Errorf("%v requests, want 2", n)
+	}
+}
@@ -1,16 +1,12 @@
 package network
 
 import (
-	"errors"
-	"fmt"
-	openapi "github.com/alibabacloud-go/darabonba-openapi/client"
-	ecs20140526 "github.com/alibabacloud-go/ecs-20140526/v2/client"
 	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hahaps/common-provider/src/common/model"
 	"github.com/hahaps/common-provider/src/common/utils"
 	"github.com/hahaps/common-provider/src/input"
 	"github.com/hahaps/common-provider/src/models/network"
 	"github.com/hahaps/input-provider-aliyun/src/common"
-	"strconv"
 )
 
 var SubnetSchemes = []utils.Scheme {
@@ -36,76 +32,96 @@ var SubnetSchemes = []utils.Scheme {
 		Type: utils.String,
 		Default: "1",
 	},
+	utils.Scheme{
+		Param: "tags",
+		Required: false,
+		Type: utils.Map,
+	},
+	utils.Scheme{
+		Param: "resource_group_id",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
 }
 
 type Subnet struct {
-	client *ecs20140526.Client
+	client *common.VpcApiClient
 	credential input.Credential
 	input.Resource
 }
 
-func (sub *Subnet)init(credential input.Credential) error {
-	config := &openapi.Config{
-		AccessKeyId: &credential.SecretId,
-		AccessKeySecret: &credential.SecretKey,
-	}
-	config.Endpoint = tea.String("ecs.aliyuncs.com")
-	cli, err := ecs20140526.NewClient(config)
-	if err != nil {
-		return err
-	}
-	sub.credential = credential
-	sub.client = cli
-	return nil
+// NewModel returns an empty model of the resource.
+func (Subnet)NewModel() model.BaseModel {
+	return network.NewSubnetModel()
 }
 
 func (Subnet)Call(params input.Params, replay *input.Replay) error {
 	sub := &Subnet{}
-	var next string
 	var err error
 	var subnets []interface{}
-	params.Args, err = utils.CheckParam(params.Args, NetworkSchemes)
+	common.NormalizeRegion(params.Args)
+	params.Args, err = utils.CheckParam(params.Args, SubnetSchemes)
+	if err != nil {
+		return common.ParamError(err)
+	}
+	tagFilters, err := common.TagFilters(params.Args)
+	if err != nil {
+		return err
+	}
+	invoker, err := common.NewInvoker(params)
 	if err != nil {
 		return err
 	}
-	err = sub.init(params.Credential)
+	defer invoker.Close()
+	regions, err := common.Regions(invoker, params.Credential, params.Args["region"].(string))
 	if err != nil {
 		return err
 	}
 	limit := int32(params.Args["limit"].(int))
-	next = params.Args["marker"].(string)
-	net := ""
-	if params.Args["network"] != nil {
-		net = params.Args["network"].(string)
+	pager, err := common.NewPaginator(common.PageNumberStyle, params.Args["marker"].(string), regions, limit)
+	if err != nil {
+		return err
 	}
-	region := params.Args["region"].(string)
-	if next == "" {
+	if pager.Done() {
 		return nil
 	}
-	number, err := strconv.ParseInt(next,10,32)
+	region := pager.Region()
+	sub.credential = params.Credential
+	sub.client, err = common.VpcClient(invoker, params.Credential, region)
 	if err != nil {
-		return errors.New("bad page number[marker] info")
+		return err
+	}
+	defer common.ReleaseClient(sub.client)
+	net := ""
+	if params.Args["network"] != nil {
+		net = params.Args["network"].(string)
 	}
-	pageNum := int32(number)
 	query := map[string]interface{} {
 		"RegionId": region,
 		"CloudType": common.CloudType,
 		"AccountId": sub.credential.AccountId,
 	}
-	request := &ecs20140526.DescribeVSwitchesRequest{
+	request := &common.DescribeVSwitchesRequest{
 		RegionId: &region,
-		PageSize: &limit,
-		PageNumber: &pageNum,
+		PageSize: tea.Int32(pager.PageSize()),
+		PageNumber: tea.Int32(pager.PageNumber()),
 	}
+	request.Tag = common.VpcTagFilters(tagFilters)
+	request.ResourceGroupId = common.OptionalString(params.Args["resource_group_id"].(string))
 	if net != "" {
 		request.VpcId = &net
 	}
-	resp, err := sub.client.DescribeVSwitches(request)
+	var resp *common.DescribeVSwitchesResponse
+	err = invoker.Invoke(common.ProductVpc, region, "DescribeVSwitches", func() (err error) {
+		resp, err = sub.client.DescribeVSwitches(request)
+		return err
+	})
 	if err != nil {
 		return err
 	}
 	if resp.Body.VSwitches == nil || resp.Body.VSwitches.VSwitch == nil {
-		return errors.New("bad response for query vSwitch")
+		return common.NewError(common.Upstream, "bad response for query vSwitch")
 	}
 	for _, b := range resp.Body.VSwitches.VSwitch {
 		s := network.NewSubnetModel()
@@ -120,29 +136,28 @@ func (Subnet)Call(params input.Params, replay *input.Replay) error {
 		s.CreateTime = utils.SafeString(b.CreationTime)
 		s.Description = utils.SafeString(b.Description)
 		s.NetworkId = utils.SafeString(b.VpcId)
+		tags := common.VpcTags(b.Tags)
+		s.Tags = tags.String()
 		s.Extra = map[string]interface{}{
 			"ZoneId": utils.SafeString(b.ZoneId),
 			"ResourceGroupId": utils.SafeString(b.ResourceGroupId),
+			"Tags": tags.Map(),
 		}
 		s.SetIndex()
 		s.SetChecksum()
 		checked, key := s.CheckRequired()
 		if !checked {
-			return errors.New(
-				fmt.Sprintf("Value[%v] should not be empty", key))
+			return common.NewError(common.Upstream, "Value[%v] should not be empty", key)
 		}
 		subnets = append(subnets, s)
 	}
-	if !utils.CheckQueryKeys(query, network.NetworkModel{}) {
-		return errors.New("query key is not attribute of SubnetModel")
+	if !utils.CheckQueryKeys(query, network.SubnetModel{}) {
+		return common.NewError(common.Internal, "query key is not attribute of SubnetModel")
 	}
-	total := utils.SafeInt32(resp.Body.TotalCount)
-	if pageNum * limit >= total {
-		next = ""
-	} else {
-		next = strconv.Itoa(int(pageNum + 1))
+	replay.Next, err = pager.Next(utils.SafeInt32(resp.Body.TotalCount), "")
+	if err != nil {
+		return err
 	}
-	replay.Next = next
 	replay.Query = query
 	replay.Result = subnets
 	return nil
@@ -0,0 +1,59 @@
+package network_test
+
+import (
+	"github.com/hahaps/common-provider/src/models/network"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"testing"
+)
+
+func vswitch(id string) map[string]interface{} {
+	return map[string]interface{}{
+		"VSwitchId": id,
+		"VSwitchName": "vswitch-" + id,
+		"VpcId": "vpc-fake0001",
+		"Status": "Available",
+		"CidrBlock": "172.16.0.0/24",
+	}
+}
+
+func TestSubnet(t *testing.T) {
+	server := fakeapi.Start(t)
+	replay, err := fakeapi.Call("Subnet", map[string]interface{}{"region": fakeapi.Region, "network": "vpc-fake0001"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.Result) != 1 || replay.Next != "" {
+		t.Fatalf("got %v records and Next %q, want 1 record and no Next", len(replay.Result), replay.Next)
+	}
+	subnet := replay.Result[0].(*network.SubnetModel)
+	if subnet.ProviderId != "vsw-fake0001" || subnet.NetworkId != "vpc-fake0001" || subnet.CIDR != "172.16.0.0/24" || subnet.Tags != "env=test" {
+		t.Errorf("unexpected subnet %+v", subnet)
+	}
+	if request := server.Requests("DescribeVSwitches")[0]; request.Get("VpcId") != "vpc-fake0001" {
+		t.Errorf("unexpected request %v", request)
+	}
+}
+
+func TestSubnetPages(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Handle("DescribeVSwitches", fakeapi.Pages{
+		List: "VSwitches.VSwitch",
+		Items: fakeapi.Items(vswitch("vsw-1"), vswitch("vsw-2"), vswitch("vsw-3")),
+	}.Handler())
+	ids := walk(t, "Subnet", map[string]interface{}{"region": fakeapi.Region, "limit": 2})
+	if !equal(ids, "vsw-1", "vsw-2", "vsw-3") {
+		t.Errorf("subnets %v", ids)
+	}
+	if n := len(server.Requests("DescribeVSwitches")); n != 2 {
+		t.Errorf("%v requests, want 2", n)
+	}
+}
+
+func TestSubnetFail(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Fail("DescribeVSwitches", 1, 400, "InvalidRegionId.NotFound", "The specified region does not exist.")
+	if _, err := fakeapi.Call("Subnet", map[string]interface{}{"region": fakeapi.Region}); common.ErrorCodeOf(err) != common.RegionUnavailable {
+		t.Errorf("got %v, want a RegionUnavailable error", err)
+	}
+}
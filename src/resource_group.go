@@ -0,0 +1,125 @@
+package src
+
+import (
+	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hahaps/common-provider/src/common/model"
+	"github.com/hahaps/common-provider/src/common/utils"
+	"github.com/hahaps/common-provider/src/input"
+	"github.com/hahaps/common-provider/src/models"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+)
+
+const ResourceGroupProductType string = "ResourceGroup"
+
+var ResourceGroupSchemes = []utils.Scheme {
+	utils.Scheme{
+		Param: "status",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
+	utils.Scheme{
+		Param: "limit",
+		Required: false,
+		Type: utils.Int,
+		Default: utils.DefaultLimit,
+	},
+	utils.Scheme{
+		Param: "marker",
+		Required: false,
+		Type: utils.String,
+		Default: "1",
+	},
+}
+
+type ResourceGroup struct {
+	client *common.ResourceManagerApiClient
+	credential input.Credential
+	input.Resource
+}
+
+// NewModel returns an empty model of the resource.
+func (ResourceGroup)NewModel() model.BaseModel {
+	return models.NewResourceModel()
+}
+
+func (ResourceGroup)Call(params input.Params, replay *input.Replay) error {
+	group := &ResourceGroup{}
+	var err error
+	var groups []interface{}
+	params.Args, err = utils.CheckParam(params.Args, ResourceGroupSchemes)
+	if err != nil {
+		return common.ParamError(err)
+	}
+	invoker, err := common.NewInvoker(params)
+	if err != nil {
+		return err
+	}
+	defer invoker.Close()
+	limit := int32(params.Args["limit"].(int))
+	pager, err := common.NewPaginator(common.PageNumberStyle, params.Args["marker"].(string), nil, limit)
+	if err != nil {
+		return err
+	}
+	if pager.Done() {
+		return nil
+	}
+	group.credential = params.Credential
+	group.client, err = common.ResourceManagerClient(invoker, params.Credential)
+	if err != nil {
+		return err
+	}
+	defer common.ReleaseClient(group.client)
+	query := map[string]interface{} {
+		"CloudType": common.CloudType,
+		"ProductType": ResourceGroupProductType,
+	}
+	request := &common.ListResourceGroupsRequest{
+		PageSize: tea.Int32(pager.PageSize()),
+		PageNumber: tea.Int32(pager.PageNumber()),
+		Status: common.OptionalString(params.Args["status"].(string)),
+	}
+	var resp *common.ListResourceGroupsResponse
+	err = invoker.Invoke(common.ProductResourceManager, "", "ListResourceGroups", func() (err error) {
+		resp, err = group.client.ListResourceGroups(request)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if resp.Body.ResourceGroups == nil || resp.Body.ResourceGroups.ResourceGroup == nil {
+		return common.NewError(common.Upstream, "bad response for query resource groups")
+	}
+	for _, b := range resp.Body.ResourceGroups.ResourceGroup {
+		rg := models.NewResourceModel()
+		rg.Deleted = 0
+		rg.CloudType = common.CloudType
+		rg.ProviderId = utils.SafeString(b.Id)
+		rg.Name = utils.SafeString(b.Name)
+		rg.ProductType = ResourceGroupProductType
+		rg.ProductCode = common.ProductResourceManager
+		rg.CreateTime = utils.SafeString(b.CreateDate)
+		rg.Extra = map[string]interface{}{
+			"AccountId": utils.SafeString(b.AccountId),
+			"DisplayName": utils.SafeString(b.DisplayName),
+			"Status": utils.SafeString(b.Status),
+		}
+		rg.SetIndex()
+		rg.SetChecksum()
+		checked, key := rg.CheckRequired()
+		if !checked {
+			return common.NewError(common.Upstream, "Value[%v] should not be empty", key)
+		}
+		groups = append(groups, rg)
+	}
+	if !utils.CheckQueryKeys(query, models.ResourceModel{}) {
+		return common.NewError(common.Internal, "query key is not attribute of ResourceModel")
+	}
+	replay.Next, err = pager.Next(utils.SafeInt32(resp.Body.TotalCount), "")
+	if err != nil {
+		return err
+	}
+	replay.Query = query
+	replay.Result = groups
+	return nil
+}
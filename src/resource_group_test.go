@@ -0,0 +1,108 @@
+package src_test
+
+import (
+	"github.com/hahaps/common-provider/src/models"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"testing"
+)
+
+func group(id, status string) map[string]interface{} {
+	return map[string]interface{}{
+		"Id": id,
+		"Name": "group-" + id,
+		"DisplayName": "Group " + id,
+		"Status": status,
+		"AccountId": fakeapi.AccountId,
+		"CreateDate": "2021-01-01T00:00:00+08:00",
+	}
+}
+
+func TestResourceGroup(t *testing.T) {
+	fakeapi.Start(t)
+	replay, err := fakeapi.Call("ResourceGroup", map[string]interface{}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.Result) != 1 || replay.Next != "" {
+		t.Fatalf("got %v records and Next %q, want 1 record and no Next", len(replay.Result), replay.Next)
+	}
+	rg := replay.Result[0].(*models.ResourceModel)
+	if rg.ProviderId != fakeapi.ResourceGroupId || rg.Name != "fake-group" || rg.ProductCode != common.ProductResourceManager || rg.Extra["Status"] != "OK" {
+		t.Errorf("unexpected resource group %+v", rg)
+	}
+	if replay.Query["ProductType"] != "ResourceGroup" || replay.Query["CloudType"] != common.CloudType {
+		t.Errorf("unexpected query %v", replay.Query)
+	}
+}
+
+func TestResourceGroupPages(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Handle("ListResourceGroups", fakeapi.Pages{
+		List: "ResourceGroups.ResourceGroup",
+		Items: fakeapi.Items(group("rg-1", "OK"), group("rg-2", "OK"), group("rg-3", "Deleting")),
+	}.Handler())
+	var ids []string
+	args := map[string]interface{}{"limit": 2, "status": "OK", "marker": "1"}
+	for calls := 0; args["marker"] != ""; calls++ {
+		if calls == 10 {
+			t.Fatal("pagination of ResourceGroup does not end")
+		}
+		replay, err := fakeapi.Call("ResourceGroup", args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, record := range replay.Result {
+			ids = append(ids, record.(*models.ResourceModel).ProviderId)
+		}
+		args["marker"] = replay.Next
+	}
+	if len(ids) != 3 || ids[0] != "rg-1" || ids[2] != "rg-3" {
+		t.Errorf("resource groups %v", ids)
+	}
+	requests := server.Requests("ListResourceGroups")
+	if len(requests) != 2 || requests[1].Get("PageNumber") != "2" || requests[1].Get("PageSize") != "2" || requests[1].Get("Status") != "OK" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
+
+func TestResourceGroupFail(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Fail("ListResourceGroups", 1, 403, "NoPermission", "You are not authorized to do this action.")
+	if _, err := fakeapi.Call("ResourceGroup", map[string]interface{}{}); common.ErrorCodeOf(err) != common.Forbidden {
+		t.Errorf("got %v, want a Forbidden error", err)
+	}
+}
+
+// Every inventory resource lists the resources of the resource group only.
+func TestResourceGroupFilter(t *testing.T) {
+	server := fakeapi.Start(t)
+	actions := map[string]string{
+		"Server": "DescribeInstances",
+		"Image": "DescribeImages",
+		"Disk": "DescribeDisks",
+		"Nic": "DescribeNetworkInterfaces",
+		"SecurityGroup": "DescribeSecurityGroups",
+		"Network": "DescribeVpcs",
+		"Subnet": "DescribeVSwitches",
+		"FloatingIp": "DescribeEipAddresses",
+	}
+	for resource, action := range actions {
+		for groupId, want := range map[string]int{fakeapi.ResourceGroupId: 1, "rg-other": 0} {
+			replay, err := fakeapi.Call(resource, map[string]interface{}{
+				"region": fakeapi.Region,
+				"resource_group_id": groupId,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(replay.Result) != want {
+				t.Errorf("%v of %v got %v records, want %v", resource, groupId, len(replay.Result), want)
+			}
+			requests := server.Requests(action)
+			if got := requests[len(requests) - 1].Get("ResourceGroupId"); got != groupId {
+				t.Errorf("%v sent ResourceGroupId %q, want %q", action, got, groupId)
+			}
+		}
+	}
+}
@@ -1,22 +1,50 @@
 package src
 
 import (
+	"github.com/hahaps/common-provider/src/common/utils"
 	"github.com/hahaps/common-provider/src/input"
 	"github.com/hahaps/input-provider-aliyun/src/compute"
 	"github.com/hahaps/input-provider-aliyun/src/network"
+	"github.com/hahaps/input-provider-aliyun/src/state"
 	"github.com/hahaps/input-provider-aliyun/src/storage"
 )
 
 var ResourceMap = map[string]input.Resource {
-	"Server": &compute.Server{},
+	"Server": state.Track("Server", &compute.Server{}),
 	"ServerMetric": &compute.ServerMetric{},
-	"Image": &compute.Image{},
-	"Disk": &storage.Disk{},
-	"Network": &network.Network{},
-	"Subnet": &network.Subnet{},
-	"Nic": &network.Nic{},
-	"SecurityGroup": &network.SecurityGroup{},
-	"FloatingIp": &network.FloatingIp{},
+	"Image": state.Track("Image", &compute.Image{}),
+	"Disk": state.Track("Disk", &storage.Disk{}),
+	"Network": state.Track("Network", &network.Network{}),
+	"Subnet": state.Track("Subnet", &network.Subnet{}),
+	"Nic": state.Track("Nic", &network.Nic{}),
+	"SecurityGroup": state.Track("SecurityGroup", &network.SecurityGroup{}),
+	"FloatingIp": state.Track("FloatingIp", &network.FloatingIp{}),
 	"FloatingIpMetric": &network.FloatingIpMetric{},
-	"InstanceBill": &InstanceBill{},
+	"InstanceBill": state.Track("InstanceBill", &InstanceBill{}),
+	"ResourceGroup": state.Track("ResourceGroup", &ResourceGroup{}),
+	"Schema": &Schema{},
+	"CredentialCheck": &CredentialCheck{},
+	"MetricCatalog": &MetricCatalog{},
+	"Metric": &Metric{},
+}
+
+// Params of each resource of ResourceMap, besides the common.InvokerSchemes
+// and, for the tracked ones, the state.StateSchemes, see Schemes
+var SchemeMap = map[string][]utils.Scheme {
+	"Server": compute.ServerSchemes,
+	"ServerMetric": compute.ServerMetricSchemes,
+	"Image": compute.ImageSchemes,
+	"Disk": storage.DiskSchemes,
+	"Network": network.NetworkSchemes,
+	"Subnet": network.SubnetSchemes,
+	"Nic": network.NicSchemes,
+	"SecurityGroup": network.SecurityGroupSchemes,
+	"FloatingIp": network.FloatingIpSchemes,
+	"FloatingIpMetric": network.FloatingIpMetricSchemes,
+	"InstanceBill": InstanceBillSchemes,
+	"ResourceGroup": ResourceGroupSchemes,
+	"Schema": SchemaSchemes,
+	"CredentialCheck": CredentialCheckSchemes,
+	"MetricCatalog": MetricCatalogSchemes,
+	"Metric": MetricSchemes,
 }
@@ -0,0 +1,150 @@
+package src
+
+import (
+	"fmt"
+	"github.com/hahaps/common-provider/src/common/model"
+	"github.com/hahaps/common-provider/src/common/utils"
+	"github.com/hahaps/common-provider/src/input"
+	"github.com/hahaps/common-provider/src/models"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/state"
+	"reflect"
+	"sort"
+)
+
+const JSONSchemaDraft string = "http://json-schema.org/draft-07/schema#"
+
+var SchemaSchemes = []utils.Scheme {
+	utils.Scheme{
+		Param: "resource",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
+}
+
+// Models emitted by the resources unable to create their empty model
+var modelMap = map[string]func() model.BaseModel {
+	"ServerMetric": func() model.BaseModel { return models.NewMetricModel() },
+	"FloatingIpMetric": func() model.BaseModel { return models.NewMetricModel() },
+	"Metric": func() model.BaseModel { return models.NewMetricModel() },
+	"InstanceBill": func() model.BaseModel { return models.NewInstanceBillModel() },
+}
+
+var jsonTypes = map[reflect.Kind]string {
+	utils.Int: "integer",
+	utils.Float64: "number",
+	utils.String: "string",
+	utils.Bool: "boolean",
+	utils.Array: "array",
+	utils.Slice: "array",
+	utils.Map: "object",
+}
+
+// Schema describes the params of the resources, so a caller can validate
+// its args before calling them. Its result has a JSON Schema per resource,
+// or only the one of the "resource" param.
+type Schema struct {
+	input.Resource
+}
+
+func (Schema)Call(params input.Params, replay *input.Replay) error {
+	var err error
+	params.Args, err = utils.CheckParam(params.Args, SchemaSchemes)
+	if err != nil {
+		return common.ParamError(err)
+	}
+	names := []string{params.Args["resource"].(string)}
+	if names[0] == "" {
+		names = ResourceNames()
+	}
+	var schemas []interface{}
+	for _, name := range names {
+		schema, err := JSONSchema(name)
+		if err != nil {
+			return common.NewError(common.InvalidParam, "%v", err)
+		}
+		schemas = append(schemas, schema)
+	}
+	replay.Result = schemas
+	return nil
+}
+
+// ResourceNames returns the names of ResourceMap, sorted.
+func ResourceNames() []string {
+	var names []string
+	for name := range ResourceMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Schemes returns every param accepted by the resource name.
+func Schemes(name string) ([]utils.Scheme, error) {
+	schemes, ok := SchemeMap[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown resource %v", name)
+	}
+	if name == "Schema" {
+		return schemes, nil
+	}
+	schemes = append(append([]utils.Scheme{}, schemes...), common.InvokerSchemes...)
+	if tracked, ok := ResourceMap[name].(*state.TrackedResource); ok {
+		schemes = append(schemes, tracked.Schemes()...)
+	}
+	return schemes, nil
+}
+
+// ModelType returns the type of the models emitted by the resource name,
+// e.g. "compute.ServerModel", or "" when it emits no model.
+func ModelType(name string) string {
+	resource := ResourceMap[name]
+	if tracked, ok := resource.(*state.TrackedResource); ok {
+		resource = tracked.Resource
+	}
+	var record model.BaseModel
+	if res, ok := resource.(state.ModelResource); ok {
+		record = res.NewModel()
+	} else if newModel, ok := modelMap[name]; ok {
+		record = newModel()
+	} else {
+		return ""
+	}
+	return reflect.Indirect(reflect.ValueOf(record)).Type().String()
+}
+
+// JSONSchema returns the JSON Schema of the args of the resource name. The
+// args besides its params are passed through, and the type of its models
+// is set to "x-model".
+func JSONSchema(name string) (map[string]interface{}, error) {
+	schemes, err := Schemes(name)
+	if err != nil {
+		return nil, err
+	}
+	properties := map[string]interface{}{}
+	required := []string{}
+	for _, scheme := range schemes {
+		property := map[string]interface{} {
+			"type": jsonTypes[scheme.Type],
+		}
+		if scheme.Required {
+			required = append(required, scheme.Param)
+		} else if scheme.Default != nil {
+			property["default"] = scheme.Default
+		}
+		properties[scheme.Param] = property
+	}
+	schema := map[string]interface{} {
+		"$schema": JSONSchemaDraft,
+		"title": name,
+		"type": "object",
+		"properties": properties,
+		"required": required,
+		"additionalProperties": true,
+	}
+	if modelType := ModelType(name); modelType != "" {
+		schema["x-model"] = modelType
+	}
+	return schema, nil
+}
@@ -0,0 +1,46 @@
+package src_test
+
+import (
+	"github.com/hahaps/input-provider-aliyun/src"
+	"testing"
+)
+
+func params(t *testing.T, name string) map[string]bool {
+	schemes, err := src.Schemes(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := map[string]bool{}
+	for _, scheme := range schemes {
+		if result[scheme.Param] {
+			t.Errorf("param %v of %v declared twice", scheme.Param, name)
+		}
+		result[scheme.Param] = true
+	}
+	return result
+}
+
+func TestSchemes(t *testing.T) {
+	want := map[string][]string{
+		"ServerMetric": {"region", "metric_names", "limit", "marker", "tags", "resource_group_id", "cassette"},
+		"FloatingIpMetric": {"region", "metric_names", "limit", "marker", "tags", "resource_group_id"},
+		"Subnet": {"region", "network", "limit", "marker", "tags", "resource_group_id"},
+		"Server": {"tags", "resource_group_id", "incremental", "tombstones", "state_file"},
+		"InstanceBill": {"incremental", "state_file"},
+		"Metric": {"namespace", "inventory", "metric_names", "limit", "marker", "tags"},
+	}
+	for name, names := range want {
+		declared := params(t, name)
+		for _, param := range names {
+			if !declared[param] {
+				t.Errorf("param %v of %v missing", param, name)
+			}
+		}
+	}
+	if declared := params(t, "InstanceBill"); declared["tombstones"] {
+		t.Error("InstanceBill declares tombstones")
+	}
+	for _, name := range src.ResourceNames() {
+		params(t, name)
+	}
+}
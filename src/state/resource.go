@@ -0,0 +1,205 @@
+package state
+
+import (
+	"crypto/md5"
+	"fmt"
+	"github.com/hahaps/common-provider/src/common/model"
+	"github.com/hahaps/common-provider/src/common/utils"
+	"github.com/hahaps/common-provider/src/input"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// Env var of the default "state_file" param
+const EnvStateFile = "ALIBABA_CLOUD_STATE_FILE"
+
+// Optional params of the resources wrapped by Track
+var StateSchemes = []utils.Scheme {
+	utils.Scheme{
+		Param: "incremental",
+		Required: false,
+		Type: utils.Bool,
+		Default: false,
+	},
+	utils.Scheme{
+		Param: "tombstones",
+		Required: false,
+		Type: utils.Bool,
+		Default: false,
+	},
+	utils.Scheme{
+		Param: "state_file",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
+}
+
+// ModelResource is a resource able to create its empty model, for the
+// tombstones of its records.
+type ModelResource interface {
+	input.Resource
+	NewModel() model.BaseModel
+}
+
+// TrackedResource keeps the state of the records of a resource between
+// runs. With the "incremental" param set, it emits only the records whose
+// checksum changed since they were last emitted. With "tombstones" set, it
+// emits a Deleted record for each record vanished since the last complete
+// run, only supported when the resource is a ModelResource.
+type TrackedResource struct {
+	Name string
+	Resource input.Resource
+}
+
+func Track(name string, resource input.Resource) *TrackedResource {
+	return &TrackedResource{Name: name, Resource: resource}
+}
+
+// Schemes returns the StateSchemes supported by the wrapped resource.
+func (res *TrackedResource)Schemes() []utils.Scheme {
+	if _, ok := res.Resource.(ModelResource); ok {
+		return StateSchemes
+	}
+	var schemes []utils.Scheme
+	for _, scheme := range StateSchemes {
+		if scheme.Param != "tombstones" {
+			schemes = append(schemes, scheme)
+		}
+	}
+	return schemes
+}
+
+// Call runs the wrapped resource, then drops its unchanged records and
+// appends the tombstones. The counts of changed and unchanged records are
+// set to the "Incremental" key of replay.Query, the count of tombstones to
+// the "Tombstones" key.
+func (res *TrackedResource)Call(params input.Params, replay *input.Replay) error {
+	args := map[string]interface{}{}
+	for _, scheme := range StateSchemes {
+		if val, ok := params.Args[scheme.Param]; ok {
+			args[scheme.Param] = val
+		}
+	}
+	args, err := utils.CheckParam(args, StateSchemes)
+	if err != nil {
+		return common.ParamError(err)
+	}
+	incremental := args["incremental"].(bool)
+	tombstones := args["tombstones"].(bool)
+	if _, ok := res.Resource.(ModelResource); tombstones && !ok {
+		return common.NewError(common.InvalidParam, "tombstones are not supported by %v", res.Name)
+	}
+	marker, ok := params.Args["marker"].(string)
+	if !ok {
+		// Default marker of the inventory resources
+		marker = "1"
+	}
+	filter := filterOf(params.Args)
+	if err = res.Resource.Call(params, replay); err != nil || !(incremental || tombstones) {
+		return err
+	}
+	if replay.Query == nil {
+		// The marker was the end of the pagination
+		return nil
+	}
+	path, err := statePath(args["state_file"].(string))
+	if err != nil {
+		return err
+	}
+	store, err := OpenStore(path)
+	if err != nil {
+		return common.NewError(common.InvalidParam, "bad state file %v: %v", path, err)
+	}
+	var deleted []interface{}
+	if tombstones {
+		if deleted, err = res.track(store, params, replay, marker, filter); err != nil {
+			return err
+		}
+	}
+	if incremental {
+		if err = res.filterUnchanged(store, params, replay); err != nil {
+			return err
+		}
+	}
+	if tombstones {
+		replay.Query["Tombstones"] = len(deleted)
+		replay.Result = append(replay.Result, deleted...)
+	}
+	return nil
+}
+
+func (res *TrackedResource)filterUnchanged(store Store, params input.Params, replay *input.Replay) error {
+	var result []interface{}
+	previous := map[Scope]map[string]string{}
+	changed := map[Scope]map[string]string{}
+	unchanged := 0
+	for _, record := range replay.Result {
+		scope, id, checksum := res.identify(params, replay, record)
+		if _, ok := previous[scope]; !ok {
+			var err error
+			if previous[scope], err = store.Checksums(scope); err != nil {
+				return common.NewError(common.Internal, "read state of %v: %v", scope, err)
+			}
+			changed[scope] = map[string]string{}
+		}
+		if previous[scope][id] == checksum {
+			unchanged++
+			continue
+		}
+		changed[scope][id] = checksum
+		result = append(result, record)
+	}
+	for scope, checksums := range changed {
+		if err := store.Update(scope, checksums); err != nil {
+			return common.NewError(common.Internal, "write state of %v: %v", scope, err)
+		}
+	}
+	replay.Query["Incremental"] = map[string]interface{}{
+		"Changed": len(result),
+		"Unchanged": unchanged,
+	}
+	replay.Result = result
+	return nil
+}
+
+// identify returns the scope, ProviderId and checksum digest of record.
+// Records without ProviderId are identified by their index, and those
+// without RegionId by the RegionId of the query.
+func (res *TrackedResource)identify(params input.Params, replay *input.Replay, record interface{}) (Scope, string, string) {
+	scope := Scope{AccountId: params.Credential.AccountId, Resource: res.Name}
+	scope.RegionId, _ = replay.Query["RegionId"].(string)
+	var id, checksum string
+	if base, ok := record.(model.BaseModel); ok {
+		id = base.GetIndex()
+		checksum = fmt.Sprintf("%x", md5.Sum([]byte(base.GetChecksum())))
+	}
+	val := reflect.Indirect(reflect.ValueOf(record))
+	if val.Kind() == reflect.Struct {
+		if field := val.FieldByName("ProviderId"); field.Kind() == reflect.String && field.String() != "" {
+			id = field.String()
+		}
+		if field := val.FieldByName("RegionId"); field.Kind() == reflect.String && field.String() != "" {
+			scope.RegionId = field.String()
+		}
+	}
+	return scope, id, checksum
+}
+
+// statePath returns path, or the path of EnvStateFile, or the state file
+// in the user cache dir.
+func statePath(path string) (string, error) {
+	if path == "" {
+		path = os.Getenv(EnvStateFile)
+	}
+	if path != "" {
+		return path, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", common.NewError(common.InvalidParam, "state_file is required: %v", err)
+	}
+	return filepath.Join(dir, "provider-aliyun", "state.json"), nil
+}
@@ -0,0 +1,226 @@
+package state
+
+import (
+	"encoding/json"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Scope groups the records of one resource in one region of an account.
+// Filter tells apart the runs listing a subset of the records.
+type Scope struct {
+	AccountId string
+	RegionId string
+	Resource string
+	Filter string
+}
+
+func (scope Scope)String() string {
+	key := strings.Join([]string{scope.AccountId, scope.RegionId, scope.Resource}, "/")
+	if scope.Filter != "" {
+		key += "?" + scope.Filter
+	}
+	return key
+}
+
+// Run is a pagination run of a scope in progress.
+type Run struct {
+	// Marker expected for the next page
+	Next string `json:"next"`
+	// ProviderIds of the pages already listed
+	Seen []string `json:"seen"`
+	// Limit of the pages, page number markers of another limit do not
+	// follow the run
+	Limit string `json:"limit,omitempty"`
+}
+
+// Store keeps the checksum of every record emitted, keyed by scope and
+// ProviderId, and the members of the scopes listed by complete runs.
+type Store interface {
+	// Checksums returns the checksums of scope keyed by ProviderId.
+	Checksums(scope Scope) (map[string]string, error)
+	// Update sets the checksums of the given ProviderIds of scope, an
+	// empty checksum removes the ProviderId.
+	Update(scope Scope, checksums map[string]string) error
+	// Run returns the run of scope in progress, nil if none.
+	Run(scope Scope) (*Run, error)
+	// SetRun saves the run of scope in progress, nil drops it.
+	SetRun(scope Scope, run *Run) error
+	// Members returns the ProviderIds listed by the last complete run of
+	// scope, nil before the first one.
+	Members(scope Scope) ([]string, error)
+	// SetMembers records the ProviderIds listed by a complete run of scope.
+	SetMembers(scope Scope, members []string) error
+}
+
+// StoreOpener opens the store at path.
+type StoreOpener func(path string) (Store, error)
+
+var storeOpener StoreOpener = OpenFileStore
+
+var stores = map[string]Store{}
+
+var storeLock = sync.Mutex{}
+
+// SetStoreOpener replaces the opener of the stores and drops the opened
+// ones. The previous opener is returned to be restored afterwards.
+func SetStoreOpener(opener StoreOpener) StoreOpener {
+	storeLock.Lock()
+	defer storeLock.Unlock()
+	previous := storeOpener
+	storeOpener = opener
+	stores = map[string]Store{}
+	return previous
+}
+
+// OpenStore returns the store at path, opened once per process.
+func OpenStore(path string) (Store, error) {
+	storeLock.Lock()
+	defer storeLock.Unlock()
+	if store, ok := stores[path]; ok {
+		return store, nil
+	}
+	store, err := storeOpener(path)
+	if err != nil {
+		return nil, err
+	}
+	stores[path] = store
+	return store, nil
+}
+
+type fileState struct {
+	Scopes map[string]map[string]string `json:"scopes"`
+	Runs map[string]*Run `json:"runs,omitempty"`
+	Members map[string][]string `json:"members,omitempty"`
+}
+
+// FileStore is a Store persisted as a JSON file shared by the provider
+// processes. Reads load the file, updates re-read it under its lock before
+// replacing it, so concurrent runs never drop each other's changes.
+type FileStore struct {
+	Path string
+}
+
+func OpenFileStore(path string) (Store, error) {
+	store := &FileStore{Path: path}
+	if _, err := store.load(); err != nil {
+		return nil, err
+	}
+	return store, nil
+}
+
+func (store *FileStore)Checksums(scope Scope) (map[string]string, error) {
+	state, err := store.load()
+	if err != nil {
+		return nil, err
+	}
+	checksums := map[string]string{}
+	for id, checksum := range state.Scopes[scope.String()] {
+		checksums[id] = checksum
+	}
+	return checksums, nil
+}
+
+func (store *FileStore)Update(scope Scope, checksums map[string]string) error {
+	return store.update(func(state *fileState) {
+		current, ok := state.Scopes[scope.String()]
+		if !ok {
+			current = map[string]string{}
+			state.Scopes[scope.String()] = current
+		}
+		for id, checksum := range checksums {
+			if checksum == "" {
+				delete(current, id)
+			} else {
+				current[id] = checksum
+			}
+		}
+	})
+}
+
+func (store *FileStore)Run(scope Scope) (*Run, error) {
+	state, err := store.load()
+	if err != nil {
+		return nil, err
+	}
+	run, ok := state.Runs[scope.String()]
+	if !ok {
+		return nil, nil
+	}
+	return &Run{Next: run.Next, Seen: append([]string{}, run.Seen...), Limit: run.Limit}, nil
+}
+
+func (store *FileStore)SetRun(scope Scope, run *Run) error {
+	return store.update(func(state *fileState) {
+		if run == nil {
+			delete(state.Runs, scope.String())
+		} else {
+			state.Runs[scope.String()] = run
+		}
+	})
+}
+
+func (store *FileStore)Members(scope Scope) ([]string, error) {
+	state, err := store.load()
+	if err != nil {
+		return nil, err
+	}
+	members, ok := state.Members[scope.String()]
+	if !ok {
+		return nil, nil
+	}
+	return append([]string{}, members...), nil
+}
+
+func (store *FileStore)SetMembers(scope Scope, members []string) error {
+	return store.update(func(state *fileState) {
+		state.Members[scope.String()] = append([]string{}, members...)
+	})
+}
+
+// load reads the state of the file, empty when it does not exist yet.
+func (store *FileStore)load() (*fileState, error) {
+	state := &fileState{}
+	content, err := ioutil.ReadFile(store.Path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err = json.Unmarshal(content, state); err != nil {
+			return nil, err
+		}
+	}
+	if state.Scopes == nil {
+		state.Scopes = map[string]map[string]string{}
+	}
+	if state.Runs == nil {
+		state.Runs = map[string]*Run{}
+	}
+	if state.Members == nil {
+		state.Members = map[string][]string{}
+	}
+	return state, nil
+}
+
+// update applies change to the state read under the lock of the file,
+// then replaces the file atomically.
+func (store *FileStore)update(change func(state *fileState)) error {
+	unlock, err := common.LockFile(store.Path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	state, err := store.load()
+	if err != nil {
+		return err
+	}
+	change(state)
+	content, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return common.WriteFileAtomic(store.Path, content)
+}
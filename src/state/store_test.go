@@ -0,0 +1,105 @@
+package state
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func openStore(t *testing.T, path string) Store {
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "state.json")
+	scope := Scope{AccountId: "1", RegionId: "cn-hangzhou", Resource: "Server"}
+	store := openStore(t, path)
+	if err := store.Update(scope, map[string]string{"i-1": "a", "i-2": "b"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Update(scope, map[string]string{"i-2": ""}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetRun(scope, &Run{Next: "2", Seen: []string{"i-1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetMembers(scope, []string{"i-1", "i-3"}); err != nil {
+		t.Fatal(err)
+	}
+	reopened := openStore(t, path)
+	if checksums, _ := reopened.Checksums(scope); !reflect.DeepEqual(checksums, map[string]string{"i-1": "a"}) {
+		t.Errorf("checksums %v", checksums)
+	}
+	if run, _ := reopened.Run(scope); run == nil || run.Next != "2" || !reflect.DeepEqual(run.Seen, []string{"i-1"}) {
+		t.Errorf("run %+v", run)
+	}
+	if members, _ := reopened.Members(scope); !reflect.DeepEqual(members, []string{"i-1", "i-3"}) {
+		t.Errorf("members %v", members)
+	}
+	if err := store.SetRun(scope, nil); err != nil {
+		t.Fatal(err)
+	}
+	if run, _ := reopened.Run(scope); run != nil {
+		t.Errorf("dropped run %+v still read", run)
+	}
+	other := Scope{AccountId: "1", RegionId: "cn-beijing", Resource: "Server"}
+	if members, _ := reopened.Members(other); members != nil {
+		t.Errorf("members %v before the first run", members)
+	}
+}
+
+// Stores of several processes on the same file keep each other's updates.
+func TestFileStoreConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	stores := []Store{openStore(t, path), openStore(t, path)}
+	wg := sync.WaitGroup{}
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			store := stores[w % 2]
+			scope := Scope{AccountId: "1", RegionId: "cn-hangzhou", Resource: fmt.Sprint("Resource", w)}
+			for i := 0; i < 20; i++ {
+				id := fmt.Sprint("id-", i)
+				if err := store.Update(scope, map[string]string{id: id}); err != nil {
+					t.Error(err)
+				}
+				if err := store.SetMembers(scope, []string{id}); err != nil {
+					t.Error(err)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	store := openStore(t, path)
+	for w := 0; w < 4; w++ {
+		scope := Scope{AccountId: "1", RegionId: "cn-hangzhou", Resource: fmt.Sprint("Resource", w)}
+		if checksums, _ := store.Checksums(scope); len(checksums) != 20 {
+			t.Errorf("%v keeps %v checksums, want 20", scope, len(checksums))
+		}
+		if members, _ := store.Members(scope); !reflect.DeepEqual(members, []string{"id-19"}) {
+			t.Errorf("%v members %v", scope, members)
+		}
+	}
+	files, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "*.tmp"))
+	if len(files) != 0 {
+		t.Errorf("temporary files left %v", files)
+	}
+}
+
+func TestOpenFileStoreCorrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	if err := ioutil.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenFileStore(path); err == nil {
+		t.Error("a corrupt state file should fail")
+	}
+}
@@ -0,0 +1,146 @@
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/hahaps/common-provider/src/input"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"reflect"
+)
+
+// Params not narrowing the records listed by a run
+var unfilteredParams = map[string]bool{
+	"region": true,
+	"limit": true,
+	"marker": true,
+}
+
+// filterOf returns the params narrowing the records listed by a run, as a
+// JSON object with sorted keys.
+func filterOf(args map[string]interface{}) string {
+	filter := map[string]interface{}{}
+	for key, value := range args {
+		if unfilteredParams[key] || isScheme(key) {
+			continue
+		}
+		if val := reflect.ValueOf(value); !val.IsValid() || val.IsZero() ||
+			((val.Kind() == reflect.Map || val.Kind() == reflect.Slice) && val.Len() == 0) {
+			continue
+		}
+		filter[key] = value
+	}
+	if len(filter) == 0 {
+		return ""
+	}
+	content, _ := json.Marshal(filter)
+	return string(content)
+}
+
+func isScheme(param string) bool {
+	for _, scheme := range common.InvokerSchemes {
+		if scheme.Param == param {
+			return true
+		}
+	}
+	for _, scheme := range StateSchemes {
+		if scheme.Param == param {
+			return true
+		}
+	}
+	return false
+}
+
+// track adds the records of the page to the run of its region. A run
+// starts on the first page of the region and goes on while every page is
+// the one following the previous. On the last page of a run, the
+// ProviderIds listed by the previous complete run and not by this one are
+// returned as tombstones. A run broken by a failure, a skipped page or a
+// page of another limit never produces tombstones.
+func (res *TrackedResource)track(store Store, params input.Params, replay *input.Replay, marker, filter string) ([]interface{}, error) {
+	region, _ := replay.Query["RegionId"].(string)
+	scope := Scope{
+		AccountId: params.Credential.AccountId,
+		RegionId: region,
+		Resource: res.Name,
+		Filter: filter,
+	}
+	limit := fmt.Sprint(params.Args["limit"])
+	run, err := store.Run(scope)
+	if err != nil {
+		return nil, common.NewError(common.Internal, "read state of %v: %v", scope, err)
+	}
+	if _, first := common.MarkerPosition(marker); first {
+		run = &Run{Seen: []string{}, Limit: limit}
+	} else if run == nil || run.Next != marker || run.Limit != limit {
+		// Not the page following the previous one of the run
+		if run != nil {
+			err = store.SetRun(scope, nil)
+		}
+		return nil, err
+	}
+	for _, record := range replay.Result {
+		_, id, _ := res.identify(params, replay, record)
+		run.Seen = append(run.Seen, id)
+	}
+	nextRegion, nextFirst := common.MarkerPosition(replay.Next)
+	if replay.Next != "" && !(nextFirst && nextRegion != region) {
+		run.Next = replay.Next
+		if err = store.SetRun(scope, run); err != nil {
+			return nil, common.NewError(common.Internal, "write state of %v: %v", scope, err)
+		}
+		return nil, nil
+	}
+	previous, err := store.Members(scope)
+	if err != nil {
+		return nil, common.NewError(common.Internal, "read state of %v: %v", scope, err)
+	}
+	seen := map[string]bool{}
+	for _, id := range run.Seen {
+		seen[id] = true
+	}
+	var tombstones []interface{}
+	forgotten := map[string]string{}
+	for _, id := range previous {
+		if !seen[id] {
+			tombstones = append(tombstones, res.tombstone(params, region, id))
+			forgotten[id] = ""
+		}
+	}
+	if err = store.SetMembers(scope, run.Seen); err != nil {
+		return nil, common.NewError(common.Internal, "write state of %v: %v", scope, err)
+	}
+	if err = store.SetRun(scope, nil); err != nil {
+		return nil, common.NewError(common.Internal, "write state of %v: %v", scope, err)
+	}
+	if len(forgotten) > 0 {
+		// Reappearing records are emitted again by the incremental mode
+		scope.Filter = ""
+		if err = store.Update(scope, forgotten); err != nil {
+			return nil, common.NewError(common.Internal, "write state of %v: %v", scope, err)
+		}
+	}
+	return tombstones, nil
+}
+
+// tombstone returns the model of a vanished record with Deleted set.
+func (res *TrackedResource)tombstone(params input.Params, region, id string) interface{} {
+	record := res.Resource.(ModelResource).NewModel()
+	val := reflect.Indirect(reflect.ValueOf(record))
+	fields := map[string]string{
+		"ProviderId": id,
+		"CloudType": common.CloudType,
+		"AccountId": params.Credential.AccountId,
+		"RegionId": region,
+	}
+	for name, value := range fields {
+		if field := val.FieldByName(name); field.Kind() == reflect.String && field.CanSet() {
+			field.SetString(value)
+		}
+	}
+	if field := val.FieldByName("Deleted"); field.Kind() == reflect.Int64 && field.CanSet() {
+		field.SetInt(1)
+	}
+	record.SetIndex()
+	record.SetChecksum()
+	return record
+}
@@ -0,0 +1,156 @@
+package state
+
+import (
+	"github.com/hahaps/common-provider/src/common/model"
+	"github.com/hahaps/common-provider/src/input"
+	"github.com/hahaps/common-provider/src/models/compute"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// pagedServers lists ids by page number, one region.
+type pagedServers struct {
+	ids []string
+}
+
+func (pagedServers)NewModel() model.BaseModel {
+	return compute.NewServerModel()
+}
+
+func (res *pagedServers)Call(params input.Params, replay *input.Replay) error {
+	if _, ok := params.Args["limit"]; !ok {
+		params.Args["limit"] = 10
+	}
+	limit := params.Args["limit"].(int)
+	page, _ := strconv.Atoi(params.Args["marker"].(string))
+	start, end := (page - 1) * limit, page * limit
+	if start > len(res.ids) {
+		start = len(res.ids)
+	}
+	if end >= len(res.ids) {
+		end = len(res.ids)
+	} else {
+		replay.Next = strconv.Itoa(page + 1)
+	}
+	for _, id := range res.ids[start:end] {
+		server := compute.NewServerModel()
+		server.ProviderId = id
+		server.CloudType = common.CloudType
+		server.AccountId = params.Credential.AccountId
+		server.RegionId = "cn-hangzhou"
+		server.SetIndex()
+		server.SetChecksum()
+		replay.Result = append(replay.Result, server)
+	}
+	replay.Query = map[string]interface{}{"RegionId": "cn-hangzhou"}
+	return nil
+}
+
+type billing struct{}
+
+func (billing)Call(params input.Params, replay *input.Replay) error {
+	replay.Query = map[string]interface{}{"RegionId": ""}
+	return nil
+}
+
+// process is a provider process of its own, with a store opened on path.
+type process struct {
+	t *testing.T
+	resource *TrackedResource
+	path string
+}
+
+func (proc process)call(marker string, limit int) *input.Replay {
+	proc.t.Helper()
+	SetStoreOpener(OpenFileStore)
+	replay := &input.Replay{}
+	params := input.Params{
+		Credential: input.Credential{AccountId: "1"},
+		Args: map[string]interface{}{"marker": marker, "limit": limit, "tombstones": true, "state_file": proc.path},
+	}
+	if err := proc.resource.Call(params, replay); err != nil {
+		proc.t.Fatal(err)
+	}
+	return replay
+}
+
+func deletedIds(replay *input.Replay) []string {
+	var ids []string
+	for _, record := range replay.Result {
+		if server := record.(*compute.ServerModel); server.Deleted == 1 {
+			ids = append(ids, server.ProviderId)
+		}
+	}
+	return ids
+}
+
+func TestTombstones(t *testing.T) {
+	servers := &pagedServers{ids: []string{"i-1", "i-2", "i-3", "i-4"}}
+	proc := process{t: t, resource: Track("Server", servers), path: filepath.Join(t.TempDir(), "state.json")}
+	proc.call("1", 2)
+	proc.call("2", 2)
+	servers.ids = []string{"i-1", "i-3", "i-4"}
+	if replay := proc.call("1", 2); len(deletedIds(replay)) != 0 || replay.Query["Tombstones"] != 0 {
+		t.Errorf("tombstones %v before the last page", deletedIds(replay))
+	}
+	if ids := deletedIds(proc.call("2", 2)); len(ids) != 1 || ids[0] != "i-2" {
+		t.Errorf("tombstones %v, want i-2", ids)
+	}
+	// A resumed run is not complete
+	servers.ids = []string{"i-1"}
+	if ids := deletedIds(proc.call("2", 2)); len(ids) != 0 {
+		t.Errorf("tombstones %v of a resumed run", ids)
+	}
+}
+
+// Two processes interleaving runs of the same scope, and of another one,
+// never tombstone a listed ProviderId.
+func TestTombstonesInterleaved(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	servers := &pagedServers{ids: []string{"i-1", "i-2", "i-3", "i-4"}}
+	images := &pagedServers{ids: []string{"m-1", "m-2"}}
+	a := process{t: t, resource: Track("Server", servers), path: path}
+	b := process{t: t, resource: Track("Server", servers), path: path}
+	other := process{t: t, resource: Track("Image", images), path: path}
+	a.call("1", 2)
+	a.call("2", 2)
+	a.call("1", 2)
+	b.call("1", 1)
+	other.call("1", 1)
+	if ids := deletedIds(a.call("2", 2)); len(ids) != 0 {
+		t.Errorf("tombstones %v of a run broken by another limit", ids)
+	}
+	other.call("2", 1)
+	for marker := 2; marker <= 4; marker++ {
+		if ids := deletedIds(b.call(strconv.Itoa(marker), 1)); len(ids) != 0 {
+			t.Errorf("tombstones %v", ids)
+		}
+	}
+	store, err := OpenFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scope := Scope{AccountId: "1", RegionId: "cn-hangzhou", Resource: "Server"}
+	if members, _ := store.Members(scope); len(members) != 4 {
+		t.Errorf("servers %v, want the 4 servers", members)
+	}
+	scope.Resource = "Image"
+	if members, _ := store.Members(scope); len(members) != 2 {
+		t.Errorf("images %v, want the 2 images", members)
+	}
+}
+
+func TestTombstonesWithoutModel(t *testing.T) {
+	resource := Track("InstanceBill", billing{})
+	params := input.Params{Args: map[string]interface{}{"tombstones": true, "state_file": filepath.Join(t.TempDir(), "state.json")}}
+	if err := resource.Call(params, &input.Replay{}); common.ErrorCodeOf(err) != common.InvalidParam {
+		t.Errorf("got %v, want an InvalidParam error", err)
+	}
+	for _, scheme := range resource.Schemes() {
+		if scheme.Param == "tombstones" {
+			t.Error("tombstones param advertised without model")
+		}
+	}
+}
@@ -1,17 +1,13 @@
 package storage
 
 import (
-	"errors"
-	"fmt"
-	openapi "github.com/alibabacloud-go/darabonba-openapi/client"
 	ecs20140526 "github.com/alibabacloud-go/ecs-20140526/v2/client"
 	"github.com/alibabacloud-go/tea/tea"
+	"github.com/hahaps/common-provider/src/common/model"
 	"github.com/hahaps/common-provider/src/common/utils"
 	"github.com/hahaps/common-provider/src/input"
-	"github.com/hahaps/common-provider/src/models/compute"
 	"github.com/hahaps/common-provider/src/models/storage"
 	"github.com/hahaps/input-provider-aliyun/src/common"
-	"strconv"
 )
 
 var DiskSchemes = []utils.Scheme {
@@ -32,6 +28,17 @@ var DiskSchemes = []utils.Scheme {
 		Type: utils.String,
 		Default: "1",
 	},
+	utils.Scheme{
+		Param: "tags",
+		Required: false,
+		Type: utils.Map,
+	},
+	utils.Scheme{
+		Param: "resource_group_id",
+		Required: false,
+		Type: utils.String,
+		Default: "",
+	},
 }
 
 type Disk struct {
@@ -40,56 +47,72 @@ type Disk struct {
 	input.Resource
 }
 
-func (disk *Disk)init(credential input.Credential) error {
-	config := &openapi.Config{
-		AccessKeyId: &credential.SecretId,
-		AccessKeySecret: &credential.SecretKey,
-	}
-	config.Endpoint = tea.String("ecs-cn-hangzhou.aliyuncs.com")
-	cli, err := ecs20140526.NewClient(config)
-	if err != nil {
-		return err
-	}
-	disk.credential = credential
-	disk.client = cli
-	return nil
+// NewModel returns an empty model of the resource.
+func (Disk)NewModel() model.BaseModel {
+	return storage.NewDiskModel()
 }
 
 func (Disk)Call(params input.Params, replay *input.Replay) error {
 	disk := &Disk{}
-	var next string
 	var err error
 	var disks []interface{}
+	common.NormalizeRegion(params.Args)
 	params.Args, err = utils.CheckParam(params.Args, DiskSchemes)
+	if err != nil {
+		return common.ParamError(err)
+	}
+	tagFilters, err := common.TagFilters(params.Args)
 	if err != nil {
 		return err
 	}
-	err = disk.init(params.Credential)
+	invoker, err := common.NewInvoker(params)
+	if err != nil {
+		return err
+	}
+	defer invoker.Close()
+	regions, err := common.Regions(invoker, params.Credential, params.Args["region"].(string))
 	if err != nil {
 		return err
 	}
 	limit := int32(params.Args["limit"].(int))
-	next = params.Args["marker"].(string)
-	region := params.Args["region"].(string)
-	query := map[string]interface{} {
-		"RegionId": region,
-		"CloudType": common.CloudType,
-		"AccountId": disk.credential.AccountId,
+	pager, err := common.NewPaginator(common.MaxResultsStyle, params.Args["marker"].(string), regions, limit)
+	if err != nil {
+		return err
 	}
-	if next == "" {
+	if pager.Done() {
 		return nil
 	}
-	number, err := strconv.ParseInt(next,10,32)
+	region := pager.Region()
+	disk.credential = params.Credential
+	disk.client, err = common.EcsClient(invoker, params.Credential, region)
 	if err != nil {
-		return errors.New("bad page number[marker] info")
+		return err
+	}
+	defer common.ReleaseClient(disk.client)
+	query := map[string]interface{} {
+		"RegionId": region,
+		"CloudType": common.CloudType,
+		"AccountId": disk.credential.AccountId,
 	}
-	pageNum := int32(number)
 	request := &ecs20140526.DescribeDisksRequest{
 		RegionId: tea.String(region),
-		PageSize: &limit,
-		PageNumber: &pageNum,
+		MaxResults: tea.Int32(pager.PageSize()),
+	}
+	for _, tag := range tagFilters {
+		request.Tag = append(request.Tag, &ecs20140526.DescribeDisksRequestTag{
+			Key: tea.String(tag.Key),
+			Value: common.OptionalString(tag.Value),
+		})
 	}
-	resp, err := disk.client.DescribeDisks(request)
+	request.ResourceGroupId = common.OptionalString(params.Args["resource_group_id"].(string))
+	if pager.NextToken() != "" {
+		request.NextToken = tea.String(pager.NextToken())
+	}
+	var resp *ecs20140526.DescribeDisksResponse
+	err = invoker.Invoke(common.ProductEcs, region, "DescribeDisks", func() (err error) {
+		resp, err = disk.client.DescribeDisks(request)
+		return err
+	})
 	if err != nil {
 		return err
 	}
@@ -109,7 +132,8 @@ func (Disk)Call(params input.Params, replay *input.Replay) error {
 		dis.Category = utils.SafeString(dk.Category)
 		dis.ExpiredTime = utils.SafeString(dk.ExpiredTime)
 		dis.Description = utils.SafeString(dk.Description)
-		dis.Tags = getDiskTags(dk.Tags)
+		tags := getDiskTags(dk.Tags)
+		dis.Tags = tags.String()
 		dis.Attachments = getAttachments(dk.Attachments)
 		dis.Extra = map[string]interface{}{
 			"ResourceGroupId": utils.SafeString(dk.ResourceGroupId),
@@ -120,44 +144,37 @@ func (Disk)Call(params input.Params, replay *input.Replay) error {
 			"ZoneId": utils.SafeString(dk.ZoneId),
 			"Device": utils.SafeString(dk.Device),
 			"SourceSnapshotId": utils.SafeString(dk.SourceSnapshotId),
+			"Tags": tags.Map(),
 		}
 		dis.SetIndex()
 		dis.SetChecksum()
 		checked, key := dis.CheckRequired()
 		if !checked {
-			return errors.New(
-				fmt.Sprintf("Value[%v] should not be empty", key))
+			return common.NewError(common.Upstream, "Value[%v] should not be empty", key)
 		}
 		disks = append(disks, dis)
 	}
-	if !utils.CheckQueryKeys(query, compute.ServerModel{}) {
-		return errors.New("query key is not attribute of DiskModel")
+	if !utils.CheckQueryKeys(query, storage.DiskModel{}) {
+		return common.NewError(common.Internal, "query key is not attribute of DiskModel")
 	}
-	total := utils.SafeInt32(resp.Body.TotalCount)
-	if pageNum * limit >= total {
-		next = ""
-	} else {
-		next = strconv.Itoa(int(pageNum + 1))
+	replay.Next, err = pager.Next(utils.SafeInt32(resp.Body.TotalCount), utils.SafeString(resp.Body.NextToken))
+	if err != nil {
+		return err
 	}
-	replay.Next = next
 	replay.Query = query
 	replay.Result = disks
 	return nil
 }
 
-func getDiskTags(tags *ecs20140526.DescribeDisksResponseBodyDisksDiskTags) string {
+func getDiskTags(tags *ecs20140526.DescribeDisksResponseBodyDisksDiskTags) common.Tags {
+	result := common.Tags{}
 	if tags == nil {
-		return ""
-	}
-	tgs := ""
-	l := len(tags.Tag)
-	for i, tg := range tags.Tag {
-		tgs += fmt.Sprintf("%v=%v", tg.TagKey, tg.TagValue)
-		if i + 1 < l {
-			tgs += ";"
-		}
+		return result
+	}
+	for _, tg := range tags.Tag {
+		result.Add(tg.TagKey, tg.TagValue)
 	}
-	return tgs
+	return result
 }
 
 func getAttachments(attach *ecs20140526.DescribeDisksResponseBodyDisksDiskAttachments) (attachments []map[string]interface{}) {
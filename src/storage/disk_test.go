@@ -0,0 +1,88 @@
+package storage_test
+
+import (
+	"github.com/hahaps/common-provider/src/models/storage"
+	"github.com/hahaps/input-provider-aliyun/src/common"
+	"github.com/hahaps/input-provider-aliyun/src/fakeapi"
+	"testing"
+)
+
+func disk(id string) map[string]interface{} {
+	return map[string]interface{}{
+		"DiskId": id,
+		"DiskName": "disk-" + id,
+		"Status": "Available",
+		"Type": "data",
+		"Category": "cloud_essd",
+		"Size": 100,
+	}
+}
+
+func diskIds(result []interface{}) []string {
+	var ids []string
+	for _, record := range result {
+		ids = append(ids, record.(*storage.DiskModel).ProviderId)
+	}
+	return ids
+}
+
+func TestDisk(t *testing.T) {
+	fakeapi.Start(t)
+	replay, err := fakeapi.Call("Disk", map[string]interface{}{"region": fakeapi.Region})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replay.Result) != 1 || replay.Next != "" {
+		t.Fatalf("got %v records and Next %q, want 1 record and no Next", len(replay.Result), replay.Next)
+	}
+	dis := replay.Result[0].(*storage.DiskModel)
+	if dis.ProviderId != "d-fake0001" || dis.AttachedServer != "i-fake0001" || dis.Size != 40 || dis.Category != "cloud_essd" {
+		t.Errorf("unexpected disk %+v", dis)
+	}
+	if len(dis.Attachments) != 1 {
+		t.Errorf("attachments %v, want 1", dis.Attachments)
+	}
+	if replay.Query["RegionId"] != fakeapi.Region || replay.Query["AccountId"] != fakeapi.AccountId {
+		t.Errorf("unexpected query %v", replay.Query)
+	}
+}
+
+func TestDiskPages(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Handle("DescribeDisks", fakeapi.Pages{
+		List: "Disks.Disk",
+		Items: fakeapi.Items(disk("d-1"), disk("d-2"), disk("d-3")),
+	}.Handler())
+	args := map[string]interface{}{"region": fakeapi.Region, "limit": 2}
+	replay, err := fakeapi.Call("Disk", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := diskIds(replay.Result); len(ids) != 2 || ids[0] != "d-1" || ids[1] != "d-2" {
+		t.Errorf("first page %v", ids)
+	}
+	args["marker"] = replay.Next
+	replay, err = fakeapi.Call("Disk", args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := diskIds(replay.Result); len(ids) != 1 || ids[0] != "d-3" || replay.Next != "" {
+		t.Errorf("last page %v with Next %q", ids, replay.Next)
+	}
+	requests := server.Requests("DescribeDisks")
+	if len(requests) != 2 || requests[0].Get("MaxResults") != "2" || requests[1].Get("NextToken") != "2" {
+		t.Errorf("unexpected requests %v", requests)
+	}
+}
+
+func TestDiskFail(t *testing.T) {
+	server := fakeapi.Start(t)
+	server.Fail("DescribeDisks", 1, 403, "Forbidden.RAM", "User not authorized to operate on the specified resource.")
+	if _, err := fakeapi.Call("Disk", map[string]interface{}{"region": fakeapi.Region}); common.ErrorCodeOf(err) != common.Forbidden {
+		t.Errorf("got %v, want a Forbidden error", err)
+	}
+	server.Fail("DescribeDisks", 1, 500, "InternalError", "The request processing has failed due to some unknown error.")
+	if _, err := fakeapi.Call("Disk", map[string]interface{}{"region": fakeapi.Region}); err != nil {
+		t.Errorf("an internal error should be retried, got %v", err)
+	}
+}
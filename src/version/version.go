@@ -0,0 +1,178 @@
+package version
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version, as "v1.2.3" or "0.1" with the missing
+// parts set to 0.
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	Pre string
+}
+
+func Parse(version string) (Version, error) {
+	var ver Version
+	str := strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if idx := strings.IndexByte(str, '+'); idx >= 0 {
+		str = str[:idx]
+	}
+	if idx := strings.IndexByte(str, '-'); idx >= 0 {
+		str, ver.Pre = str[:idx], str[idx+1:]
+	}
+	parts := strings.Split(str, ".")
+	if len(parts) > 3 {
+		return ver, fmt.Errorf("bad version %v", version)
+	}
+	nums := []*int{&ver.Major, &ver.Minor, &ver.Patch}
+	for idx, part := range parts {
+		num, err := strconv.Atoi(part)
+		if err != nil || num < 0 {
+			return ver, fmt.Errorf("bad version %v", version)
+		}
+		*nums[idx] = num
+	}
+	return ver, nil
+}
+
+// Compare returns -1, 0 or 1 when ver is lower than, equal to or greater
+// than other. A pre-release is lower than its release, pre-releases being
+// ordered by their dot separated identifiers as in semver.
+func (ver Version)Compare(other Version) int {
+	pairs := [][2]int{
+		{ver.Major, other.Major},
+		{ver.Minor, other.Minor},
+		{ver.Patch, other.Patch},
+	}
+	for _, pair := range pairs {
+		if pair[0] != pair[1] {
+			if pair[0] < pair[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case ver.Pre == other.Pre:
+		return 0
+	case ver.Pre == "":
+		return 1
+	case other.Pre == "":
+		return -1
+	}
+	return comparePre(ver.Pre, other.Pre)
+}
+
+// comparePre compares the identifiers of two pre-releases one by one:
+// numeric ones numerically and lower than alphanumeric ones, which compare
+// in ASCII order. A pre-release with fewer identifiers is lower.
+func comparePre(pre, other string) int {
+	ids, others := strings.Split(pre, "."), strings.Split(other, ".")
+	for i := 0; i < len(ids) && i < len(others); i++ {
+		num, numErr := strconv.Atoi(ids[i])
+		otherNum, otherErr := strconv.Atoi(others[i])
+		switch {
+		case numErr == nil && otherErr == nil:
+			if num != otherNum {
+				return sign(num - otherNum)
+			}
+		case numErr == nil:
+			return -1
+		case otherErr == nil:
+			return 1
+		case ids[i] != others[i]:
+			return sign(strings.Compare(ids[i], others[i]))
+		}
+	}
+	return sign(len(ids) - len(others))
+}
+
+func sign(value int) int {
+	switch {
+	case value < 0:
+		return -1
+	case value > 0:
+		return 1
+	}
+	return 0
+}
+
+func (ver Version)String() string {
+	str := fmt.Sprintf("v%d.%d.%d", ver.Major, ver.Minor, ver.Patch)
+	if ver.Pre != "" {
+		str += "-" + ver.Pre
+	}
+	return str
+}
+
+var operators = []string{">=", "<=", "!=", ">", "<", "=", "^", "~"}
+
+// Match reports whether version satisfies the constraint. A constraint is
+// a list of comparators, all of which must be satisfied, as ">=0.1 <0.3";
+// constraints joined by "||" are satisfied by any of them. A comparator is
+// a version prefixed by one of >=, <=, >, <, =, != or by ^ for the same
+// major version (the same minor version below v1), or ~ for the same
+// minor version. A bare version has to be equal.
+func Match(version, constraint string) (bool, error) {
+	ver, err := Parse(version)
+	if err != nil {
+		return false, err
+	}
+	matched := false
+	for _, alternative := range strings.Split(constraint, "||") {
+		comparators := strings.Fields(alternative)
+		if len(comparators) == 0 {
+			return false, fmt.Errorf("bad constraint %v", constraint)
+		}
+		all := true
+		for _, comparator := range comparators {
+			ok, err := compare(ver, comparator)
+			if err != nil {
+				return false, err
+			}
+			all = all && ok
+		}
+		matched = matched || all
+	}
+	return matched, nil
+}
+
+func compare(ver Version, comparator string) (bool, error) {
+	operator := "="
+	for _, op := range operators {
+		if strings.HasPrefix(comparator, op) {
+			operator = op
+			comparator = comparator[len(op):]
+			break
+		}
+	}
+	bound, err := Parse(comparator)
+	if err != nil {
+		return false, err
+	}
+	cmp := ver.Compare(bound)
+	switch operator {
+	case ">=":
+		return cmp >= 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case "!=":
+		return cmp != 0, nil
+	case ">":
+		return cmp > 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "^":
+		if bound.Major == 0 {
+			return cmp >= 0 && ver.Major == 0 && ver.Minor == bound.Minor, nil
+		}
+		return cmp >= 0 && ver.Major == bound.Major, nil
+	case "~":
+		return cmp >= 0 && ver.Major == bound.Major && ver.Minor == bound.Minor, nil
+	}
+	return cmp == 0, nil
+}
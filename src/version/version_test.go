@@ -0,0 +1,119 @@
+package version
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		version string
+		want Version
+	}{
+		{"v1.2.3", Version{Major: 1, Minor: 2, Patch: 3}},
+		{"0.1", Version{Minor: 1}},
+		{"2", Version{Major: 2}},
+		{" v1.0.0-rc.1+build.5 ", Version{Major: 1, Pre: "rc.1"}},
+	}
+	for _, c := range cases {
+		got, err := Parse(c.version)
+		if err != nil || got != c.want {
+			t.Errorf("Parse(%q) = %+v, %v, want %+v", c.version, got, err, c.want)
+		}
+	}
+	for _, bad := range []string{"", "v", "1.2.3.4", "1..2", "a.b", "1.-2", "-rc.1"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q) should fail", bad)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// Each version is lower than the next one
+	ordered := []string{
+		"0.9.9",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.2",
+		"1.0.0-alpha.10",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.2.0",
+		"2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			ver, _ := Parse(ordered[i])
+			other, _ := Parse(ordered[j])
+			want := sign(i - j)
+			if got := ver.Compare(other); got != want {
+				t.Errorf("%v compared to %v = %v, want %v", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		version string
+		constraint string
+		want bool
+	}{
+		{"v0.1", "v0.1", true},
+		{"v0.1.1", "0.1", false},
+		{"v0.2", "=0.2.0", true},
+		{"v0.2", "!=0.2", false},
+		{"v0.1", ">=0.1 <0.3", true},
+		{"v0.2.9", ">=0.1 <0.3", true},
+		{"v0.3", ">=0.1 <0.3", false},
+		{"v0.0.9", ">=0.1 <0.3", false},
+		{"v0.3.0-rc.1", ">=0.1 <0.3", true},
+		{"v0.3", ">0.2 <=0.3", true},
+		{"v1.4.2", "^1.2", true},
+		{"v2.0.0", "^1.2", false},
+		{"v1.1.9", "^1.2", false},
+		{"v0.2.5", "^0.2.1", true},
+		{"v0.3.0", "^0.2.1", false},
+		{"v1.2.9", "~1.2.3", true},
+		{"v1.3.0", "~1.2.3", false},
+		{"v1.2.2", "~1.2.3", false},
+		{"v0.5", "<0.2 || >=0.4", true},
+		{"v0.3", "<0.2 || >=0.4", false},
+		{"v0.1", "<0.2 || >=0.4", true},
+		{"v1.0.0-beta", ">=1.0.0-alpha.10 <1.0.0", true},
+		{"v1.0.0-alpha.9", ">=1.0.0-alpha.10", false},
+	}
+	for _, c := range cases {
+		got, err := Match(c.version, c.constraint)
+		if err != nil || got != c.want {
+			t.Errorf("Match(%q, %q) = %v, %v, want %v", c.version, c.constraint, got, err, c.want)
+		}
+	}
+}
+
+func TestMatchMalformed(t *testing.T) {
+	constraints := []string{
+		"",
+		"   ",
+		">=",
+		">= 0.1",
+		"0.1 ||",
+		"|| 0.1",
+		">=0.1 <",
+		"^x",
+		"~1.2.3.4",
+		"=>0.1",
+		">=0.1,<0.3",
+	}
+	for _, constraint := range constraints {
+		if _, err := Match("v0.2", constraint); err == nil {
+			t.Errorf("Match(v0.2, %q) should fail", constraint)
+		}
+	}
+	if _, err := Match("not a version", ">=0.1"); err == nil {
+		t.Error("Match of a bad version should fail")
+	}
+}